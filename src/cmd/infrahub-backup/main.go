@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	app "infrahub-ops/src/internal/app"
 
@@ -30,6 +32,8 @@ func main() {
 	var force bool
 	var neo4jMetadata string
 	var excludeTaskManagerDB bool
+	var namespaceAll bool
+	var namespaces []string
 	var restoreExcludeTaskManagerDB bool
 	var restoreMigrateFormat bool
 
@@ -38,27 +42,213 @@ func main() {
 		Short:        "Create a backup of the current Infrahub instance",
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return iops.CreateBackup(force, neo4jMetadata, excludeTaskManagerDB)
+			if namespaceAll || len(namespaces) > 0 {
+				if namespaceAll && len(namespaces) > 0 {
+					return fmt.Errorf("%w: --namespace-all and --namespaces cannot be combined", app.ErrPrerequisites)
+				}
+				if iops.Config().K8sNamespace != "" {
+					return fmt.Errorf("%w: --k8s-namespace cannot be combined with --namespace-all or --namespaces", app.ErrPrerequisites)
+				}
+				return iops.CreateNamespaceBackups(namespaces, force, neo4jMetadata, excludeTaskManagerDB)
+			}
+			return iops.CreateBackupWithRetries(force, neo4jMetadata, excludeTaskManagerDB)
 		},
 	}
-	createCmd.Flags().BoolVar(&force, "force", false, "Force backup creation even if there are running tasks")
-	createCmd.Flags().StringVar(&neo4jMetadata, "neo4jmetadata", "all", "Whether to backup neo4j metadata or not (all, none, users, roles)")
-	createCmd.Flags().BoolVar(&excludeTaskManagerDB, "exclude-taskmanager", false, "Exclude task manager database from the backup")
+	addBackupFlags(createCmd, iops.Config(), &force, &neo4jMetadata, &excludeTaskManagerDB)
+	createCmd.Flags().BoolVar(&namespaceAll, "namespace-all", false, "Kubernetes: back up every namespace with Infrahub pods, one archive per namespace under <backup-dir>/<namespace>")
+	createCmd.Flags().StringSliceVar(&namespaces, "namespaces", nil, "Kubernetes: back up these namespaces (comma-separated or repeated), one archive per namespace under <backup-dir>/<namespace>")
+	createCmd.Flags().BoolVar(&iops.Config().DumpOnly, "dump-only", false, "Write only the Neo4j database dump and a dump_information.json to a directory, without the task manager database or an archive (not restorable with restore)")
+	createCmd.Flags().StringVar(&iops.Config().DumpDir, "dump-dir", "", "Parent directory for --dump-only output (default the backup directory)")
+	createCmd.Flags().StringVar(&iops.Config().OutputDir, "output-dir", "", "Write the archive to this directory instead of the backup directory, keeping a one-off export out of listing and retention")
+	createCmd.Flags().BoolVar(&iops.Config().NoRestart, "no-restart", false, "Neo4j Community only: leave the application services stopped after the backup (for maintenance windows)")
+
+	var restoreLatest bool
+	var restoreFromS3 bool
+	var restoreAllowPartial bool
+	var restoreFromDir string
 
 	restoreCmd := &cobra.Command{
-		Use:          "restore <backup-file>",
+		Use:          "restore [backup-file]",
 		Short:        "Restore Infrahub from a backup archive",
-		Args:         cobra.ExactArgs(1),
+		Args:         cobra.MaximumNArgs(1),
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if restoreFromDir != "" {
+				if restoreLatest || len(args) > 0 {
+					return fmt.Errorf("%w: --from-dir cannot be combined with --latest or a backup file argument", app.ErrPrerequisites)
+				}
+				return iops.RestoreFromDirectory(restoreFromDir, restoreExcludeTaskManagerDB, restoreMigrateFormat)
+			}
+			if restoreLatest {
+				if len(args) > 0 {
+					return fmt.Errorf("%w: --latest cannot be combined with a backup file argument", app.ErrPrerequisites)
+				}
+				return iops.RestoreLatestBackup(restoreFromS3, restoreAllowPartial, restoreExcludeTaskManagerDB, restoreMigrateFormat)
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("%w: a backup file is required (or use --latest or --from-dir)", app.ErrPrerequisites)
+			}
 			return iops.RestoreBackup(args[0], restoreExcludeTaskManagerDB, restoreMigrateFormat)
 		},
 	}
+	restoreCmd.Flags().BoolVar(&restoreLatest, "latest", false, "Restore the newest complete backup from the backup directory (or S3 with --s3)")
+	restoreCmd.Flags().StringVar(&restoreFromDir, "from-dir", "", "Restore from a directory holding an already extracted backup/ tree instead of an archive; the directory is left in place")
+	restoreCmd.Flags().BoolVar(&restoreFromS3, "s3", false, "With --latest, select the newest backup from the S3 bucket instead of the backup directory")
+	restoreCmd.Flags().BoolVar(&restoreAllowPartial, "allow-partial", false, "With --latest, also select a partial --best-effort backup when it is the newest")
 	restoreCmd.Flags().BoolVar(&restoreExcludeTaskManagerDB, "exclude-taskmanager", false, "Skip restoring the task manager database even if present in the archive")
+	restoreCmd.Flags().StringSliceVar(&iops.Config().ExcludeComponents, "exclude-components", nil, "Skip restoring these components (comma-separated): neo4j, task-manager, auth")
+	restoreCmd.Flags().StringSliceVar(&iops.Config().IncludeComponents, "include-components", nil, "Restore only these components (comma-separated): neo4j, task-manager, auth")
 	restoreCmd.Flags().BoolVar(&restoreMigrateFormat, "migrate-format", false, "Run neo4j-admin database migrate --to-format=block after the restore completes")
+	restoreCmd.Flags().BoolVar(&iops.Config().ConfirmDestructive, "confirm-destructive", false, "Skip the interactive confirmation before wiping and restoring data (required without a TTY)")
+	restoreCmd.Flags().BoolVarP(&iops.Config().ConfirmDestructive, "yes", "y", false, "Alias for --confirm-destructive")
+	restoreCmd.Flags().BoolVar(&iops.Config().ValidateOnly, "validate-only", false, "Extract and verify the archive and check it against the deployment, then stop before anything is changed")
+	restoreCmd.Flags().StringVar(&iops.Config().OutputFormat, "output", "text", "Format of the restore plan printed before confirmation: text or json")
+	restoreCmd.Flags().StringVar(&iops.Config().PgRestoreDB, "pg-restore-db", "", "Database pg_restore connects to (default postgres, or the task manager database with --pg-no-create)")
+	restoreCmd.Flags().BoolVar(&iops.Config().PgRestoreNoClean, "pg-no-clean", false, "Do not pass --clean to pg_restore")
+	restoreCmd.Flags().BoolVar(&iops.Config().PgRestoreNoCreate, "pg-no-create", false, "Do not pass --create to pg_restore; restore into an existing database")
+	restoreCmd.Flags().StringVar(&iops.Config().PgRestoreOpts, "pg-restore-opts", "", "Additional pg_restore options, e.g. \"--no-owner -x\"")
+	restoreCmd.Flags().BoolVar(&iops.Config().HealthAfterRestore, "health-after-restore", false, "Wait for infrahub-server to answer its API after the restore and fail if it does not")
+	restoreCmd.Flags().DurationVar(&iops.Config().HealthTimeout, "health-timeout", iops.Config().HealthTimeout, "How long --health-after-restore waits for infrahub-server")
+	restoreCmd.Flags().StringVar(&iops.Config().TargetProject, "target-project", "", "Restore into this Docker Compose project, disabling environment auto-detection; fails if the project has no running Infrahub deployment")
+	restoreCmd.Flags().StringVar(&iops.Config().TargetNamespace, "target-namespace", "", "Restore into this Kubernetes namespace, disabling environment auto-detection; fails if the namespace has no Infrahub pods")
+	restoreCmd.Flags().BoolVar(&iops.Config().StrictChecksums, "strict-checksums", false, "Fail if the archive contains a file that has no checksum in the backup metadata")
+	restoreCmd.Flags().BoolVar(&iops.Config().SchemaOnly, "schema-only", false, "Only apply the Neo4j constraints and indexes captured in the backup to the current database; data is not touched")
+	restoreCmd.Flags().BoolVar(&iops.Config().ExcludeNeo4jAuth, "exclude-neo4j-auth", false, "Neo4j Community: keep the current users instead of restoring the ones in the backup")
+	restoreCmd.Flags().BoolVar(&iops.Config().Warmup, "warmup", false, "After the Neo4j restore, wait for its indexes to come online and run a warmup query before Infrahub services start")
+	restoreCmd.Flags().DurationVar(&iops.Config().WarmupTimeout, "warmup-timeout", iops.Config().WarmupTimeout, "How long --warmup waits for the database and its indexes")
+	restoreCmd.Flags().BoolVar(&iops.Config().Neo4jRestoreVerify, "neo4j-restore-verify", false, "After the Neo4j restore, count nodes and relationships and fail, leaving Infrahub stopped, if they diverge from the counts recorded in the backup")
+	restoreCmd.Flags().Float64Var(&iops.Config().Neo4jVerifyTolerance, "neo4j-restore-verify-tolerance", iops.Config().Neo4jVerifyTolerance, "Percentage by which --neo4j-restore-verify lets the counts differ from the backup")
+	restoreCmd.Flags().StringVar(&iops.Config().Neo4jMetadataScript, "neo4j-metadata-script", "", "Path in the database container of the Neo4j metadata script to replay after an Enterprise restore (default: the script neo4j-admin extracts from the backup)")
+	restoreCmd.Flags().BoolVar(&iops.Config().SkipMetadataRestore, "skip-metadata-restore", false, "Do not replay the Neo4j users and roles captured in an Enterprise backup")
+	restoreCmd.Flags().BoolVar(&iops.Config().NoParallel, "no-parallel", false, "Copy the Neo4j backup and restore the task manager database one after the other instead of concurrently")
+	restoreCmd.Flags().BoolVar(&iops.Config().NoStreamRestore, "no-stream-restore", false, "Neo4j Community: copy the dump into the database container before loading it instead of streaming it to neo4j-admin database load --from-stdin")
+	restoreCmd.Flags().BoolVar(&iops.Config().KeepTemp, "keep-temp", false, "Keep the temporary working directory (and any diagnostic logs) after the restore")
+	restoreCmd.Flags().BoolVar(&iops.Config().Neo4jPortCheck, "neo4j-port-check", iops.Config().Neo4jPortCheck, "Neo4j Community: after the process stops, wait until nothing listens on the Bolt and HTTP ports before loading (skipped when the container has neither ss nor netstat)")
+	restoreCmd.Flags().StringVar(&iops.Config().WatchdogMode, "watchdog-mode", "binary", "Neo4j Community watchdog: binary (falls back to shell), shell, or none (risks leaving Neo4j stopped if interrupted)")
+	restoreCmd.Flags().DurationVar(&iops.Config().WatchdogHeartbeatInterval, "watchdog-heartbeat-interval", iops.Config().WatchdogHeartbeatInterval, "How often to refresh the Neo4j Community watchdog heartbeat; the watchdog resumes Neo4j after 6 missed beats (0 disables)")
+	restoreCmd.Flags().BoolVar(&iops.Config().IncludeNeo4jLogs, "include-neo4j-logs", false, "Neo4j Community: copy the tail of the Neo4j log files into the working directory and the diagnostic bundle after the restore")
+	restoreCmd.Flags().StringSliceVar(&iops.Config().Neo4jLogPaths, "neo4j-log-paths", iops.Config().Neo4jLogPaths, "Neo4j log files in the database container captured by --include-neo4j-logs (comma-separated)")
+	restoreCmd.Flags().IntVar(&iops.Config().Neo4jLogLines, "neo4j-log-lines", iops.Config().Neo4jLogLines, "Number of lines kept from the end of each Neo4j log by --include-neo4j-logs")
+
+	var prunePolicy app.RetentionPolicy
+	var pruneLocal bool
+	var pruneS3 bool
+
+	pruneCmd := &cobra.Command{
+		Use:          "prune",
+		Short:        "Delete backups that fall outside the retention policy",
+		Long:         "Apply the retention policy on demand to backups in the backup directory and/or S3 bucket.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return iops.PruneBackups(prunePolicy, pruneLocal, pruneS3, iops.Config().DryRun)
+		},
+	}
+	pruneCmd.Flags().IntVar(&prunePolicy.KeepLast, "keep-last", 0, "Keep the N most recent backups in each location (0 disables)")
+	pruneCmd.Flags().DurationVar(&prunePolicy.MaxAge, "max-age", 0, "Delete backups older than this duration, e.g. 720h (0 disables)")
+	pruneCmd.Flags().BoolVar(&pruneLocal, "local", true, "Prune backups in the backup directory")
+	pruneCmd.Flags().BoolVar(&pruneS3, "s3", false, "Prune backups in the S3 bucket (requires S3_* env vars)")
+
+	diffCmd := &cobra.Command{
+		Use:          "diff <backup-a> <backup-b>",
+		Short:        "Compare the metadata of two backups",
+		Long:         "Compare the metadata of two backups, given as local paths or s3://bucket/key URIs: versions, Neo4j edition, components, file checksums and dump sizes. Only the metadata is read from each archive.",
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return iops.DiffBackups(args[0], args[1])
+		},
+	}
+
+	var listOpts app.BackupListOptions
+
+	listCmd := &cobra.Command{
+		Use:          "list",
+		Short:        "List available backups",
+		Long:         "List backups in the backup directory and/or S3 bucket, newest first.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return iops.ListBackups(listOpts)
+		},
+	}
+	listCmd.Flags().BoolVar(&listOpts.Local, "local", true, "List backups in the backup directory")
+	listCmd.Flags().BoolVar(&listOpts.S3, "s3", false, "List backups in the S3 bucket (requires S3_* env vars)")
+	listCmd.Flags().StringVar(&listOpts.Since, "since", "", "Only list backups created at or after this time (RFC3339 or a duration such as 7d or 36h)")
+	listCmd.Flags().StringVar(&listOpts.Before, "before", "", "Only list backups created before this time (RFC3339 or a duration such as 7d or 36h)")
+	listCmd.Flags().StringVar(&listOpts.Label, "label", "", "Only list backups with this label")
+	listCmd.Flags().BoolVar(&listOpts.Labels, "labels", false, "Show the label of each backup (reads the metadata of every archive)")
+	listCmd.Flags().BoolVar(&listOpts.Keys, "keys", false, "Show the integrity key identifier of each backup (reads the metadata of every archive)")
+	listCmd.Flags().BoolVar(&listOpts.Latest, "latest", false, "Print only the path (or s3:// URI) of the newest matching backup")
+
+	uploadCmd := &cobra.Command{
+		Use:          "upload <backup-file>",
+		Short:        "Upload an existing backup archive to S3",
+		Long:         "Upload a backup archive created earlier, for example after the upload step of create failed. With --resume-upload an interrupted multipart upload is continued.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return iops.UploadBackup(args[0])
+		},
+	}
+
+	var scheduleOpts app.ScheduleOptions
+
+	scheduleCmd := &cobra.Command{
+		Use:          "schedule",
+		Short:        "Create backups periodically until interrupted",
+		Long:         "Run a backup every --interval. The time of the last run is persisted so a restart neither skips nor duplicates backups; if the last backup is older than the interval, one runs immediately.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return iops.RunSchedule(scheduleOpts)
+		},
+	}
+	scheduleCmd.Flags().DurationVar(&scheduleOpts.Interval, "interval", 24*time.Hour, "Time between backups")
+	scheduleCmd.Flags().DurationVar(&scheduleOpts.Jitter, "schedule-jitter", 0, "Delay each run by a random amount up to this duration to spread load across instances")
+	scheduleCmd.Flags().StringVar(&scheduleOpts.StateFile, "state-file", "", "File recording the last run (default <backup-dir>/.infrahub_backup_schedule.json)")
+	addBackupFlags(scheduleCmd, iops.Config(), &scheduleOpts.Force, &scheduleOpts.Neo4jMetadata, &scheduleOpts.ExcludeTaskManager)
+
+	var multipartOlderThan time.Duration
+
+	s3Cmd := &cobra.Command{
+		Use:   "s3",
+		Short: "S3 bucket maintenance",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	cleanupMultipartCmd := &cobra.Command{
+		Use:          "cleanup-multipart",
+		Short:        "Abort stale incomplete multipart uploads",
+		Long:         "List the incomplete multipart uploads under the S3 prefix and abort those started more than --older-than ago. Failed or interrupted uploads otherwise keep their parts, which some providers bill as storage.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return iops.CleanupMultipartUploads(multipartOlderThan, iops.Config().DryRun)
+		},
+	}
+	cleanupMultipartCmd.Flags().DurationVar(&multipartOlderThan, "older-than", 24*time.Hour, "Only abort uploads started more than this long ago; younger uploads may still be in progress")
+	probeCmd := &cobra.Command{
+		Use:          "probe",
+		Short:        "Check S3 connectivity and permissions",
+		Long:         "Check that the S3 bucket is reachable and that the credentials allow HeadBucket, PutObject, GetObject, ListObjectsV2 and DeleteObject, using a small test object under the S3 prefix. Exits non-zero if any operation fails.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return iops.ProbeS3()
+		},
+	}
+	s3Cmd.AddCommand(probeCmd)
+	s3Cmd.AddCommand(cleanupMultipartCmd)
 
 	rootCmd.AddCommand(createCmd)
 	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(scheduleCmd)
+	rootCmd.AddCommand(uploadCmd)
+	rootCmd.AddCommand(s3Cmd)
 
 	versionCmd := &cobra.Command{
 		Use:   "version",
@@ -72,6 +262,53 @@ func main() {
 
 	if err := rootCmd.Execute(); err != nil {
 		logrus.Errorf("Command failed: %v", err)
-		os.Exit(1)
+		os.Exit(app.ExitCode(err))
 	}
 }
+
+// addBackupFlags registers the backup options shared by create and schedule, so that a
+// scheduled backup can be configured like a one-off one.
+func addBackupFlags(cmd *cobra.Command, cfg *app.Configuration, force *bool, neo4jMetadata *string, excludeTaskManager *bool) {
+	flags := cmd.Flags()
+	flags.BoolVar(force, "force", false, "Force backup creation even if there are running tasks")
+	flags.StringVar(neo4jMetadata, "neo4j-metadata", "all", "Neo4j metadata to back up: all, none, users or roles (true and false mean all and none)")
+	flags.StringVar(neo4jMetadata, "neo4jmetadata", "all", "Alias for --neo4j-metadata")
+	flags.MarkHidden("neo4jmetadata")
+	flags.BoolVar(excludeTaskManager, "exclude-taskmanager", false, "Exclude task manager database from the backup")
+	flags.StringSliceVar(&cfg.ExcludeComponents, "exclude-components", nil, "Leave these components out of the backup (comma-separated): neo4j, task-manager, auth, config")
+	flags.BoolVar(&cfg.IncludeConfig, "include-config", false, "Include a redacted snapshot of the deployment configuration (compose config or ConfigMaps) for reference")
+	flags.StringArrayVar(&cfg.ExcludeFiles, "exclude-file", nil, "Glob pattern (relative to the component directory, ** matches any depth) of files to leave out of the config component (repeatable)")
+	flags.BoolVar(&cfg.Neo4jOfflineEnterprise, "neo4j-offline-enterprise", false, "Neo4j Enterprise: stop the database and take an offline dump instead of an online backup (the database is unavailable meanwhile; users and roles are not included)")
+	flags.BoolVar(&cfg.Neo4jOfflineEnterprise, "neo4j-stop-database-first", false, "Alias for --neo4j-offline-enterprise")
+	flags.BoolVar(&cfg.ExcludeNeo4jAuth, "exclude-neo4j-auth", false, "Neo4j Community: leave the users (system database and auth files) out of the backup")
+	flags.MarkHidden("neo4j-stop-database-first")
+	flags.BoolVar(&cfg.Neo4jBackupCompress, "neo4j-backup-compress", cfg.Neo4jBackupCompress, "Pass --compress to neo4j-admin database backup (Enterprise) and use the fastest archive compression level; --neo4j-backup-compress=false stores the backup uncompressed")
+	flags.BoolVar(&cfg.NoGraphStats, "no-graph-stats", false, "Do not count the Neo4j nodes and relationships recorded in the backup metadata for restore --neo4j-restore-verify")
+	flags.StringVar(&cfg.Label, "label", "", "Free-form label recorded in the backup metadata and on the S3 object, e.g. pre-upgrade (up to 64 characters)")
+	flags.StringVar(&cfg.Compression, "compression", cfg.Compression, "Archive compression: gzip (.tar.gz), or none to write an uncompressed .tar")
+	flags.StringVar(&cfg.TarFormat, "tar-format", cfg.TarFormat, "Archive tar format: pax, or gnu for tar implementations without PAX support")
+	flags.StringVar(&cfg.ArchiveOwner, "archive-owner", "", "Store every archive entry as owned by root or a numeric UID:GID instead of the source owners, e.g. the neo4j uid")
+	flags.StringVar(&cfg.ArchivePermissions, "archive-permissions", "", "Store every archived file with this octal mode, e.g. 0644; directories also get the matching execute bits")
+	flags.IntVar(&cfg.CompressionThreads, "compression-threads", cfg.CompressionThreads, "Number of threads used to compress the backup archive (1 disables parallel compression)")
+	flags.BoolVar(&cfg.RecompressDumps, "recompress-dumps", false, "Compress already-compressed files such as the pg_dump and Neo4j dumps again instead of storing them in the archive as they are")
+	flags.StringSliceVar(&cfg.PostgresDatabases, "postgres-database", nil, "Additional database in the task manager PostgreSQL instance to back up (repeatable); the Prefect database is always included")
+	flags.StringArrayVar(&cfg.PgExcludeTables, "pg-exclude-table", nil, "Table pattern passed to pg_dump --exclude-table: the table is left out of the task manager dumps entirely (repeatable)")
+	flags.StringArrayVar(&cfg.PgExcludeTableData, "pg-exclude-table-data", nil, "Table pattern passed to pg_dump --exclude-table-data: the table is dumped without its rows (repeatable)")
+	flags.BoolVar(&cfg.CleanupOnStart, "cleanup-on-start", false, "Remove staging data left in the database containers by earlier interrupted runs before backing up")
+	flags.BoolVar(&cfg.PgPreferReplica, "pg-prefer-replica", false, "Dump the task manager database from --postgres-replica-host when it is a standby within --pg-replica-max-lag, falling back to the primary")
+	flags.DurationVar(&cfg.PgReplicaMaxLag, "pg-replica-max-lag", cfg.PgReplicaMaxLag, "Largest replay lag at which --pg-prefer-replica still dumps from the replica")
+	flags.StringVar(&cfg.PgTempDir, "pg-temp-dir", "", "Writable directory in the task manager database container for the dump, tried before /tmp, /var/tmp, the parent of $PGDATA and /run")
+	flags.BoolVar(&cfg.LocalTime, "local-time", false, "Use the local time zone instead of UTC in the backup filename and the metadata created_at, as before UTC became the default")
+	flags.BoolVar(&cfg.NoOverwrite, "no-overwrite", false, "Fail instead of adding a numeric suffix when a backup with the same name already exists locally or in S3")
+	flags.StringVar(&cfg.MinDumpSize, "min-dump-size", cfg.MinDumpSize, "Fail the backup if a database dump is smaller than this (0 only rejects empty dumps)")
+	flags.StringVar(&cfg.MaxArchiveSize, "max-archive-size", "", "Fail the backup if the archive is larger than this, e.g. 50GB (default unlimited)")
+	flags.BoolVar(&cfg.BestEffort, "best-effort", false, "Back up every component that can be reached and write a partial archive, marked _partial and exiting with status 8, instead of aborting when one fails")
+	flags.IntVar(&cfg.OperationRetries, "operation-retries", 0, "Retry a failed backup this many times with exponential backoff, after it has cleaned up; checksum, edition and authentication errors are not retried")
+	flags.BoolVar(&cfg.KeepTemp, "keep-temp", false, "Keep the temporary working directory (and any diagnostic logs) after the backup")
+	flags.BoolVar(&cfg.Neo4jPortCheck, "neo4j-port-check", cfg.Neo4jPortCheck, "Neo4j Community: after the process stops, wait until nothing listens on the Bolt and HTTP ports before dumping (skipped when the container has neither ss nor netstat)")
+	flags.StringVar(&cfg.WatchdogMode, "watchdog-mode", "binary", "Neo4j Community watchdog: binary (falls back to shell), shell, or none (risks leaving Neo4j stopped if interrupted)")
+	flags.DurationVar(&cfg.WatchdogHeartbeatInterval, "watchdog-heartbeat-interval", cfg.WatchdogHeartbeatInterval, "How often to refresh the Neo4j Community watchdog heartbeat; the watchdog resumes Neo4j after 6 missed beats (0 disables)")
+	flags.BoolVar(&cfg.IncludeNeo4jLogs, "include-neo4j-logs", false, "Neo4j Community: copy the tail of the Neo4j log files into the working directory and the diagnostic bundle after the backup")
+	flags.StringSliceVar(&cfg.Neo4jLogPaths, "neo4j-log-paths", cfg.Neo4jLogPaths, "Neo4j log files in the database container captured by --include-neo4j-logs (comma-separated)")
+	flags.IntVar(&cfg.Neo4jLogLines, "neo4j-log-lines", cfg.Neo4jLogLines, "Number of lines kept from the end of each Neo4j log by --include-neo4j-logs")
+}
@@ -100,6 +100,6 @@ func main() {
 
 	if err := rootCmd.Execute(); err != nil {
 		logrus.Errorf("Command failed: %v", err)
-		os.Exit(1)
+		os.Exit(app.ExitCode(err))
 	}
 }
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -23,44 +27,213 @@ func ReadScript(name string) ([]byte, error) {
 
 // Configuration holds the application configuration
 type Configuration struct {
-	BackupDir            string
-	DockerComposeProject string
-	K8sNamespace         string
-	Neo4jUsername        string
-	Neo4jPassword        string
-	Neo4jDatabase        string
-	PostgresUsername     string
-	PostgresPassword     string
-	PostgresDatabase     string
+	BackupDir                 string
+	DockerComposeProject      string
+	K8sNamespace              string
+	K8sSelectors              map[string]string // service -> label selector overriding the conventional selectors
+	Neo4jUsername             string
+	Neo4jPassword             string
+	Neo4jDatabase             string
+	PostgresUsername          string
+	PostgresPassword          string
+	PostgresDatabase          string
+	PostgresDatabases         []string // additional task manager databases to back up
+	PgExcludeTables           []string // pg_dump --exclude-table patterns
+	PgExcludeTableData        []string // pg_dump --exclude-table-data patterns
+	Neo4jPasswordFile         string
+	Neo4jMode                 string
+	Neo4jHost                 string
+	Neo4jBackupCompress       bool
+	Neo4jOfflineEnterprise    bool   // stop the database and dump it instead of the online backup
+	Neo4jEdition              string // community or enterprise, overriding detection
+	Neo4jPortCheck            bool   // wait for the Bolt and HTTP ports to close after stopping Community
+	ExcludeNeo4jAuth          bool   // leave the Community users (system database and auth files) out
+	ExcludeNeo4j              bool   // set from --exclude-components or --include-components
+	ExcludeComponents         []string
+	IncludeComponents         []string // restore only
+	Neo4jMetadataScript       string
+	SkipMetadataRestore       bool
+	PostgresPasswordFile      string
+	PostgresHost              string
+	PostgresPort              int
+	PostgresReplicaHost       string        // standby that --pg-prefer-replica dumps from
+	PostgresReplicaPort       int           // defaults to PostgresPort
+	PgPreferReplica           bool          // dump from the replica when it is caught up
+	PgReplicaMaxLag           time.Duration // replay lag above which the primary is dumped instead
+	PostgresClientService     string
+	PgTempDir                 string // preferred directory for pg_dump output inside the PostgreSQL client service
+	PgRestoreDB               string
+	PgRestoreNoClean          bool
+	PgRestoreNoCreate         bool
+	PgRestoreOpts             string
+	KeepTemp                  bool
+	CleanupOnStart            bool // remove staging data left in the containers by earlier runs
+	NoParallel                bool // run independent restore steps sequentially
+	NoStreamRestore           bool // copy the Neo4j Community dump into the container instead of streaming it
+	NoRestart                 bool
+	OperationRetries          int  // extra attempts of a failed backup
+	CopyRetries               int  // extra attempts of a failed copy to or from a container
+	MaxOpenFiles              int  // backup files read at once while computing and validating checksums
+	BestEffort                bool // archive the components that could be backed up instead of aborting
+	NoOverwrite               bool
+	ConfirmDestructive        bool
+	ValidateOnly              bool   // stop a restore after the archive has been verified, before any mutation
+	StrictChecksums           bool   // fail a restore when the archive holds files without a checksum
+	SchemaOnly                bool   // only apply the Neo4j constraints and indexes captured in the backup
+	TargetProject             string // restore into this Docker Compose project, without auto-detection
+	TargetNamespace           string // restore into this Kubernetes namespace, without auto-detection
+	HealthAfterRestore        bool
+	HealthTimeout             time.Duration
+	Warmup                    bool // wait for Neo4j indexes and warm caches after a restore
+	WarmupTimeout             time.Duration
+	NoGraphStats              bool    // skip recording the Neo4j node and relationship counts in a backup
+	Neo4jRestoreVerify        bool    // count Neo4j nodes and relationships after a restore
+	Neo4jVerifyTolerance      float64 // percent the counts may differ from the backup
+	OutputFormat              string
+	Quiet                     bool   // only warnings, errors and the final result
+	DryRun                    bool   // preview retention and cleanup deletions without deleting
+	AuditLog                  string // JSON lines file recording every backend operation
+	SummaryFile               string // human-readable report written after backup/restore
+	SummaryOnFailure          bool   // write a diagnostic bundle when a backup or restore fails
+	RecordBackend             string // capture file for RecordingBackend
+	ReplayBackend             string // capture file replayed instead of a real deployment
+	IncludeConfig             bool
+	DumpOnly                  bool     // write only the Neo4j dump and dump_information.json
+	DumpDir                   string   // parent directory of --dump-only output (default BackupDir)
+	OutputDir                 string   // directory for a one-off archive kept out of the managed BackupDir
+	LocalTime                 bool     // name and date backups in the local time zone instead of UTC
+	ExcludeFiles              []string // glob patterns removed from the config component before archiving
+	IntegrityKey              string
+	IntegrityKeyFile          string
+	IntegrityKeyring          string // directory of previous integrity keys, selected by the key identifier in the metadata
+	CompressionThreads        int
+	RecompressDumps           bool   // deflate already-compressed dumps again instead of storing them
+	TarFormat                 string // pax or gnu
+	Compression               string // archive codec: gzip or none
+	ArchiveOwner              string // root or UID:GID stored for every archive entry; empty keeps the source owners
+	ArchivePermissions        string // octal mode stored for every archived file; empty keeps the source modes
+	Label                     string // free-form label recorded in the backup metadata
+	MaxArchiveSize            string
+	MinDumpSize               string
+	WatchdogMode              string
+	WatchdogHeartbeatInterval time.Duration
+	IncludeNeo4jLogs          bool     // capture the tail of the Neo4j log files around a Community backup or restore
+	Neo4jLogPaths             []string // Neo4j log files in the database container
+	Neo4jLogLines             int
+	// Commands of the external tools, for images that install them off PATH
+	Neo4jAdminPath  string
+	CypherShellPath string
+	PgDumpPath      string
+	PgRestorePath   string
+	KubectlPath     string
+	DockerPath      string
 	// S3 configuration
-	S3Upload        bool
-	S3Bucket        string
-	S3Endpoint      string
-	S3AccessKeyID   string
-	S3SecretKey     string
-	S3Region        string
+	S3Upload      bool
+	S3Bucket      string
+	S3Endpoint    string
+	S3AccessKeyID string
+	S3SecretKey   string
+	S3SecretFile  string
+	S3Region      string
+	// Region used to sign requests when it differs from the bucket region
+	S3SigningRegion string
+	S3Proxy         string
+	S3Prefix        string // key prefix of backup objects, empty or ending in "/"
+	S3ContentType   string // overrides the Content-Type detected from the archive
+	S3MaxRetries    int
+	S3HTTPTimeout   time.Duration
+	// TLS trust for S3-compatible endpoints with an internal CA
+	S3CABundle           string
+	S3InsecureSkipVerify bool
+	// Resumable multipart upload checkpointed to <archive>.s3state
+	S3ResumeUpload bool
+	S3ResumeMaxAge time.Duration
+	// Upload under year=YYYY/month=MM/day=DD/ below the prefix
+	S3DatePartition bool
+	// Delete the local archive after the upload, once verified unless forced
+	S3DeleteLocal             bool
+	VerifyS3BeforeDeleteLocal bool
+	ForceDeleteLocal          bool
+	// AWS shared files used through the default credential chain when no access key is set
+	S3SharedCredentialsFile string
+	S3SharedConfigFile      string
+
+	// Set when the target came from the "environment use" context file
+	environmentContextFile string
 }
 
 // InfrahubOps is the main application struct
 type InfrahubOps struct {
 	config                  *Configuration
-	backend                 EnvironmentBackend
+	backend                 Backend
 	executor                *CommandExecutor
 	dockerBackend           *DockerBackend
 	kubernetesBackend       *KubernetesBackend
 	infrahubInternalAddress string // cached INFRAHUB_INTERNAL_ADDRESS from task-worker
+	auditLog                *auditLog
+	report                  atomic.Pointer[operationReport] // active --summary-file or --summary-on-failure report, read by the log hook
+	reportHookOnce          sync.Once
+	files                   *fileLimiter           // bounds the backup files read at once to MaxOpenFiles
+	s3BucketRegion          atomic.Pointer[string] // region S3 reported for the bucket when it differs from S3_REGION
 }
 
 // NewInfrahubOps creates a new InfrahubOps instance
 func NewInfrahubOps() *InfrahubOps {
-	executor := NewCommandExecutor()
-	config := &Configuration{
-		BackupDir:    getEnvOrDefault("BACKUP_DIR", filepath.Join(getCurrentDir(), "infrahub_backups")),
-		K8sNamespace: os.Getenv("INFRAHUB_K8S_NAMESPACE"),
+	cfg := DefaultConfiguration()
+	return &InfrahubOps{
+		config:   cfg,
+		executor: NewCommandExecutor(),
+		files:    newFileLimiter(cfg.MaxOpenFiles),
+	}
+}
+
+// NewInfrahubOpsWithBackend creates an InfrahubOps that uses backend instead of detecting
+// the environment. A nil cfg uses DefaultConfiguration. Programs outside this module use
+// infrahubops.NewWithBackend.
+func NewInfrahubOpsWithBackend(cfg *Configuration, backend Backend) *InfrahubOps {
+	if cfg == nil {
+		cfg = DefaultConfiguration()
 	}
 	return &InfrahubOps{
-		config:   config,
-		executor: executor,
+		config:   cfg,
+		backend:  backend,
+		executor: NewCommandExecutor(),
+		files:    newFileLimiter(cfg.MaxOpenFiles),
+	}
+}
+
+// DefaultConfiguration returns the configuration defaults, including values read from the environment.
+func DefaultConfiguration() *Configuration {
+	return &Configuration{
+		BackupDir:                 getEnvOrDefault("BACKUP_DIR", filepath.Join(getCurrentDir(), "infrahub_backups")),
+		K8sNamespace:              os.Getenv("INFRAHUB_K8S_NAMESPACE"),
+		PostgresHost:              defaultPostgresHost,
+		PostgresPort:              defaultPostgresPort,
+		PgReplicaMaxLag:           defaultPgReplicaMaxLag,
+		WatchdogHeartbeatInterval: defaultWatchdogHeartbeatInterval,
+		Neo4jLogPaths:             defaultNeo4jLogPaths,
+		Neo4jLogLines:             defaultNeo4jLogLines,
+		CompressionThreads:        runtime.NumCPU(),
+		TarFormat:                 "pax",
+		Compression:               archiveCompressionGzip,
+		Neo4jBackupCompress:       true,
+		Neo4jPortCheck:            true,
+		MinDumpSize:               defaultMinDumpSize,
+		HealthTimeout:             defaultHealthTimeout,
+		WarmupTimeout:             defaultWarmupTimeout,
+		Neo4jVerifyTolerance:      defaultNeo4jVerifyTolerance,
+		S3MaxRetries:              defaultS3MaxRetries,
+		CopyRetries:               defaultCopyRetries,
+		MaxOpenFiles:              defaultMaxOpenFiles,
+		S3HTTPTimeout:             defaultS3HTTPTimeout,
+		S3ResumeMaxAge:            defaultS3ResumeMaxAge,
+		VerifyS3BeforeDeleteLocal: true,
+		Neo4jAdminPath:            neo4jAdminTool,
+		CypherShellPath:           cypherShellTool,
+		PgDumpPath:                pgDumpTool,
+		PgRestorePath:             pgRestoreTool,
+		KubectlPath:               kubectlTool,
+		DockerPath:                dockerTool,
 	}
 }
 
@@ -82,9 +255,9 @@ func (iops *InfrahubOps) getKubernetesBackend() *KubernetesBackend {
 	return iops.kubernetesBackend
 }
 
-func (iops *InfrahubOps) backendOrder() []EnvironmentBackend {
-	order := []EnvironmentBackend{}
-	add := func(backend EnvironmentBackend) {
+func (iops *InfrahubOps) backendOrder() []Backend {
+	order := []Backend{}
+	add := func(backend Backend) {
 		if backend == nil {
 			return
 		}
@@ -109,9 +282,23 @@ func (iops *InfrahubOps) backendOrder() []EnvironmentBackend {
 	return order
 }
 
-func (iops *InfrahubOps) ensureBackend() (EnvironmentBackend, error) {
+func (iops *InfrahubOps) ensureBackend() (Backend, error) {
+	if iops.backend == nil && iops.config.ReplayBackend != "" {
+		replay, err := NewReplayBackend(iops.config.ReplayBackend, iops.config)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Warnf("Replaying backend interactions from %s (%s %s); no deployment is contacted", iops.config.ReplayBackend, replay.Name(), replay.Info())
+		iops.backend = replay
+	}
 	if iops.backend != nil {
-		return iops.backend, nil
+		// Injected and replayed backends are wrapped on first use
+		backend, err := iops.decorateBackend(iops.backend)
+		if err != nil {
+			return nil, err
+		}
+		iops.backend = backend
+		return backend, nil
 	}
 
 	detectionErrors := []string{}
@@ -125,16 +312,20 @@ func (iops *InfrahubOps) ensureBackend() (EnvironmentBackend, error) {
 			}
 			continue
 		}
-		iops.backend = backend
 		logrus.Infof("Detected %s environment (%s)", backend.Name(), backend.Info())
-		return backend, nil
+		audited, err := iops.decorateBackend(backend)
+		if err != nil {
+			return nil, err
+		}
+		iops.backend = audited
+		return audited, nil
 	}
 
 	if len(detectionErrors) > 0 {
-		return nil, fmt.Errorf("environment detection errors: %s", strings.Join(detectionErrors, "; "))
+		return nil, fmt.Errorf("%w: detection errors: %s", ErrEnvironmentNotFound, strings.Join(detectionErrors, "; "))
 	}
 
-	return nil, fmt.Errorf("no Infrahub environment detected")
+	return nil, fmt.Errorf("%w: no Infrahub deployment detected", ErrEnvironmentNotFound)
 }
 
 func (iops *InfrahubOps) Exec(service string, command []string, opts *ExecOptions) (string, error) {
@@ -142,7 +333,11 @@ func (iops *InfrahubOps) Exec(service string, command []string, opts *ExecOption
 	if err != nil {
 		return "", err
 	}
-	return backend.Exec(service, command, opts)
+	output, err := backend.Exec(service, command, opts)
+	if err != nil {
+		iops.report.Load().recordFailedCommand(service, command, err)
+	}
+	return output, err
 }
 
 // getInfrahubInternalAddress fetches and caches INFRAHUB_INTERNAL_ADDRESS from task-worker.
@@ -205,53 +400,64 @@ func (iops *InfrahubOps) ExecStream(service string, command []string, opts *Exec
 	if err != nil {
 		return "", err
 	}
-	return backend.ExecStream(service, command, opts)
+	output, err := backend.ExecStream(service, command, opts)
+	if err != nil {
+		iops.report.Load().recordFailedCommand(service, command, err)
+	}
+	return output, err
 }
 
-func (iops *InfrahubOps) CopyTo(service, src, dest string) error {
+func (iops *InfrahubOps) StartServices(services ...string) error {
 	backend, err := iops.ensureBackend()
 	if err != nil {
 		return err
 	}
-	return backend.CopyTo(service, src, dest)
+	return backend.Start(services...)
 }
 
-func (iops *InfrahubOps) CopyFrom(service, src, dest string) error {
+func (iops *InfrahubOps) StopServices(services ...string) error {
 	backend, err := iops.ensureBackend()
 	if err != nil {
 		return err
 	}
-	return backend.CopyFrom(service, src, dest)
+	return backend.Stop(services...)
 }
 
-func (iops *InfrahubOps) StartServices(services ...string) error {
+func (iops *InfrahubOps) IsServiceRunning(service string) (bool, error) {
 	backend, err := iops.ensureBackend()
 	if err != nil {
-		return err
+		return false, err
 	}
-	return backend.Start(services...)
+	return backend.IsRunning(service)
 }
 
-func (iops *InfrahubOps) StopServices(services ...string) error {
+// HasService reports whether the deployment has the service, running or not.
+func (iops *InfrahubOps) HasService(service string) (bool, error) {
 	backend, err := iops.ensureBackend()
 	if err != nil {
-		return err
+		return false, err
 	}
-	return backend.Stop(services...)
+	return hasService(backend, service)
 }
 
-func (iops *InfrahubOps) IsServiceRunning(service string) (bool, error) {
+func (iops *InfrahubOps) IsServiceReady(service string) (bool, error) {
 	backend, err := iops.ensureBackend()
 	if err != nil {
 		return false, err
 	}
-	return backend.IsRunning(service)
+	return backend.IsReady(service)
 }
 
 // Prerequisites checker
 func (iops *InfrahubOps) checkPrerequisites() error {
-	// Docker and kubectl are now optional. This function always succeeds.
-	return nil
+	// Docker and kubectl are now optional; only the Neo4j settings are validated.
+	if _, err := iops.resolveNeo4jMode(); err != nil {
+		return fmt.Errorf("%w: %w", ErrPrerequisites, err)
+	}
+	if _, err := normalizeNeo4jEdition(iops.config.Neo4jEdition); err != nil {
+		return fmt.Errorf("%w: %w", ErrPrerequisites, err)
+	}
+	return iops.checkLocalToolPaths()
 }
 
 // Environment detection
@@ -270,7 +476,7 @@ func (iops *InfrahubOps) DetectEnvironment() error {
 		return fmt.Errorf("could not fetch database credentials: %w", err)
 	}
 
-	return nil
+	return iops.checkContainerToolPaths()
 }
 
 func (iops *InfrahubOps) getInfrahubVersion() string {
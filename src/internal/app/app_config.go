@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"os"
 	"regexp"
 	"strings"
@@ -25,7 +26,11 @@ func (iops *InfrahubOps) fetchDatabaseCredentials() error {
 		return err
 	}
 
-	// Try to get credentials from environment first
+	// Flags are applied already; each later source only fills what is still missing, so
+	// flags win over secret files, which win over the environment and the containers
+	if err := iops.loadCredentialsFromFiles(); err != nil {
+		return err
+	}
 	iops.loadCredentialsFromEnvironment()
 
 	// Fetch Neo4j credentials if not fully configured
@@ -47,21 +52,55 @@ func (iops *InfrahubOps) fetchDatabaseCredentials() error {
 	return nil
 }
 
-// loadCredentialsFromEnvironment loads credentials from environment variables
+// loadCredentialsFromEnvironment loads the credentials not set by flags or secret files from
+// environment variables
 func (iops *InfrahubOps) loadCredentialsFromEnvironment() {
-	if value := os.Getenv("INFRAHUB_DB_DATABASE"); value != "" {
+	if value := os.Getenv("INFRAHUB_DB_DATABASE"); value != "" && iops.config.Neo4jDatabase == "" {
 		iops.config.Neo4jDatabase = value
 	}
-	if value := os.Getenv("INFRAHUB_DB_USERNAME"); value != "" {
+	if value := os.Getenv("INFRAHUB_DB_USERNAME"); value != "" && iops.config.Neo4jUsername == "" {
 		iops.config.Neo4jUsername = value
 	}
-	if value := os.Getenv("INFRAHUB_DB_PASSWORD"); value != "" {
+	if value := os.Getenv("INFRAHUB_DB_PASSWORD"); value != "" && iops.config.Neo4jPassword == "" {
 		iops.config.Neo4jPassword = value
 	}
 
 	iops.applyPrefectConnection(os.Getenv("PREFECT_API_DATABASE_CONNECTION_URL"))
 }
 
+// loadCredentialsFromFiles loads database passwords from mounted secret files, unless
+// --neo4j-password or --postgres-password set them
+func (iops *InfrahubOps) loadCredentialsFromFiles() error {
+	if iops.config.Neo4jPasswordFile != "" && iops.config.Neo4jPassword == "" {
+		password, err := readSecretFile(iops.config.Neo4jPasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read Neo4j password file: %w", err)
+		}
+		iops.config.Neo4jPassword = password
+	}
+	if iops.config.PostgresPasswordFile != "" && iops.config.PostgresPassword == "" {
+		password, err := readSecretFile(iops.config.PostgresPasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read PostgreSQL password file: %w", err)
+		}
+		iops.config.PostgresPassword = password
+	}
+	return nil
+}
+
+// readSecretFile reads a secret from a file, trimming the trailing newline
+func readSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(content), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
 // hasNeo4jCredentials checks if all Neo4j credentials are configured
 func (iops *InfrahubOps) hasNeo4jCredentials() bool {
 	return iops.config.Neo4jDatabase != "" &&
@@ -141,7 +180,8 @@ func (iops *InfrahubOps) applyPostgresDefaults() {
 	}
 }
 
-// applyPrefectConnection parses and applies Prefect database connection string
+// applyPrefectConnection parses a Prefect database connection string and applies the
+// settings that are not configured yet
 func (iops *InfrahubOps) applyPrefectConnection(connStr string) {
 	if connStr == "" {
 		return
@@ -157,13 +197,13 @@ func (iops *InfrahubOps) applyPrefectConnection(connStr string) {
 		return
 	}
 
-	if connConfig.Database != "" {
+	if connConfig.Database != "" && iops.config.PostgresDatabase == "" {
 		iops.config.PostgresDatabase = connConfig.Database
 	}
-	if connConfig.User != "" {
+	if connConfig.User != "" && iops.config.PostgresUsername == "" {
 		iops.config.PostgresUsername = connConfig.User
 	}
-	if connConfig.Password != "" {
+	if connConfig.Password != "" && iops.config.PostgresPassword == "" {
 		iops.config.PostgresPassword = connConfig.Password
 	}
 }
@@ -0,0 +1,16 @@
+package app
+
+import (
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+// newTestOps returns an InfrahubOps driven by fake, with copies failing on the first error.
+func newTestOps(t *testing.T, fake *apptest.FakeBackend) *InfrahubOps {
+	t.Helper()
+	cfg := DefaultConfiguration()
+	cfg.BackupDir = t.TempDir()
+	cfg.CopyRetries = 0
+	return NewInfrahubOpsWithBackend(cfg, fake)
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditRecord is one line of the --audit-log file.
+type auditRecord struct {
+	Time       string   `json:"time"`
+	Operation  string   `json:"operation"`
+	Backend    string   `json:"backend"`
+	Target     string   `json:"target,omitempty"`
+	Service    string   `json:"service,omitempty"`
+	Services   []string `json:"services,omitempty"`
+	Command    []string `json:"command,omitempty"`
+	User       string   `json:"user,omitempty"`
+	EnvKeys    []string `json:"env_keys,omitempty"`
+	Src        string   `json:"src,omitempty"`
+	Dest       string   `json:"dest,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+	Status     string   `json:"status"`
+	ExitCode   *int     `json:"exit_code,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// auditLog appends JSON lines to the audit file.
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &auditLog{file: file}, nil
+}
+
+func (l *auditLog) write(record auditRecord) error {
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(record); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.file.Write(line.Bytes())
+	return err
+}
+
+// auditBackend decorates a Backend and records every operation it performs. Secrets are
+// looked up from the configuration at call time because credentials are fetched after
+// the backend has been detected.
+type auditBackend struct {
+	Backend
+	log    *auditLog
+	config *Configuration
+}
+
+func (a *auditBackend) Exec(service string, command []string, opts *ExecOptions) (string, error) {
+	start := time.Now()
+	output, err := a.Backend.Exec(service, command, opts)
+	a.record(auditRecord{Operation: "exec", Service: service, Command: command}, opts, start, err)
+	return output, err
+}
+
+func (a *auditBackend) ExecStream(service string, command []string, opts *ExecOptions) (string, error) {
+	start := time.Now()
+	output, err := a.Backend.ExecStream(service, command, opts)
+	a.record(auditRecord{Operation: "exec_stream", Service: service, Command: command}, opts, start, err)
+	return output, err
+}
+
+func (a *auditBackend) CopyTo(service, src, dest string) error {
+	start := time.Now()
+	err := a.Backend.CopyTo(service, src, dest)
+	a.record(auditRecord{Operation: "copy_to", Service: service, Src: src, Dest: dest}, nil, start, err)
+	return err
+}
+
+func (a *auditBackend) CopyFrom(service, src, dest string) error {
+	start := time.Now()
+	err := a.Backend.CopyFrom(service, src, dest)
+	a.record(auditRecord{Operation: "copy_from", Service: service, Src: src, Dest: dest}, nil, start, err)
+	return err
+}
+
+func (a *auditBackend) Start(services ...string) error {
+	start := time.Now()
+	err := a.Backend.Start(services...)
+	a.record(auditRecord{Operation: "start", Services: services}, nil, start, err)
+	return err
+}
+
+func (a *auditBackend) Stop(services ...string) error {
+	start := time.Now()
+	err := a.Backend.Stop(services...)
+	a.record(auditRecord{Operation: "stop", Services: services}, nil, start, err)
+	return err
+}
+
+func (a *auditBackend) IsRunning(service string) (bool, error) {
+	start := time.Now()
+	running, err := a.Backend.IsRunning(service)
+	a.record(auditRecord{Operation: "is_running", Service: service}, nil, start, err)
+	return running, err
+}
+
+func (a *auditBackend) IsReady(service string) (bool, error) {
+	start := time.Now()
+	ready, err := a.Backend.IsReady(service)
+	a.record(auditRecord{Operation: "is_ready", Service: service}, nil, start, err)
+	return ready, err
+}
+
+func (a *auditBackend) HasService(service string) (bool, error) {
+	start := time.Now()
+	exists, err := hasService(a.Backend, service)
+	a.record(auditRecord{Operation: "has_service", Service: service}, nil, start, err)
+	return exists, err
+}
+
+func (a *auditBackend) CaptureConfig(destDir string) error {
+	start := time.Now()
+	err := a.Backend.CaptureConfig(destDir)
+	a.record(auditRecord{Operation: "capture_config", Dest: destDir}, nil, start, err)
+	return err
+}
+
+func (a *auditBackend) record(record auditRecord, opts *ExecOptions, start time.Time, err error) {
+	record.Time = start.UTC().Format(time.RFC3339Nano)
+	record.Backend = a.Backend.Name()
+	record.Target = a.Backend.Info()
+	record.DurationMS = time.Since(start).Milliseconds()
+
+	redact := secretRedactor(a.config)
+	record.Command = redactArgs(redact, record.Command)
+	if opts != nil {
+		record.User = opts.User
+		for key := range opts.Env {
+			record.EnvKeys = append(record.EnvKeys, key)
+		}
+		slices.Sort(record.EnvKeys)
+	}
+
+	record.Status = "ok"
+	if err != nil {
+		record.Status = "error"
+		record.Error = redact(err.Error())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code := exitErr.ExitCode()
+			record.ExitCode = &code
+		}
+	} else if record.Operation == "exec" || record.Operation == "exec_stream" {
+		code := 0
+		record.ExitCode = &code
+	}
+
+	if writeErr := a.log.write(record); writeErr != nil {
+		fmt.Fprintf(os.Stderr, "failed to write audit log: %v\n", writeErr)
+	}
+}
+
+// secretRedactor masks configured secrets wherever they appear, then applies the same key=value
+// and URL credential redaction used for configuration snapshots.
+func secretRedactor(cfg *Configuration) func(string) string {
+	var secrets []string
+	for _, secret := range []string{cfg.Neo4jPassword, cfg.PostgresPassword, cfg.S3SecretKey, cfg.IntegrityKey} {
+		if secret != "" {
+			secrets = append(secrets, secret, "<redacted>")
+		}
+	}
+	replacer := strings.NewReplacer(secrets...)
+	return func(value string) string {
+		return redactConfig(replacer.Replace(value))
+	}
+}
+
+// decorateBackend wraps backend in the recording (--record-backend) and audit (--audit-log)
+// decorators as configured.
+func (iops *InfrahubOps) decorateBackend(backend Backend) (Backend, error) {
+	if _, ok := backend.(*auditBackend); ok {
+		return backend, nil
+	}
+	if _, ok := backend.(*RecordingBackend); !ok && iops.config.RecordBackend != "" {
+		recording, err := NewRecordingBackend(backend, iops.config.RecordBackend, iops.config)
+		if err != nil {
+			return nil, err
+		}
+		backend = recording
+	}
+	if iops.config.AuditLog == "" {
+		return backend, nil
+	}
+	if iops.auditLog == nil {
+		log, err := openAuditLog(iops.config.AuditLog)
+		if err != nil {
+			return nil, err
+		}
+		iops.auditLog = log
+	}
+	return &auditBackend{Backend: backend, log: iops.auditLog, config: iops.config}, nil
+}
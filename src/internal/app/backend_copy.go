@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultCopyRetries = 2
+	copyRetryBaseDelay = 5 * time.Second
+	copyRetryMaxDelay  = time.Minute
+	copyDirectionTo    = "to"
+	copyDirectionFrom  = "from"
+)
+
+// CopyTo copies src on this machine to dest in service. A failed or truncated copy is retried
+// up to --copy-retries times, after the partial dest has been removed.
+func (iops *InfrahubOps) CopyTo(service, src, dest string) error {
+	backend, err := iops.ensureBackend()
+	if err != nil {
+		return err
+	}
+	return iops.retryCopy(copyDirectionTo, service, src, dest,
+		func() error {
+			if err := backend.CopyTo(service, src, dest); err != nil {
+				return err
+			}
+			return iops.verifyCopySize(service, dest, src)
+		},
+		func() { iops.removeRemotePaths(service, dest) },
+	)
+}
+
+// CopyFrom copies src in service to dest on this machine. A failed or truncated copy is
+// retried up to --copy-retries times, after the partial dest has been removed unless it
+// existed before.
+func (iops *InfrahubOps) CopyFrom(service, src, dest string) error {
+	backend, err := iops.ensureBackend()
+	if err != nil {
+		return err
+	}
+	_, statErr := os.Stat(dest)
+	existed := statErr == nil
+	return iops.retryCopy(copyDirectionFrom, service, src, dest,
+		func() error {
+			if err := backend.CopyFrom(service, src, dest); err != nil {
+				return err
+			}
+			return iops.verifyCopySize(service, src, dest)
+		},
+		func() {
+			if existed {
+				return
+			}
+			if err := os.RemoveAll(dest); err != nil {
+				logrus.Warnf("Failed to remove partial copy %s: %v", dest, err)
+			}
+		},
+	)
+}
+
+// verifyCopySize compares the size of the file remote in service with the local file, since
+// docker compose cp and kubectl cp can exit successfully after a truncated transfer.
+// Directories are not compared, and neither are files whose remote size can't be read, for
+// images without stat.
+func (iops *InfrahubOps) verifyCopySize(service, remote, local string) error {
+	info, err := os.Stat(local)
+	if err != nil {
+		return fmt.Errorf("copy of %s left no readable file: %w", local, err)
+	}
+	if info.IsDir() {
+		return nil
+	}
+	output, err := iops.Exec(service, []string{"stat", "-c", "%s", remote}, nil)
+	if err != nil {
+		logrus.Debugf("Could not read the size of %s in %s to verify the copy: %v", remote, service, err)
+		return nil
+	}
+	remoteSize, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		logrus.Debugf("Could not read the size of %s in %s to verify the copy: %q", remote, service, output)
+		return nil
+	}
+	if remoteSize != info.Size() {
+		return fmt.Errorf("copy is incomplete: %s in %s has %d bytes, %s has %d", remote, service, remoteSize, local, info.Size())
+	}
+	return nil
+}
+
+// retryCopy runs a copy until it succeeds or the attempts are used up, calling cleanup and
+// backing off between attempts. A replayed backend is never retried: its answers are fixed.
+func (iops *InfrahubOps) retryCopy(direction, service, src, dest string, run func() error, cleanup func()) error {
+	attempts := max(iops.config.CopyRetries, 0) + 1
+	if iops.config.ReplayBackend != "" {
+		attempts = 1
+	}
+	fields := logrus.Fields{"direction": direction, "service": service, "source": src, "destination": dest}
+	delay := copyRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil {
+			if attempt > 1 {
+				logrus.WithFields(fields).WithField("attempt", attempt).Info("Copy succeeded after retrying")
+			}
+			return nil
+		}
+		if attempt >= attempts {
+			iops.report.Load().recordFailedCommand(service, []string{"copy", direction, src, dest}, err)
+			if attempts > 1 {
+				return fmt.Errorf("copy failed after %d attempts: %w", attempts, err)
+			}
+			return err
+		}
+		logrus.WithFields(fields).WithFields(logrus.Fields{
+			"attempt":      attempt,
+			"max_attempts": attempts,
+			"retry_in":     delay.String(),
+		}).Warnf("Copy failed: %v", err)
+		cleanup()
+		time.Sleep(delay)
+		delay = min(delay*2, copyRetryMaxDelay)
+	}
+}
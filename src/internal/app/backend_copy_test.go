@@ -0,0 +1,57 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestCopyFromVerifiesSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteSize apptest.Response
+		wantErr    string
+	}{
+		{name: "same size", remoteSize: apptest.Response{Output: "11\n"}},
+		{name: "truncated", remoteSize: apptest.Response{Output: "4096\n"}, wantErr: "copy is incomplete"},
+		{name: "no stat in the image", remoteSize: apptest.Response{Output: "sh: stat: not found"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := apptest.NewFakeBackend("database")
+			fake.SetFile("database", "/tmp/neo4j.dump", []byte("dump output"))
+			fake.On("database", []string{"stat", "-c", "%s"}, tt.remoteSize)
+			iops := newTestOps(t, fake)
+
+			dest := filepath.Join(t.TempDir(), "neo4j.dump")
+			err := iops.CopyFrom("database", "/tmp/neo4j.dump", dest)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CopyFrom() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CopyFrom() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCopyToVerifiesSize(t *testing.T) {
+	fake := apptest.NewFakeBackend("database")
+	fake.On("database", []string{"stat", "-c", "%s", "/tmp/watchdog"}, apptest.Response{Output: "3\n"})
+	iops := newTestOps(t, fake)
+	src := filepath.Join(t.TempDir(), "watchdog")
+	if err := os.WriteFile(src, []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := iops.CopyTo("database", src, "/tmp/watchdog")
+	if err == nil || !strings.Contains(err.Error(), "copy is incomplete") {
+		t.Fatalf("CopyTo() = %v, want an incomplete copy", err)
+	}
+}
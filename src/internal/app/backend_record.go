@@ -0,0 +1,497 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const backendCaptureVersion = 2
+
+// backendCapture is the first line of the JSON lines file written by RecordingBackend and
+// read by ReplayBackend; every following line is a backendInteraction.
+type backendCapture struct {
+	Version    int    `json:"version"`
+	RecordedAt string `json:"recorded_at"`
+	Backend    string `json:"backend"`
+	Target     string `json:"target"`
+}
+
+// backendInteraction is one recorded backend call. File payloads are stored as references
+// (size and SHA-256), never as content; a copied directory lists its files.
+type backendInteraction struct {
+	Operation string        `json:"operation"`
+	Service   string        `json:"service,omitempty"`
+	Services  []string      `json:"services,omitempty"`
+	Command   []string      `json:"command,omitempty"`
+	User      string        `json:"user,omitempty"`
+	EnvKeys   []string      `json:"env_keys,omitempty"`
+	Src       string        `json:"src,omitempty"`
+	Dest      string        `json:"dest,omitempty"`
+	Size      int64         `json:"size,omitempty"`
+	SHA256    string        `json:"sha256,omitempty"`
+	Dir       bool          `json:"dir,omitempty"`
+	Files     []backendFile `json:"files,omitempty"`
+	Output    string        `json:"output,omitempty"`
+	Running   bool          `json:"running,omitempty"`
+	Exists    bool          `json:"exists,omitempty"`
+	Ready     bool          `json:"ready,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	ExitCode  int           `json:"exit_code,omitempty"`
+}
+
+// backendFile is one file of a directory copied in or out of a service, relative to it.
+type backendFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// RecordingBackend wraps a Backend and appends every interaction to a capture file that
+// ReplayBackend can replay offline. Commands, outputs and errors are redacted with the
+// secrets known when the line is written, like the audit log; credentials read from the
+// deployment later are caught by the key=value redaction of the output that carried them.
+// Watchdog heartbeat refreshes are not recorded, since their number depends on timing.
+type RecordingBackend struct {
+	Backend
+	config *Configuration
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecordingBackend records the interactions of backend to path, replacing any previous capture.
+func NewRecordingBackend(backend Backend, path string, cfg *Configuration) (*RecordingBackend, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backend capture: %w", err)
+	}
+	r := &RecordingBackend{Backend: backend, config: cfg, file: file}
+	header := backendCapture{
+		Version:    backendCaptureVersion,
+		RecordedAt: time.Now().UTC().Format(time.RFC3339),
+		Backend:    backend.Name(),
+		Target:     backend.Info(),
+	}
+	if err := r.writeLine(header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write backend capture: %w", err)
+	}
+	return r, nil
+}
+
+func (r *RecordingBackend) Exec(service string, command []string, opts *ExecOptions) (string, error) {
+	output, err := r.Backend.Exec(service, command, opts)
+	if isWatchdogHeartbeat(service, command) {
+		return output, err
+	}
+	r.record(execInteraction("exec", service, command, opts, output, err))
+	return output, err
+}
+
+func (r *RecordingBackend) ExecStream(service string, command []string, opts *ExecOptions) (string, error) {
+	output, err := r.Backend.ExecStream(service, command, opts)
+	r.record(execInteraction("exec_stream", service, command, opts, output, err))
+	return output, err
+}
+
+func (r *RecordingBackend) CopyTo(service, src, dest string) error {
+	err := r.Backend.CopyTo(service, src, dest)
+	interaction := backendInteraction{Operation: "copy_to", Service: service, Src: src, Dest: dest}
+	interaction.setReference(src)
+	r.record(withError(interaction, err))
+	return err
+}
+
+func (r *RecordingBackend) CopyFrom(service, src, dest string) error {
+	err := r.Backend.CopyFrom(service, src, dest)
+	interaction := backendInteraction{Operation: "copy_from", Service: service, Src: src, Dest: dest}
+	if err == nil {
+		interaction.setReference(dest)
+	}
+	r.record(withError(interaction, err))
+	return err
+}
+
+func (r *RecordingBackend) Start(services ...string) error {
+	err := r.Backend.Start(services...)
+	r.record(withError(backendInteraction{Operation: "start", Services: services}, err))
+	return err
+}
+
+func (r *RecordingBackend) Stop(services ...string) error {
+	err := r.Backend.Stop(services...)
+	r.record(withError(backendInteraction{Operation: "stop", Services: services}, err))
+	return err
+}
+
+func (r *RecordingBackend) IsRunning(service string) (bool, error) {
+	running, err := r.Backend.IsRunning(service)
+	r.record(withError(backendInteraction{Operation: "is_running", Service: service, Running: running}, err))
+	return running, err
+}
+
+func (r *RecordingBackend) IsReady(service string) (bool, error) {
+	ready, err := r.Backend.IsReady(service)
+	r.record(withError(backendInteraction{Operation: "is_ready", Service: service, Ready: ready}, err))
+	return ready, err
+}
+
+func (r *RecordingBackend) HasService(service string) (bool, error) {
+	exists, err := hasService(r.Backend, service)
+	r.record(withError(backendInteraction{Operation: "has_service", Service: service, Exists: exists}, err))
+	return exists, err
+}
+
+func (r *RecordingBackend) CaptureConfig(destDir string) error {
+	err := r.Backend.CaptureConfig(destDir)
+	r.record(withError(backendInteraction{Operation: "capture_config", Dest: destDir}, err))
+	return err
+}
+
+func (r *RecordingBackend) record(interaction backendInteraction) {
+	redact := secretRedactor(r.config)
+	interaction.Command = redactArgs(redact, interaction.Command)
+	interaction.Output = redact(interaction.Output)
+	interaction.Error = redact(interaction.Error)
+	if err := r.writeLine(interaction); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write backend capture: %v\n", err)
+	}
+}
+
+// writeLine appends value to the capture as one JSON line.
+func (r *RecordingBackend) writeLine(value any) error {
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.file.Write(line.Bytes())
+	return err
+}
+
+// isWatchdogHeartbeat reports whether command refreshes the watchdog heartbeat file.
+func isWatchdogHeartbeat(service string, command []string) bool {
+	return service == "database" && slices.Equal(command, []string{"touch", neo4jRemoteWatchdogHeartbeat})
+}
+
+// ReplayBackend answers backend calls from a capture written by RecordingBackend. Each call
+// takes the first unused recorded interaction with the same operation and service that
+// matches it, so services driven concurrently (parallel restores) replay in any interleaving;
+// a call with no such interaction is reported as a divergence. Heartbeat refreshes always
+// succeed. Files copied out of a service are recreated as zero-filled placeholders of the
+// recorded size, and directories with the recorded tree.
+type ReplayBackend struct {
+	config  *Configuration
+	capture backendCapture
+
+	mu     sync.Mutex
+	queues map[string][]*replayEntry
+}
+
+// replayEntry is a recorded interaction and whether a call has consumed it.
+type replayEntry struct {
+	index       int
+	interaction backendInteraction
+	used        bool
+}
+
+// NewReplayBackend loads a capture file for replay.
+func NewReplayBackend(path string, cfg *Configuration) (*ReplayBackend, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backend capture: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	var capture backendCapture
+	if err := decoder.Decode(&capture); err != nil {
+		return nil, fmt.Errorf("failed to parse backend capture %s: %w", path, err)
+	}
+	if capture.Version != backendCaptureVersion {
+		return nil, fmt.Errorf("unsupported backend capture version %d", capture.Version)
+	}
+	p := &ReplayBackend{config: cfg, capture: capture, queues: map[string][]*replayEntry{}}
+	for index := 1; ; index++ {
+		var interaction backendInteraction
+		if err := decoder.Decode(&interaction); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse backend capture %s, interaction %d: %w", path, index, err)
+		}
+		key := replayKey(interaction.Operation, interaction.Service)
+		p.queues[key] = append(p.queues[key], &replayEntry{index: index, interaction: interaction})
+	}
+	return p, nil
+}
+
+func replayKey(operation, service string) string {
+	return operation + "\x00" + service
+}
+
+func (p *ReplayBackend) Name() string { return p.capture.Backend }
+
+func (p *ReplayBackend) Info() string { return p.capture.Target }
+
+func (p *ReplayBackend) Detect() error { return nil }
+
+func (p *ReplayBackend) Exec(service string, command []string, opts *ExecOptions) (string, error) {
+	return p.replayExec("exec", service, command, opts)
+}
+
+func (p *ReplayBackend) ExecStream(service string, command []string, opts *ExecOptions) (string, error) {
+	return p.replayExec("exec_stream", service, command, opts)
+}
+
+func (p *ReplayBackend) replayExec(operation, service string, command []string, opts *ExecOptions) (string, error) {
+	if opts != nil && opts.Stdin != nil {
+		if _, err := io.Copy(io.Discard, opts.Stdin); err != nil {
+			return "", err
+		}
+	}
+	if isWatchdogHeartbeat(service, command) {
+		return "", nil
+	}
+	redacted := redactArgs(secretRedactor(p.config), command)
+	interaction, err := p.take(operation, service, func(recorded backendInteraction) bool {
+		return slices.Equal(recorded.Command, redacted)
+	}, fmt.Sprint(redacted))
+	if err != nil {
+		return "", err
+	}
+	return interaction.Output, interaction.err()
+}
+
+func (p *ReplayBackend) CopyTo(service, src, dest string) error {
+	interaction, err := p.take("copy_to", service, nil, dest)
+	if err != nil {
+		return err
+	}
+	return interaction.err()
+}
+
+func (p *ReplayBackend) CopyFrom(service, src, dest string) error {
+	interaction, err := p.take("copy_from", service, nil, src)
+	if err != nil {
+		return err
+	}
+	if recordedErr := interaction.err(); recordedErr != nil {
+		return recordedErr
+	}
+	if !interaction.Dir {
+		return createPlaceholder(dest, interaction.Size)
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	for _, file := range interaction.Files {
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) {
+			return fmt.Errorf("replay: recorded file %q is outside the copied directory", file.Path)
+		}
+		path := filepath.Join(dest, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := createPlaceholder(path, file.Size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createPlaceholder creates a zero-filled file of size bytes at path.
+func createPlaceholder(path string, size int64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Truncate(size)
+}
+
+func (p *ReplayBackend) Start(services ...string) error {
+	interaction, err := p.take("start", "", func(recorded backendInteraction) bool {
+		return slices.Equal(recorded.Services, services)
+	}, fmt.Sprint(services))
+	if err != nil {
+		return err
+	}
+	return interaction.err()
+}
+
+func (p *ReplayBackend) Stop(services ...string) error {
+	interaction, err := p.take("stop", "", func(recorded backendInteraction) bool {
+		return slices.Equal(recorded.Services, services)
+	}, fmt.Sprint(services))
+	if err != nil {
+		return err
+	}
+	return interaction.err()
+}
+
+func (p *ReplayBackend) IsRunning(service string) (bool, error) {
+	interaction, err := p.take("is_running", service, nil, "")
+	if err != nil {
+		return false, err
+	}
+	return interaction.Running, interaction.err()
+}
+
+func (p *ReplayBackend) IsReady(service string) (bool, error) {
+	interaction, err := p.take("is_ready", service, nil, "")
+	if err != nil {
+		return false, err
+	}
+	return interaction.Ready, interaction.err()
+}
+
+func (p *ReplayBackend) HasService(service string) (bool, error) {
+	interaction, err := p.take("has_service", service, nil, "")
+	if err != nil {
+		return false, err
+	}
+	return interaction.Exists, interaction.err()
+}
+
+func (p *ReplayBackend) CaptureConfig(destDir string) error {
+	interaction, err := p.take("capture_config", "", nil, destDir)
+	if err != nil {
+		return err
+	}
+	return interaction.err()
+}
+
+// take consumes the first unused interaction recorded for operation on service that matches
+// the call.
+func (p *ReplayBackend) take(operation, service string, matches func(backendInteraction) bool, detail string) (backendInteraction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var next *replayEntry
+	for _, entry := range p.queues[replayKey(operation, service)] {
+		if entry.used {
+			continue
+		}
+		if matches == nil || matches(entry.interaction) {
+			entry.used = true
+			return entry.interaction, nil
+		}
+		if next == nil {
+			next = entry
+		}
+	}
+	if next == nil {
+		return backendInteraction{}, fmt.Errorf("replay diverged: unexpected %s %s %s, no recorded interaction left for it", operation, service, detail)
+	}
+	return backendInteraction{}, fmt.Errorf("replay diverged: got %s %s %s, the next recorded one (interaction %d) is %v%v",
+		operation, service, detail, next.index, next.interaction.Command, next.interaction.Services)
+}
+
+// replayError is a recorded failure; ExitCode keeps the original exit status.
+type replayError struct {
+	message  string
+	exitCode int
+}
+
+func (e *replayError) Error() string { return e.message }
+
+func (e *replayError) ExitCode() int { return e.exitCode }
+
+func (i backendInteraction) err() error {
+	if i.Error == "" {
+		return nil
+	}
+	return &replayError{message: i.Error, exitCode: i.ExitCode}
+}
+
+func execInteraction(operation, service string, command []string, opts *ExecOptions, output string, err error) backendInteraction {
+	interaction := backendInteraction{Operation: operation, Service: service, Command: slices.Clone(command), Output: output}
+	if opts != nil {
+		interaction.User = opts.User
+		for key := range opts.Env {
+			interaction.EnvKeys = append(interaction.EnvKeys, key)
+		}
+		slices.Sort(interaction.EnvKeys)
+	}
+	return withError(interaction, err)
+}
+
+func withError(interaction backendInteraction, err error) backendInteraction {
+	if err == nil {
+		return interaction
+	}
+	interaction.Error = err.Error()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		interaction.ExitCode = exitErr.ExitCode()
+	}
+	return interaction
+}
+
+func redactArgs(redact func(string) string, args []string) []string {
+	if args == nil {
+		return nil
+	}
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = redact(arg)
+	}
+	return redacted
+}
+
+// setReference records the size and SHA-256 of the local file at path, or the files of the
+// local directory with their total size. Unreadable paths are left as zero values.
+func (i *backendInteraction) setReference(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if !info.IsDir() {
+		i.Size, i.SHA256 = fileReference(path)
+		return
+	}
+	i.Dir = true
+	_ = filepath.WalkDir(path, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(path, current)
+		if err != nil {
+			return nil
+		}
+		size, sum := fileReference(current)
+		i.Files = append(i.Files, backendFile{Path: filepath.ToSlash(relative), Size: size, SHA256: sum})
+		i.Size += size
+		return nil
+	})
+}
+
+// fileReference returns the size and SHA-256 of a local file, or zero values if it cannot be read.
+func fileReference(path string) (int64, string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, ""
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, ""
+	}
+	return size, hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+// dirCopyBackend copies a fixed directory tree out of any service, which FakeBackend can't.
+type dirCopyBackend struct {
+	*apptest.FakeBackend
+	files map[string]string
+}
+
+func (b *dirCopyBackend) CopyFrom(service, src, dest string) error {
+	for name, content := range b.files {
+		path := filepath.Join(dest, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRecordAndReplayBackend(t *testing.T) {
+	cfg := DefaultConfiguration()
+	cfg.Neo4jPassword = "s3cret"
+	capturePath := filepath.Join(t.TempDir(), "capture.jsonl")
+
+	fake := apptest.NewFakeBackend("database", "task-manager-db")
+	fake.On("database", []string{"cypher-shell"}, apptest.Response{Output: "ok"})
+	fake.On("task-manager-db", []string{"pg_restore"}, apptest.Response{Output: "restored"})
+	recorder, err := NewRecordingBackend(&dirCopyBackend{FakeBackend: fake, files: map[string]string{
+		"neo4j.log":     "started",
+		"sub/debug.log": "debug output",
+	}}, capturePath, cfg)
+	if err != nil {
+		t.Fatalf("NewRecordingBackend: %v", err)
+	}
+	mustExec := func(b Backend, service string, command ...string) string {
+		t.Helper()
+		output, err := b.Exec(service, command, nil)
+		if err != nil {
+			t.Fatalf("Exec %s %v: %v", service, command, err)
+		}
+		return output
+	}
+	mustExec(recorder, "database", "cypher-shell", "-ps3cret", "RETURN 1")
+	mustExec(recorder, "database", "touch", neo4jRemoteWatchdogHeartbeat)
+	mustExec(recorder, "task-manager-db", "pg_restore", "-d", "prefect")
+	if err := recorder.CopyFrom("database", "/logs", filepath.Join(t.TempDir(), "logs")); err != nil {
+		t.Fatalf("CopyFrom: %v", err)
+	}
+
+	// One header line and one line per interaction, without the heartbeat or the password
+	data, err := os.ReadFile(capturePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Errorf("capture has %d lines, want 4:\n%s", lines, data)
+	}
+	if strings.Contains(string(data), "s3cret") || strings.Contains(string(data), neo4jRemoteWatchdogHeartbeat) {
+		t.Errorf("capture contains the password or a heartbeat:\n%s", data)
+	}
+	for scanner := bufio.NewScanner(strings.NewReader(string(data))); scanner.Scan(); {
+		if !strings.HasPrefix(scanner.Text(), "{") {
+			t.Errorf("capture line %q is not a JSON object", scanner.Text())
+		}
+	}
+
+	// Replay across services in another order, with heartbeats in between
+	replay, err := NewReplayBackend(capturePath, cfg)
+	if err != nil {
+		t.Fatalf("NewReplayBackend: %v", err)
+	}
+	mustExec(replay, "database", "touch", neo4jRemoteWatchdogHeartbeat)
+	if got := mustExec(replay, "task-manager-db", "pg_restore", "-d", "prefect"); got != "restored" {
+		t.Errorf("replayed pg_restore output = %q", got)
+	}
+	mustExec(replay, "database", "touch", neo4jRemoteWatchdogHeartbeat)
+	dest := filepath.Join(t.TempDir(), "logs")
+	if err := replay.CopyFrom("database", "/logs", dest); err != nil {
+		t.Fatalf("replayed CopyFrom: %v", err)
+	}
+	if got := mustExec(replay, "database", "cypher-shell", "-ps3cret", "RETURN 1"); got != "ok" {
+		t.Errorf("replayed cypher-shell output = %q", got)
+	}
+	info, err := os.Stat(filepath.Join(dest, "sub", "debug.log"))
+	if err != nil || info.Size() != int64(len("debug output")) {
+		t.Errorf("replayed directory file = %v, %v; want a placeholder of the recorded size", info, err)
+	}
+
+	if _, err := replay.Exec("database", []string{"cypher-shell", "-ps3cret", "RETURN 1"}, nil); err == nil || !strings.Contains(err.Error(), "replay diverged") {
+		t.Errorf("second replay of a single recorded call = %v, want a divergence", err)
+	}
+	if _, err := replay.Exec("task-manager-db", []string{"psql"}, nil); err == nil || !strings.Contains(err.Error(), "replay diverged") {
+		t.Errorf("unrecorded call = %v, want a divergence", err)
+	}
+}
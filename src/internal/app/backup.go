@@ -1,30 +1,127 @@
 package app
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 )
 
 // CreateBackup creates a full backup of the Infrahub deployment
 func (iops *InfrahubOps) CreateBackup(force bool, neo4jMetadata string, excludeTaskManager bool) (retErr error) {
+	// Registered first so it runs last, after services have been restarted
+	report := iops.startReport("backup")
+	defer func() { iops.finishReport(retErr) }()
+
+	if iops.config.DryRun {
+		logrus.Info("--dry-run: the backup runs as usual; only the deletion of the local archive after the upload is previewed")
+	}
 	if err := iops.checkPrerequisites(); err != nil {
 		return err
 	}
 
+	maxArchiveSize, err := parseByteSize(iops.config.MaxArchiveSize)
+	if err != nil {
+		return fmt.Errorf("invalid --max-archive-size: %w", err)
+	}
+	minDumpSize, err := parseByteSize(iops.config.MinDumpSize)
+	if err != nil {
+		return fmt.Errorf("invalid --min-dump-size: %w", err)
+	}
+	if err := validateExcludePatterns(iops.config.ExcludeFiles); err != nil {
+		return err
+	}
+	if err := iops.validateS3DeleteLocal(); err != nil {
+		return err
+	}
+	if iops.config.S3Upload {
+		if err := iops.validateS3MaxRetries(); err != nil {
+			return err
+		}
+	}
+	if err := validatePgExcludeTables(iops.config); err != nil {
+		return err
+	}
+	if err := iops.checkOutputDir(); err != nil {
+		return err
+	}
+	neo4jMetadata, err = normalizeNeo4jMetadata(neo4jMetadata)
+	if err != nil {
+		return err
+	}
+	tarFormat, err := parseTarFormat(iops.config.TarFormat)
+	if err != nil {
+		return err
+	}
+	if iops.config.Compression, err = parseArchiveCompression(iops.config.Compression); err != nil {
+		return err
+	}
+	tarNormalize, err := parseTarNormalization(iops.config.ArchiveOwner, iops.config.ArchivePermissions)
+	if err != nil {
+		return err
+	}
+	iops.config.ArchiveOwner, iops.config.ArchivePermissions = tarNormalize.ownerString(), tarNormalize.permissionsString()
+	if err := validateBackupLabel(iops.config.Label); err != nil {
+		return err
+	}
+	excludeTaskManager, err = iops.applyBackupComponentSelection(excludeTaskManager)
+	if err != nil {
+		return err
+	}
+	if !excludeTaskManager {
+		if err := iops.checkPgReplicaConfig(); err != nil {
+			return err
+		}
+	}
+
 	if err := iops.DetectEnvironment(); err != nil {
 		return err
 	}
+	var taskManagerAbsence string
+	if !excludeTaskManager && !iops.config.DumpOnly {
+		if taskManagerAbsence = iops.taskManagerAbsence(); taskManagerAbsence != "" {
+			if iops.config.ExcludeNeo4j {
+				return fmt.Errorf("nothing to back up: neo4j is excluded and the task manager database cannot be dumped: %s", taskManagerAbsence)
+			}
+			logrus.Warnf("Skipping the task manager database: %s; pass --exclude-taskmanager to skip it explicitly", taskManagerAbsence)
+			report.set("Task manager database", "skipped: "+taskManagerAbsence)
+			excludeTaskManager = true
+		}
+	}
+	if err := iops.tolerateUnavailable(iops.checkBackupServices(force, excludeTaskManager)); err != nil {
+		return err
+	}
+	if iops.config.CleanupOnStart {
+		if err := iops.cleanupStaleStaging(excludeTaskManager); err != nil {
+			return err
+		}
+	}
 
 	// Detect Neo4j edition
 	editionInfo := iops.detectNeo4jEditionInfo("backup")
-	if editionInfo.IsCommunity {
+	report.set("Neo4j edition", describeNeo4jPath(editionInfo.Edition))
+	report.set("Services restarted", "no (services were not stopped)")
+	if !iops.config.ExcludeNeo4j {
+		if err := checkNeo4jEdition(editionInfo.Edition); err != nil {
+			return err
+		}
+	}
+	if err := iops.tolerateUnavailable(iops.checkContainerPermissions(editionInfo.Edition, false)); err != nil {
+		return err
+	}
+	// Only the Neo4j backup needs Community to be stopped
+	stopForNeo4j := editionInfo.IsCommunity && !iops.config.ExcludeNeo4j
+	if stopForNeo4j {
 		logrus.Warn("Neo4j Community Edition detected; Infrahub services will be stopped and restarted before the backup begins.")
 		logrus.Warn("Waiting 10 seconds to allow the user to abort... CTRL+C to cancel.")
 		time.Sleep(10 * time.Second)
@@ -35,13 +132,13 @@ func (iops *InfrahubOps) CreateBackup(force bool, neo4jMetadata string, excludeT
 	// Check for running tasks unless --force is set
 	if !force {
 		logrus.Info("Checking for running tasks before backup...")
-		if err := iops.waitForRunningTasks(); err != nil {
+		if err := iops.tolerateUnavailable(iops.waitForRunningTasks()); err != nil {
 			return err
 		}
 	}
 
 	var servicesToRestart []string
-	if editionInfo.IsCommunity {
+	if stopForNeo4j {
 		stoppedServices, stopErr := iops.stopAppContainers()
 		if stopErr != nil {
 			if len(stoppedServices) > 0 {
@@ -56,7 +153,14 @@ func (iops *InfrahubOps) CreateBackup(force bool, neo4jMetadata string, excludeT
 			if len(servicesToRestart) == 0 {
 				return
 			}
+			if iops.config.NoRestart {
+				iops.logServicesLeftStopped(servicesToRestart)
+				report.set("Services restarted", "no, left stopped by --no-restart: "+strings.Join(servicesToRestart, ", "))
+				return
+			}
+			report.set("Services restarted", "yes: "+strings.Join(servicesToRestart, ", "))
 			if startErr := iops.startAppContainers(servicesToRestart); startErr != nil {
+				report.set("Services restarted", "FAILED: "+startErr.Error())
 				logrus.Errorf("Failed to restart services after backup: %v", startErr)
 				if retErr == nil {
 					retErr = fmt.Errorf("failed to restart services after backup: %w", startErr)
@@ -65,17 +169,27 @@ func (iops *InfrahubOps) CreateBackup(force bool, neo4jMetadata string, excludeT
 		}()
 	}
 
-	backupFilename := iops.generateBackupFilename()
-	backupPath := filepath.Join(iops.config.BackupDir, backupFilename)
+	if iops.config.DumpOnly {
+		done := report.begin("Neo4j dump (--dump-only)")
+		err := iops.createDatabaseDump(neo4jMetadata, version, editionInfo.Edition)
+		done(err)
+		return err
+	}
+
+	backupFilename, err := iops.resolveBackupFilename()
+	if err != nil {
+		return err
+	}
+	backupPath := filepath.Join(iops.archiveDir(), backupFilename)
 	workDir, err := os.MkdirTemp("", "infrahub_backup_*")
 	if err != nil {
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
-	defer os.RemoveAll(workDir)
+	defer iops.cleanupWorkDir(workDir)
 
 	logrus.WithFields(logrus.Fields{
 		"filename":      backupFilename,
-		"backup_dir":    iops.config.BackupDir,
+		"backup_dir":    iops.archiveDir(),
 		"neo4j_edition": editionInfo.Edition,
 	}).Info("Creating backup")
 
@@ -85,33 +199,159 @@ func (iops *InfrahubOps) CreateBackup(force bool, neo4jMetadata string, excludeT
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	if err := os.MkdirAll(iops.config.BackupDir, 0755); err != nil {
+	if err := os.MkdirAll(iops.archiveDir(), 0755); err != nil {
 		return fmt.Errorf("failed to create backup parent directory: %w", err)
 	}
 
 	// Create metadata
-	backupID := strings.TrimSuffix(backupFilename, ".tar.gz")
-	metadata := iops.createBackupMetadata(backupID, !excludeTaskManager, version, editionInfo.Edition)
+	backupID := trimBackupArchiveSuffix(backupFilename)
+	metadata := iops.createBackupMetadata(backupID, !iops.config.ExcludeNeo4j, !excludeTaskManager, version, editionInfo.Edition)
+	metadata.TaskManagerAbsence = taskManagerAbsence
+	if metadata.Label != "" {
+		report.set("Label", metadata.Label)
+	}
+	if isNeo4jEnterpriseEdition(editionInfo.Edition) && !iops.config.ExcludeNeo4j {
+		if iops.config.Neo4jOfflineEnterprise {
+			if neo4jMetadata != "none" {
+				logrus.Warn("Users and roles are not included in an offline Neo4j dump; back them up with an online backup")
+			}
+			neo4jMetadata = "none"
+			metadata.Neo4jBackupMode = neo4jBackupModeOffline
+		} else {
+			compressed := iops.config.Neo4jBackupCompress
+			metadata.Neo4jBackupCompressed = &compressed
+			metadata.Neo4jBackupMode = neo4jBackupModeOnline
+		}
+		metadata.Neo4jMetadata = neo4jMetadata
+		report.set("Neo4j backup mode", metadata.Neo4jBackupMode)
+	}
 
-	// Backup databases
-	if err := iops.backupDatabase(backupDir, neo4jMetadata, editionInfo.Edition); err != nil {
-		return err
+	// Backup databases; with --best-effort a failed component is recorded and left out
+	var dumps []string
+	if iops.config.ExcludeNeo4j {
+		logrus.Info("Skipping Neo4j database backup as requested")
+	} else {
+		// Capture the schema while Neo4j still accepts queries; it is only needed by
+		// restore --schema-only, so a failure does not stop the backup
+		if count, err := iops.backupNeo4jSchema(backupDir); err != nil {
+			logrus.Warnf("Failed to capture the Neo4j schema; restore --schema-only will not be available for this backup: %v", err)
+		} else {
+			metadata.Components = append(metadata.Components, neo4jSchemaComponent)
+			report.set("Neo4j schema", fmt.Sprintf("%d statements", count))
+		}
+		// Count the graph at the same point, for restore --neo4j-restore-verify; the Neo4j
+		// Community process is only stopped later, by the Neo4j backup itself
+		if iops.config.NoGraphStats {
+			logrus.Info("Skipping Neo4j graph stats as requested")
+		} else if stats, err := iops.collectGraphStats(); err != nil {
+			logrus.Warnf("Failed to collect Neo4j graph stats; restore --neo4j-restore-verify will only log totals for this backup: %v", err)
+		} else {
+			metadata.GraphStats = &stats
+			report.set("Graph stats", stats.String())
+		}
+
+		done := report.begin("Neo4j backup")
+		err = iops.backupDatabase(backupDir, neo4jMetadata, editionInfo.Edition)
+		done(err)
+		if err != nil {
+			neo4jErr := err
+			if err := iops.tolerateComponentFailure(metadata, "database", err, filepath.Join(backupDir, neo4jBackupDirName), filepath.Join(backupDir, neo4jAuthDirName)); err != nil {
+				return err
+			}
+			if excludeTaskManager {
+				return fmt.Errorf("nothing could be backed up: %w", neo4jErr)
+			}
+		} else {
+			dumps = append(dumps, neo4jBackupDirName)
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(backupDir, neo4jAuthDirName)); err == nil && info.IsDir() {
+		metadata.Components = append(metadata.Components, neo4jAuthComponent)
 	}
 
+	var taskManagerDumps []string
 	if !excludeTaskManager {
-		if err := iops.backupTaskManagerDB(backupDir); err != nil {
-			return err
+		done := report.begin("Task manager database dump")
+		tmDumps, err := iops.backupTaskManagerDB(backupDir)
+		done(err)
+		if err != nil {
+			taskManagerErr := err
+			var leftovers []string
+			for _, database := range iops.taskManagerDatabases() {
+				leftovers = append(leftovers, filepath.Join(backupDir, iops.taskManagerDumpFilename(database)))
+			}
+			if err := iops.tolerateComponentFailure(metadata, "task-manager-db", err, leftovers...); err != nil {
+				return err
+			}
+			if len(dumps) == 0 && iops.config.ExcludeNeo4j {
+				return fmt.Errorf("nothing could be backed up: task-manager-db: %w", taskManagerErr)
+			}
+			if len(dumps) == 0 {
+				return fmt.Errorf("nothing could be backed up: database: %s; task-manager-db: %w", metadata.FailedComponents["database"], taskManagerErr)
+			}
+		} else {
+			taskManagerDumps = tmDumps
+			dumps = append(dumps, tmDumps...)
+			metadata.PgExcludedTables = iops.config.PgExcludeTables
+			metadata.PgExcludedTableData = iops.config.PgExcludeTableData
+			for _, database := range iops.taskManagerDatabases()[1:] {
+				metadata.Components = append(metadata.Components, taskManagerExtraComponentPrefix+database)
+			}
 		}
 	} else {
 		logrus.Info("Skipping task manager database backup as requested")
 	}
 
+	// Refuse to ship empty dumps, and flag a large shrink compared with the previous backup
+	dumpSizes, err := checkDumpSizes(backupDir, dumps, minDumpSize)
+	if err != nil {
+		return err
+	}
+	metadata.DumpSizes = dumpSizes
+	iops.warnOnDumpShrink(dumpSizes)
+
+	if iops.config.IncludeConfig {
+		done := report.begin("Deployment configuration")
+		err := iops.backupDeploymentConfig(backupDir)
+		done(err)
+		if err != nil {
+			if err := iops.tolerateComponentFailure(metadata, deploymentConfigComponent, err, filepath.Join(backupDir, deploymentConfigDirName)); err != nil {
+				return err
+			}
+		} else {
+			metadata.Components = append(metadata.Components, deploymentConfigComponent)
+			if _, err := pruneExcludedFiles(filepath.Join(backupDir, deploymentConfigDirName), iops.config.ExcludeFiles); err != nil {
+				return err
+			}
+			metadata.ExcludePatterns = iops.config.ExcludeFiles
+		}
+	}
+
+	if len(metadata.FailedComponents) > 0 {
+		backupFilename = markPartialBackup(metadata, backupFilename)
+		backupPath = filepath.Join(iops.archiveDir(), backupFilename)
+		report.set("Partial backup", "missing "+strings.Join(metadata.failedComponentNames(), ", "))
+		logrus.Warnf("Creating a PARTIAL backup %s without: %s", backupFilename, strings.Join(metadata.failedComponentNames(), ", "))
+	}
+
+	metadata.ComponentSizes = iops.componentSizes(backupDir, metadata.Components)
+
 	// Calculate checksums for backup files
-	checksums, err := calculateBackupChecksums(backupDir, excludeTaskManager)
+	checksums, err := calculateBackupChecksums(iops.files, backupDir, taskManagerDumps)
 	if err != nil {
 		return err
 	}
 	metadata.Checksums = checksums
+	report.setChecksums(checksums)
+	keyID, err := iops.signingKeyID()
+	if err != nil {
+		return err
+	}
+	metadata.IntegrityKeyID = keyID
+	if keyID != "" {
+		report.set("Integrity key", keyID)
+	}
 
 	metadataBytes, err := json.MarshalIndent(metadata, "", "    ")
 	if err != nil {
@@ -122,14 +362,36 @@ func (iops *InfrahubOps) CreateBackup(force bool, neo4jMetadata string, excludeT
 		return fmt.Errorf("failed to write metadata: %w", err)
 	}
 
+	if err := iops.writeMetadataSignature(backupDir, metadataBytes); err != nil {
+		return err
+	}
+
 	// TODO: Backup artifact store
 	logrus.Info("Artifact store backup will be added in future versions")
 
 	// Create tarball
-	logrus.Info("Creating backup archive...")
-	if err := createTarball(backupPath, workDir, "backup/"); err != nil {
+	logrus.WithFields(logrus.Fields{"compression": iops.config.Compression, "threads": iops.config.CompressionThreads}).Info("Creating backup archive...")
+	done := report.begin("Archive")
+	err = createTarball(backupPath, workDir, "backup/", tarballOptions{
+		Files:           iops.files,
+		Compression:     iops.config.Compression,
+		Threads:         iops.config.CompressionThreads,
+		Level:           iops.archiveCompressionLevel(editionInfo.Edition),
+		Format:          tarFormat,
+		StoreCompressed: !iops.config.RecompressDumps,
+		Normalize:       tarNormalize,
+	})
+	done(err)
+	if err != nil {
+		// Leave no truncated archive behind for a retry or a later restore to pick up
+		if removeErr := os.Remove(backupPath); removeErr != nil && !os.IsNotExist(removeErr) {
+			logrus.Warnf("Failed to remove incomplete archive %s: %v", backupPath, removeErr)
+		}
 		return fmt.Errorf("failed to create archive: %w", err)
 	}
+	if err := checkArchiveSize(backupPath, maxArchiveSize); err != nil {
+		return err
+	}
 
 	// Log backup creation with structured fields
 	fields := logrus.Fields{
@@ -139,48 +401,145 @@ func (iops *InfrahubOps) CreateBackup(force bool, neo4jMetadata string, excludeT
 	if stat, err := os.Stat(backupPath); err == nil {
 		fields["size_bytes"] = stat.Size()
 		fields["size_human"] = formatBytes(stat.Size())
+		report.set("Archive", fmt.Sprintf("%s (%s)", backupPath, formatBytes(stat.Size())))
+		if uncompressed, err := pathSize(backupDir); err == nil {
+			iops.logComponentSizes(metadata.ComponentSizes, uncompressed, stat.Size())
+		}
 	}
 	logrus.WithFields(fields).Info("Backup created successfully")
 
 	// Upload to S3 if configured
 	if iops.config.S3Upload {
-		if err := iops.uploadBackupToS3(backupPath); err != nil {
+		done := report.begin("S3 upload")
+		err := iops.uploadBackupToS3(backupPath)
+		done(err)
+		if err != nil {
 			return fmt.Errorf("backup created but failed to upload to S3: %w", err)
 		}
+		report.set("S3 destination", fmt.Sprintf("s3://%s/%s", iops.config.S3Bucket, iops.s3Key(backupFilename)))
+
+		if iops.config.S3DeleteLocal {
+			if err := iops.deleteLocalAfterUpload(backupPath, iops.s3Key(backupFilename)); err != nil {
+				return err
+			}
+			if !iops.config.DryRun {
+				report.set("Local archive", "deleted after the upload")
+			}
+		}
+	}
+
+	if iops.config.Quiet {
+		result := backupPath
+		if size, ok := fields["size_human"]; ok {
+			result = fmt.Sprintf("%s (%s)", backupPath, size)
+		}
+		if iops.config.S3Upload {
+			result += fmt.Sprintf(" uploaded to s3://%s/%s", iops.config.S3Bucket, iops.s3Key(backupFilename))
+		}
+		fmt.Println(result)
 	}
 
+	if metadata.Partial && retErr == nil {
+		return partialBackupError(metadata, backupPath)
+	}
 	return retErr
 }
 
-// RestoreBackup restores an Infrahub deployment from a backup archive
+// RestoreBackup restores an Infrahub deployment from a backup archive, given as a local path
+// or as an s3://bucket/key reference
 func (iops *InfrahubOps) RestoreBackup(backupFile string, excludeTaskManager bool, restoreMigrateFormat bool) error {
-	if _, err := os.Stat(backupFile); os.IsNotExist(err) {
-		return fmt.Errorf("backup file not found: %s", backupFile)
+	if bucket, key, ok := parseS3Reference(backupFile); ok {
+		iops.config.S3Bucket = bucket
+		return iops.restoreS3Backup(key, excludeTaskManager, restoreMigrateFormat)
+	}
+	return iops.restore(backupFile, "", false, excludeTaskManager, restoreMigrateFormat)
+}
+
+// restore restores from source: an archive, or with fromDir a directory holding the
+// extracted backup/ tree, which is read in place and left untouched. origin is the s3:// URI
+// an archive was downloaded from, or empty.
+func (iops *InfrahubOps) restore(source, origin string, fromDir bool, excludeTaskManager bool, restoreMigrateFormat bool) (retErr error) {
+	backupFile := source
+	if iops.config.DryRun && !iops.config.ValidateOnly {
+		logrus.Info("--dry-run: validating the backup without restoring it (--validate-only)")
+		iops.config.ValidateOnly = true
+	}
+	excludeTaskManager, err := iops.applyRestoreComponentSelection(excludeTaskManager)
+	if err != nil {
+		return err
+	}
+
+	report := iops.startReport("restore")
+	defer func() { iops.finishReport(retErr) }()
+	if fromDir {
+		report.set("Backup directory", source)
+		if err := checkExtractedBackupDir(source); err != nil {
+			return err
+		}
+	} else {
+		report.set("Backup file", backupFile)
+		if origin != "" {
+			report.set("Downloaded from", origin)
+		}
+		if _, err := os.Stat(backupFile); os.IsNotExist(err) {
+			return fmt.Errorf("backup file not found: %s", backupFile)
+		}
 	}
 
 	if err := iops.checkPrerequisites(); err != nil {
 		return err
 	}
 
+	if iops.hasRestoreTarget() {
+		if err := iops.pinRestoreTarget(); err != nil {
+			return err
+		}
+	}
 	if err := iops.DetectEnvironment(); err != nil {
 		return err
 	}
-
-	workDir, err := os.MkdirTemp("", "infrahub_restore_*")
-	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+	if err := iops.checkRestoreServices(); err != nil {
+		return err
 	}
-	defer os.RemoveAll(workDir)
 
-	logrus.WithFields(logrus.Fields{
-		"backup_file": backupFile,
-		"work_dir":    workDir,
-	}).Info("Starting backup restore")
+	if !excludeTaskManager {
+		if _, err := iops.pgRestoreArgs("", iops.config.PostgresDatabase); err != nil {
+			return err
+		}
+	}
 
-	// Extract backup
-	logrus.Info("Extracting backup archive...")
-	if err := extractTarball(backupFile, workDir); err != nil {
-		return fmt.Errorf("failed to extract backup: %w", err)
+	// diagDir receives the watchdog and Neo4j logs: the working directory, except that a
+	// --from-dir directory is never written to
+	var workDir, diagDir string
+	if fromDir {
+		workDir = source
+		logrus.WithField("backup_dir", workDir).Info("Starting backup restore from an extracted directory")
+		diagDir, err = os.MkdirTemp("", "infrahub_restore_diag_*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer iops.cleanupWorkDir(diagDir)
+	} else {
+		workDir, err = os.MkdirTemp("", "infrahub_restore_*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer iops.cleanupWorkDir(workDir)
+		diagDir = workDir
+
+		logrus.WithFields(logrus.Fields{
+			"backup_file": backupFile,
+			"work_dir":    workDir,
+		}).Info("Starting backup restore")
+
+		// Extract backup
+		logrus.Info("Extracting backup archive...")
+		done := report.begin("Extract archive")
+		err = extractTarball(backupFile, workDir)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to extract backup: %w", err)
+		}
 	}
 
 	// Validate backup
@@ -194,10 +553,21 @@ func (iops *InfrahubOps) RestoreBackup(backupFile string, excludeTaskManager boo
 	if err != nil {
 		return fmt.Errorf("failed to read metadata: %w", err)
 	}
+	if err := iops.verifyMetadataSignature(filepath.Dir(metadataPath), metadataBytes); err != nil {
+		return err
+	}
 	var metadata BackupMetadata
 	if err := json.Unmarshal(metadataBytes, &metadata); err != nil {
 		return fmt.Errorf("failed to parse metadata: %w", err)
 	}
+	if err := validateExtraTaskManagerDatabases(&metadata); err != nil {
+		return err
+	}
+	if iops.config.StrictChecksums {
+		if err := validateNoUnlistedFiles(workDir, &metadata); err != nil {
+			return err
+		}
+	}
 
 	// Log backup metadata with structured fields
 	logrus.WithFields(logrus.Fields{
@@ -208,6 +578,26 @@ func (iops *InfrahubOps) RestoreBackup(backupFile string, excludeTaskManager boo
 		"neo4j_edition":    metadata.Neo4jEdition,
 		"components":       metadata.Components,
 	}).Info("Backup metadata loaded")
+	report.set("Backup ID", metadata.BackupID)
+	if metadata.Label != "" {
+		report.set("Label", metadata.Label)
+	}
+	report.set("Components", strings.Join(metadata.Components, ", "))
+	if metadata.Partial {
+		warnPartialRestore(&metadata)
+		report.set("Partial backup", "missing "+strings.Join(metadata.failedComponentNames(), ", "))
+	}
+	if iops.hasRestoreTarget() {
+		iops.logRestoreSourceAndTarget(&metadata)
+	}
+
+	// --schema-only applies the captured constraints and indexes and leaves the data alone
+	if iops.config.SchemaOnly {
+		done := report.begin("Neo4j schema")
+		err := iops.restoreNeo4jSchema(workDir, &metadata)
+		done(err)
+		return err
+	}
 
 	// Detect Neo4j edition for restore
 	detectedEdition, detectionErr := iops.detectNeo4jEdition()
@@ -218,6 +608,10 @@ func (iops *InfrahubOps) RestoreBackup(backupFile string, excludeTaskManager boo
 		return err
 	}
 	editionInfo.LogDetection("restore")
+	report.set("Neo4j edition", describeNeo4jPath(neo4jEdition))
+	if err := iops.checkContainerPermissions(neo4jEdition, true); err != nil {
+		return err
+	}
 
 	// Determine task manager database availability
 	taskManagerIncluded := slices.Contains(metadata.Components, "task-manager-db")
@@ -227,8 +621,16 @@ func (iops *InfrahubOps) RestoreBackup(backupFile string, excludeTaskManager boo
 		}
 	}
 
-	// Validate checksums for all backup files
-	if err := validateBackupChecksums(workDir, &metadata, excludeTaskManager); err != nil {
+	if slices.Contains(metadata.Components, deploymentConfigComponent) {
+		logrus.Infof("Backup includes a deployment configuration snapshot under %s/ (reference only; not applied)", deploymentConfigDirName)
+	}
+
+	// Validate checksums for all backup files. CTRL+C only aborts this step cleanly; the
+	// destructive steps that follow keep the default signal handling.
+	validateCtx, stopValidate := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = validateBackupChecksums(validateCtx, iops.files, workDir, &metadata, excludeTaskManager)
+	stopValidate()
+	if err != nil {
 		return err
 	}
 
@@ -243,13 +645,56 @@ func (iops *InfrahubOps) RestoreBackup(backupFile string, excludeTaskManager boo
 		return fmt.Errorf("backup metadata includes task manager database but %s is missing", prefectDumpFilename)
 	}
 
+	restoreNeo4jData := metadata.includesNeo4j() && !iops.config.ExcludeNeo4j
+	if !restoreNeo4jData && !validatePrefect {
+		return fmt.Errorf("backup %s contains none of the components selected for restore", metadata.BackupID)
+	}
+	if iops.config.ExcludeNeo4j {
+		logrus.Info("Skipping Neo4j database restore as requested")
+	}
+
 	// Log task manager restore status
 	if taskManagerIncluded && excludeTaskManager {
 		logrus.Info("Skipping task manager database restore as requested")
+	} else if !taskManagerIncluded && metadata.TaskManagerAbsence != "" {
+		logrus.Infof("Backup does not include task manager database (%s at backup time); skipping restore", metadata.TaskManagerAbsence)
 	} else if !taskManagerIncluded {
 		logrus.Info("Backup does not include task manager database; skipping restore")
 	} else if prefectExists {
 		logrus.Info("Task manager database dump detected; will restore")
+		if len(metadata.PgExcludedTables) > 0 || len(metadata.PgExcludedTableData) > 0 {
+			logrus.WithFields(logrus.Fields{
+				"excluded_tables":     strings.Join(metadata.PgExcludedTables, ", "),
+				"excluded_table_data": strings.Join(metadata.PgExcludedTableData, ", "),
+			}).Warn("The task manager dump was taken without some tables or their rows; they will be missing or empty after the restore")
+		}
+	}
+
+	// A Community dump is piped into neo4j-admin when it supports --from-stdin, so it is not
+	// copied into the container first
+	streamNeo4j := restoreNeo4jData && iops.neo4jStreamRestore(workDir, neo4jEdition)
+
+	// Present the restore plan before any mutation
+	// In quiet mode the plan is only shown when it is needed for the confirmation prompt
+	plan := iops.buildRestorePlan(backupFile, &metadata, neo4jEdition, taskManagerIncluded, validatePrefect, restoreMigrateFormat, streamNeo4j)
+	if !iops.config.Quiet || !iops.config.ConfirmDestructive {
+		if err := plan.Print(iops.config.OutputFormat); err != nil {
+			return err
+		}
+	}
+
+	// --validate-only stops here, after the archive has been fully checked but before the
+	// deployment is touched
+	if iops.config.ValidateOnly {
+		report.set("Result", "validated only; nothing restored")
+		fmt.Printf("Backup archive is valid and restorable: %d components, Neo4j %s/%s\n",
+			len(metadata.Components), neo4jEdition, iops.detectNeo4jVersion())
+		return nil
+	}
+
+	// Require explicit confirmation before any destructive step
+	if err := iops.confirmDestructiveRestore(backupFile, origin); err != nil {
+		return err
 	}
 
 	// Wipe transient data
@@ -260,14 +705,39 @@ func (iops *InfrahubOps) RestoreBackup(backupFile string, excludeTaskManager boo
 		return err
 	}
 
-	// Restore PostgreSQL when available
+	// Stage the Neo4j backup and restore PostgreSQL; they target different services, so
+	// they run concurrently unless --no-parallel is set
+	group := new(errgroup.Group)
+	if iops.config.NoParallel {
+		group.SetLimit(1)
+	}
+	var cleanupNeo4jStaging func()
+	if restoreNeo4jData && !streamNeo4j {
+		group.Go(func() error {
+			done := report.begin("Stage Neo4j backup")
+			cleanup, err := iops.stageNeo4jBackup(workDir, neo4jEdition)
+			done(err)
+			cleanupNeo4jStaging = cleanup
+			return err
+		})
+	}
 	if validatePrefect {
-		if err := iops.restorePostgreSQL(workDir); err != nil {
+		group.Go(func() error {
+			done := report.begin("Task manager database restore")
+			err := iops.restorePostgreSQL(workDir, extraTaskManagerDatabases(&metadata))
+			done(err)
 			return err
-		}
+		})
 	} else {
 		logrus.Info("Skipping task manager database restore step")
 	}
+	err = group.Wait()
+	if cleanupNeo4jStaging != nil {
+		defer cleanupNeo4jStaging()
+	}
+	if err != nil {
+		return err
+	}
 
 	// Restart dependencies
 	if err := iops.restartDependencies(); err != nil {
@@ -275,18 +745,136 @@ func (iops *InfrahubOps) RestoreBackup(backupFile string, excludeTaskManager boo
 	}
 
 	// Restore Neo4j
-	if err := iops.restoreNeo4j(workDir, neo4jEdition, restoreMigrateFormat); err != nil {
-		return err
+	if restoreNeo4jData {
+		// Backups taken with --neo4j-metadata=none carry no users or roles to replay
+		replayMetadata := !iops.config.SkipMetadataRestore && metadata.Neo4jMetadata != "none"
+		done := report.begin("Neo4j restore")
+		err = iops.restoreNeo4j(workDir, diagDir, neo4jEdition, metadata.Neo4jBackupMode, restoreMigrateFormat, replayMetadata, streamNeo4j)
+		done(err)
+		if err != nil {
+			return err
+		}
+	} else {
+		logrus.Warn("Partial backup has no Neo4j database; the current graph is left as is")
+	}
+
+	// Warm up Neo4j before users can reach Infrahub again
+	if iops.config.Warmup && restoreNeo4jData {
+		if neo4jEdition == neo4jEditionExternal {
+			logrus.Info("Skipping --warmup for an external Neo4j")
+		} else {
+			done := report.begin("Neo4j warmup")
+			status, err := iops.warmupNeo4j(iops.config.WarmupTimeout)
+			done(err)
+			if status != "" {
+				report.set("Neo4j indexes", status)
+			}
+			if err != nil {
+				logrus.Warnf("Neo4j warmup did not complete; first queries may be slow: %v", err)
+			}
+		}
+	}
+
+	// Check that the data landed before users can reach Infrahub again; a failure leaves the
+	// services stopped so that Infrahub does not start on an empty or partial graph
+	if iops.config.Neo4jRestoreVerify && restoreNeo4jData {
+		done := report.begin("Neo4j restore verification")
+		err := iops.verifyNeo4jRestore(metadata.GraphStats, iops.config.Neo4jVerifyTolerance, iops.config.WarmupTimeout)
+		done(err)
+		if err != nil {
+			report.set("Services restarted", "no, left stopped after the failed verification")
+			return err
+		}
 	}
 
 	// Restart all services
 	logrus.Info("Restarting Infrahub services...")
 	if err := iops.StartServices("infrahub-server", "task-worker"); err != nil {
+		report.set("Services restarted", "FAILED: "+err.Error())
 		return fmt.Errorf("failed to restart infrahub services: %w", err)
 	}
+	report.set("Services restarted", "yes: infrahub-server, task-worker")
+
+	if iops.config.HealthAfterRestore {
+		done := report.begin("Health check")
+		err := iops.waitForInfrahubHealthy(iops.config.HealthTimeout)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("restore finished but Infrahub is not healthy: %w", err)
+		}
+		logrus.Info("Restore completed successfully")
+		if iops.config.Quiet {
+			fmt.Printf("Restored %s; Infrahub is healthy\n", backupFile)
+		}
+		return nil
+	}
 
 	logrus.Info("Restore completed successfully")
 	logrus.Info("Infrahub should be available shortly")
+	if iops.config.Quiet {
+		fmt.Printf("Restored %s\n", backupFile)
+	}
+
+	return nil
+}
 
+// cleanupWorkDir removes a temporary working directory unless --keep-temp is set.
+func (iops *InfrahubOps) cleanupWorkDir(workDir string) {
+	if iops.config.KeepTemp {
+		logrus.Infof("Keeping temporary directory %s", workDir)
+		return
+	}
+	if err := os.RemoveAll(workDir); err != nil {
+		logrus.Warnf("Failed to remove temporary directory %s: %v", workDir, err)
+	}
+}
+
+// restoreConfirmationPrompt is the text confirmDestructiveRestore shows before reading the
+// answer.
+func (iops *InfrahubOps) restoreConfirmationPrompt(backupFile, origin, backendName, target string) string {
+	var prompt strings.Builder
+	if origin != "" {
+		fmt.Fprintf(&prompt, "\n%s has been downloaded and checked; nothing in %s %q has changed yet.\n", origin, backendName, target)
+		if !iops.config.KeepTemp {
+			prompt.WriteString("If you don't confirm, the downloaded copy is deleted.\n")
+		}
+	}
+	fmt.Fprintf(&prompt, "\nRestoring %s will WIPE and replace all data in %s %q.\n", filepath.Base(backupFile), backendName, target)
+	fmt.Fprintf(&prompt, "Type %q to proceed: ", target)
+	return prompt.String()
+}
+
+// confirmDestructiveRestore asks the operator to type the target project/namespace before
+// a restore wipes data. --confirm-destructive/--yes skips the prompt; without a TTY the
+// flag is mandatory. An archive from S3 has already been downloaded by then, since the
+// prompt follows the checks and the restore plan, so the prompt says so.
+func (iops *InfrahubOps) confirmDestructiveRestore(backupFile, origin string) error {
+	if iops.config.ConfirmDestructive {
+		logrus.Warn("Destructive restore confirmed via --confirm-destructive")
+		return nil
+	}
+
+	backend, err := iops.ensureBackend()
+	if err != nil {
+		return err
+	}
+	target := backend.Info()
+	if target == "" {
+		target = backend.Name()
+	}
+
+	if !isInteractiveTerminal(os.Stdin) {
+		return fmt.Errorf("restore would overwrite %s %q and no TTY is available to confirm; re-run with --confirm-destructive (or --yes)", backend.Name(), target)
+	}
+
+	fmt.Fprint(os.Stderr, iops.restoreConfirmationPrompt(backupFile, origin, backend.Name(), target))
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(answer) != target {
+		return fmt.Errorf("restore aborted: confirmation did not match %q", target)
+	}
 	return nil
 }
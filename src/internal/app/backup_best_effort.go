@@ -0,0 +1,97 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// partialBackupMarker is appended to the name of an archive that misses failed components.
+const partialBackupMarker = "_partial"
+
+// ErrPartialBackup marks a --best-effort backup that archived only some of its components.
+var ErrPartialBackup = errors.New("partial backup")
+
+// tolerateComponentFailure decides what a failed backup component does to the backup. Without
+// --best-effort the error is returned and the backup aborts. With it, the failure is recorded in
+// the metadata, the files the component left behind are removed and the backup goes on.
+func (iops *InfrahubOps) tolerateComponentFailure(metadata *BackupMetadata, component string, err error, paths ...string) error {
+	if !iops.config.BestEffort {
+		return err
+	}
+	logrus.WithField("component", component).Errorf("Component failed; continuing without it because of --best-effort: %v", err)
+	for _, path := range paths {
+		if removeErr := os.RemoveAll(path); removeErr != nil {
+			logrus.Warnf("Failed to remove incomplete %s: %v", path, removeErr)
+		}
+	}
+	if metadata.FailedComponents == nil {
+		metadata.FailedComponents = map[string]string{}
+	}
+	metadata.FailedComponents[component] = err.Error()
+	metadata.Components = slices.DeleteFunc(metadata.Components, func(c string) bool { return c == component })
+	return nil
+}
+
+// tolerateUnavailable lets --best-effort go on when a check fails that only guards components
+// which are attempted, and recorded as failed, later on.
+func (iops *InfrahubOps) tolerateUnavailable(err error) error {
+	if err == nil || !iops.config.BestEffort {
+		return err
+	}
+	logrus.Warnf("Continuing because of --best-effort: %v", err)
+	return nil
+}
+
+// failedComponentNames returns the failed components of a backup, sorted.
+func (metadata *BackupMetadata) failedComponentNames() []string {
+	names := make([]string, 0, len(metadata.FailedComponents))
+	for name := range metadata.FailedComponents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// includesNeo4j reports whether the archive holds the Infrahub database. A partial backup or
+// one taken with --exclude-components neo4j lacks it.
+func (metadata *BackupMetadata) includesNeo4j() bool {
+	return slices.Contains(metadata.Components, "database")
+}
+
+// markPartialBackup flags the metadata of a backup with failed components and returns the
+// archive name with partialBackupMarker, so that the archive stands out in listings.
+func markPartialBackup(metadata *BackupMetadata, filename string) string {
+	metadata.Partial = true
+	suffix := backupArchiveSuffix(filename)
+	filename = strings.TrimSuffix(filename, suffix) + partialBackupMarker + suffix
+	metadata.BackupID = strings.TrimSuffix(filename, suffix)
+	return filename
+}
+
+// isPartialBackupName reports whether an archive name carries partialBackupMarker.
+func isPartialBackupName(name string) bool {
+	return strings.HasSuffix(trimBackupArchiveSuffix(filepath.Base(name)), partialBackupMarker)
+}
+
+// warnPartialRestore logs the components missing from a partial backup before it is restored.
+func warnPartialRestore(metadata *BackupMetadata) {
+	if !metadata.Partial {
+		return
+	}
+	logrus.Warnf("Backup %s is a PARTIAL backup taken with --best-effort; only the components it contains are restored", metadata.BackupID)
+	for _, name := range metadata.failedComponentNames() {
+		logrus.WithField("component", name).Warnf("Missing from the backup, left as is in the target: %s", metadata.FailedComponents[name])
+	}
+}
+
+// partialBackupError is returned by a --best-effort backup that wrote an archive without some
+// components, so that callers and the exit code can tell it from a complete backup.
+func partialBackupError(metadata *BackupMetadata, backupPath string) error {
+	return fmt.Errorf("%w: %s was created without %s", ErrPartialBackup, filepath.Base(backupPath), strings.Join(metadata.failedComponentNames(), ", "))
+}
@@ -1,31 +1,69 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 const (
-	backupMetadataFilename = "backup_information.json"
-	prefectDumpFilename    = "prefect.dump"
-	neo4jBackupDirName     = "database"
+	backupMetadataFilename    = "backup_information.json"
+	prefectDumpFilename       = "prefect.dump"
+	neo4jBackupDirName        = "database"
+	deploymentConfigDirName   = "config"
+	deploymentConfigComponent = "config"
+	neo4jAuthDirName          = "neo4j-auth"
+	neo4jAuthComponent        = "neo4j-auth"
 )
 
+// ErrChecksumMismatch is returned when a backup file does not match its recorded checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
 // calculateBackupChecksums calculates SHA256 checksums for all backup files
-func calculateBackupChecksums(backupDir string, excludeTaskManager bool) (map[string]string, error) {
+func calculateBackupChecksums(files *fileLimiter, backupDir string, taskManagerDumps []string) (map[string]string, error) {
 	checksums := make(map[string]string)
 
-	// Calculate checksums for Neo4j backup files
+	// Calculate checksums for Neo4j backup files, absent only from a partial backup
 	neo4jDir := filepath.Join(backupDir, neo4jBackupDirName)
-	if err := calculateDirectoryChecksums(backupDir, neo4jDir, checksums); err != nil {
-		return nil, fmt.Errorf("failed to calculate Neo4j backup checksums: %w", err)
+	if info, err := os.Stat(neo4jDir); err == nil && info.IsDir() {
+		if err := calculateDirectoryChecksums(files, backupDir, neo4jDir, checksums); err != nil {
+			return nil, fmt.Errorf("failed to calculate Neo4j backup checksums: %w", err)
+		}
 	}
 
-	// Calculate checksum for Prefect DB dump if included
-	if !excludeTaskManager {
-		prefectPath := filepath.Join(backupDir, prefectDumpFilename)
-		if err := calculateFileChecksum(backupDir, prefectPath, prefectDumpFilename, checksums); err != nil {
+	// Calculate checksums for the deployment configuration snapshot if captured
+	configDir := filepath.Join(backupDir, deploymentConfigDirName)
+	if info, err := os.Stat(configDir); err == nil && info.IsDir() {
+		if err := calculateDirectoryChecksums(files, backupDir, configDir, checksums); err != nil {
+			return nil, fmt.Errorf("failed to calculate configuration checksums: %w", err)
+		}
+	}
+
+	// Calculate checksums for the Neo4j users captured from a Community instance
+	authDir := filepath.Join(backupDir, neo4jAuthDirName)
+	if info, err := os.Stat(authDir); err == nil && info.IsDir() {
+		if err := calculateDirectoryChecksums(files, backupDir, authDir, checksums); err != nil {
+			return nil, fmt.Errorf("failed to calculate neo4j auth checksums: %w", err)
+		}
+	}
+
+	// Calculate checksums for the Neo4j schema if captured
+	if fileExists(filepath.Join(backupDir, neo4jSchemaFilename)) {
+		if err := calculateFileChecksum(files, backupDir, filepath.Join(backupDir, neo4jSchemaFilename), neo4jSchemaFilename, checksums); err != nil {
+			return nil, err
+		}
+	}
+
+	// Calculate checksums for the task manager DB dumps if included
+	for _, dump := range taskManagerDumps {
+		if err := calculateFileChecksum(files, backupDir, filepath.Join(backupDir, dump), dump, checksums); err != nil {
 			return nil, err
 		}
 	}
@@ -34,7 +72,7 @@ func calculateBackupChecksums(backupDir string, excludeTaskManager bool) (map[st
 }
 
 // calculateDirectoryChecksums walks a directory and calculates checksums for all files
-func calculateDirectoryChecksums(baseDir, targetDir string, checksums map[string]string) error {
+func calculateDirectoryChecksums(files *fileLimiter, baseDir, targetDir string, checksums map[string]string) error {
 	return filepath.Walk(targetDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -48,7 +86,7 @@ func calculateDirectoryChecksums(baseDir, targetDir string, checksums map[string
 			return fmt.Errorf("failed to get relative path for %s: %w", path, err)
 		}
 
-		sum, err := calculateSHA256(path)
+		sum, err := calculateSHA256Context(context.Background(), files, path)
 		if err != nil {
 			return fmt.Errorf("failed to calculate checksum for %s: %w", relPath, err)
 		}
@@ -59,7 +97,7 @@ func calculateDirectoryChecksums(baseDir, targetDir string, checksums map[string
 }
 
 // calculateFileChecksum calculates checksum for a single file if it exists
-func calculateFileChecksum(baseDir, filePath, relativeName string, checksums map[string]string) error {
+func calculateFileChecksum(files *fileLimiter, baseDir, filePath, relativeName string, checksums map[string]string) error {
 	stat, err := os.Stat(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -69,7 +107,7 @@ func calculateFileChecksum(baseDir, filePath, relativeName string, checksums map
 	}
 
 	if !stat.IsDir() {
-		sum, err := calculateSHA256(filePath)
+		sum, err := calculateSHA256Context(context.Background(), files, filePath)
 		if err != nil {
 			return fmt.Errorf("failed to calculate %s checksum: %w", relativeName, err)
 		}
@@ -79,52 +117,110 @@ func calculateFileChecksum(baseDir, filePath, relativeName string, checksums map
 	return nil
 }
 
-// validateBackupChecksums validates all checksums in the backup metadata
-func validateBackupChecksums(workDir string, metadata *BackupMetadata, excludeTaskManager bool) error {
+// checksumProgressInterval is how often checksum validation logs its progress.
+const checksumProgressInterval = 10 * time.Second
+
+// validateBackupChecksums validates all checksums in the backup metadata. It stops as soon as
+// ctx is cancelled, which lets CTRL+C abort a restore before anything has been changed.
+func validateBackupChecksums(ctx context.Context, files *fileLimiter, workDir string, metadata *BackupMetadata, excludeTaskManager bool) error {
 	backupDir := filepath.Join(workDir, "backup")
 
 	// Validate Neo4j backup file checksums
-	for relPath, expectedSum := range metadata.Checksums {
+	var relPaths []string
+	for relPath := range metadata.Checksums {
 		if relPath == prefectDumpFilename {
 			continue // Handle separately
 		}
-
-		filePath := filepath.Join(backupDir, relPath)
-		if err := validateFileChecksum(filePath, relPath, expectedSum); err != nil {
-			return err
+		if excludeTaskManager && isTaskManagerDump(relPath) {
+			continue
 		}
+		relPaths = append(relPaths, relPath)
 	}
+	slices.Sort(relPaths)
 
 	// Validate Prefect DB dump checksum if applicable
 	if !excludeTaskManager {
 		prefectPath := filepath.Join(backupDir, prefectDumpFilename)
 		if _, err := os.Stat(prefectPath); err == nil {
-			expectedSum, ok := metadata.Checksums[prefectDumpFilename]
-			if !ok {
+			if _, ok := metadata.Checksums[prefectDumpFilename]; !ok {
 				return fmt.Errorf("missing checksum for %s in metadata", prefectDumpFilename)
 			}
-			if err := validateFileChecksum(prefectPath, prefectDumpFilename, expectedSum); err != nil {
-				return err
-			}
+			relPaths = append(relPaths, prefectDumpFilename)
+		}
+	}
+
+	logrus.Infof("Validating checksums of %d backup files...", len(relPaths))
+	lastProgress := time.Now()
+	for i, relPath := range relPaths {
+		filePath := filepath.Join(backupDir, relPath)
+		err := ctx.Err()
+		if err == nil {
+			err = validateFileChecksum(ctx, files, filePath, relPath, metadata.Checksums[relPath])
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("checksum validation interrupted after %d of %d files; nothing was changed: %w", i, len(relPaths), ctx.Err())
+		}
+		if err != nil {
+			return err
+		}
+		if time.Since(lastProgress) >= checksumProgressInterval {
+			logrus.Infof("Validated %d/%d files", i+1, len(relPaths))
+			lastProgress = time.Now()
 		}
 	}
+	logrus.Infof("Validated %d/%d files", len(relPaths), len(relPaths))
+
+	return nil
+}
 
+// validateNoUnlistedFiles fails when the extracted backup holds a file that has no checksum in
+// the metadata (--strict-checksums), so that content added to an archive cannot go unverified.
+// Only the metadata and its signature are allowed without a checksum.
+func validateNoUnlistedFiles(workDir string, metadata *BackupMetadata) error {
+	backupDir := filepath.Join(workDir, "backup")
+	var unlisted []string
+	err := filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(backupDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", path, err)
+		}
+		switch relPath {
+		case backupMetadataFilename, backupSignatureFilename:
+			return nil
+		}
+		if _, ok := metadata.Checksums[relPath]; !ok {
+			unlisted = append(unlisted, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list backup files: %w", err)
+	}
+	if len(unlisted) > 0 {
+		return fmt.Errorf("%w: --strict-checksums is set and the archive contains files without a checksum in the metadata: %s", ErrChecksumMismatch, strings.Join(unlisted, ", "))
+	}
 	return nil
 }
 
 // validateFileChecksum validates a single file's checksum
-func validateFileChecksum(filePath, name, expectedSum string) error {
+func validateFileChecksum(ctx context.Context, files *fileLimiter, filePath, name, expectedSum string) error {
 	if _, err := os.Stat(filePath); err != nil {
 		return fmt.Errorf("missing backup file: %s", name)
 	}
 
-	actualSum, err := calculateSHA256(filePath)
+	actualSum, err := calculateSHA256Context(ctx, files, filePath)
 	if err != nil {
 		return fmt.Errorf("failed to calculate checksum for %s: %w", name, err)
 	}
 
 	if actualSum != expectedSum {
-		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expectedSum, actualSum)
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, name, expectedSum, actualSum)
 	}
 
 	return nil
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+// copyTestBackup copies a Neo4j dump and a task manager dump out of the fake containers into
+// workDir/backup and returns the metadata with their checksums.
+func copyTestBackup(t *testing.T, iops *InfrahubOps, fake *apptest.FakeBackend, workDir string) *BackupMetadata {
+	t.Helper()
+	fake.SetFile("database", "/tmp/neo4j.dump", []byte("neo4j dump"))
+	fake.SetFile("task-manager-db", "/tmp/prefect.dump", []byte("prefect dump"))
+
+	backupDir := filepath.Join(workDir, "backup")
+	if err := iops.CopyFrom("database", "/tmp/neo4j.dump", filepath.Join(backupDir, neo4jBackupDirName, "neo4j.dump")); err != nil {
+		t.Fatalf("copy neo4j dump: %v", err)
+	}
+	if err := iops.CopyFrom("task-manager-db", "/tmp/prefect.dump", filepath.Join(backupDir, prefectDumpFilename)); err != nil {
+		t.Fatalf("copy prefect dump: %v", err)
+	}
+	checksums, err := calculateBackupChecksums(iops.files, backupDir, []string{prefectDumpFilename})
+	if err != nil {
+		t.Fatalf("calculateBackupChecksums: %v", err)
+	}
+	if len(checksums) != 2 {
+		t.Fatalf("checksums = %v, want the two dumps", checksums)
+	}
+	return &BackupMetadata{Checksums: checksums}
+}
+
+func TestValidateBackupChecksums(t *testing.T) {
+	tests := []struct {
+		name               string
+		change             func(backupDir string) error
+		excludeTaskManager bool
+		wantMismatch       bool
+		wantErr            bool
+	}{
+		{name: "unchanged"},
+		{
+			name: "neo4j dump modified",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, neo4jBackupDirName, "neo4j.dump"), []byte("tampered"), 0644)
+			},
+			wantMismatch: true,
+		},
+		{
+			name: "task manager dump modified",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, prefectDumpFilename), []byte("tampered"), 0644)
+			},
+			wantMismatch: true,
+		},
+		{
+			name: "task manager dump modified but excluded",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, prefectDumpFilename), []byte("tampered"), 0644)
+			},
+			excludeTaskManager: true,
+		},
+		{
+			name:    "neo4j dump missing",
+			change:  func(dir string) error { return os.Remove(filepath.Join(dir, neo4jBackupDirName, "neo4j.dump")) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := apptest.NewFakeBackend("database", "task-manager-db")
+			iops := newTestOps(t, fake)
+			workDir := t.TempDir()
+			metadata := copyTestBackup(t, iops, fake, workDir)
+			if tt.change != nil {
+				if err := tt.change(filepath.Join(workDir, "backup")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateBackupChecksums(context.Background(), iops.files, workDir, metadata, tt.excludeTaskManager)
+			switch {
+			case tt.wantMismatch:
+				if !errors.Is(err, ErrChecksumMismatch) {
+					t.Errorf("err = %v, want ErrChecksumMismatch", err)
+				}
+			case tt.wantErr:
+				if err == nil || errors.Is(err, ErrChecksumMismatch) {
+					t.Errorf("err = %v, want a missing file error", err)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateBackupChecksumsStopsWhenCancelled(t *testing.T) {
+	fake := apptest.NewFakeBackend("database", "task-manager-db")
+	iops := newTestOps(t, fake)
+	workDir := t.TempDir()
+	metadata := copyTestBackup(t, iops, fake, workDir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := validateBackupChecksums(ctx, iops.files, workDir, metadata, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	remoteCleanupAttempts = 3
+	remoteCleanupBackoff  = 2 * time.Second
+
+	// pgDumpStagingPrefix names the pg_dump files staged in the task manager database container.
+	pgDumpStagingPrefix = "infrahubops_"
+)
+
+// removeRemotePaths deletes paths inside service, retrying transient exec failures. When every
+// attempt fails it logs the exact paths so they can be removed by hand, since leaving them
+// behind can fill the container's filesystem over repeated runs.
+func (iops *InfrahubOps) removeRemotePaths(service string, paths ...string) {
+	if len(paths) == 0 {
+		return
+	}
+	command := append([]string{"rm", "-rf"}, paths...)
+	var err error
+	for attempt := 1; attempt <= remoteCleanupAttempts; attempt++ {
+		var output string
+		if output, err = iops.Exec(service, command, nil); err == nil {
+			return
+		}
+		logrus.Debugf("Cleanup attempt %d/%d in %s failed: %v (%s)", attempt, remoteCleanupAttempts, service, err, strings.TrimSpace(output))
+		if attempt < remoteCleanupAttempts {
+			time.Sleep(remoteCleanupBackoff * time.Duration(attempt))
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"service": service,
+		"paths":   strings.Join(paths, " "),
+	}).Warnf("Failed to remove staging data after %d attempts: %v; remove it manually with: rm -rf %s", remoteCleanupAttempts, err, strings.Join(paths, " "))
+}
+
+// cleanupStaleStaging removes staging data left in the containers by earlier runs that were
+// killed before their own cleanup ran (--cleanup-on-start).
+func (iops *InfrahubOps) cleanupStaleStaging(excludeTaskManager bool) error {
+	logrus.Info("Removing stale staging data from previous runs...")
+	if !iops.isExternalNeo4j() {
+		iops.removeRemotePaths("database", neo4jTempBackupDir)
+	}
+	if excludeTaskManager {
+		return nil
+	}
+
+	client, err := iops.postgresClient(iops.config.tool(pgDumpTool))
+	if err != nil || client == "" {
+		// The dump is written locally; nothing is staged in a container
+		return nil
+	}
+	dirs := []string{}
+	for _, dir := range []string{iops.config.PgTempDir, "/tmp", "/var/tmp", iops.pgDataParentDir(client), "/run"} {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	script := `for d in "$@"; do ls -d "$d"/` + pgDumpStagingPrefix + `* 2>/dev/null; done; true`
+	output, err := iops.Exec(client, append([]string{"sh", "-c", script, "sh"}, dirs...), nil)
+	if err != nil {
+		return fmt.Errorf("failed to list stale postgres dumps in %s: %w", client, err)
+	}
+	stale := strings.Fields(output)
+	if len(stale) > 0 {
+		logrus.WithField("files", strings.Join(stale, " ")).Infof("Removing %d stale postgres dumps from %s", len(stale), client)
+		iops.removeRemotePaths(client, stale...)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// backupDeploymentConfig captures a redacted snapshot of the deployment configuration
+// (compose config or Kubernetes ConfigMaps) for reference. It is never applied on restore.
+func (iops *InfrahubOps) backupDeploymentConfig(backupDir string) error {
+	logrus.Info("Capturing deployment configuration...")
+
+	backend, err := iops.ensureBackend()
+	if err != nil {
+		return err
+	}
+
+	configDir := filepath.Join(backupDir, deploymentConfigDirName)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := backend.CaptureConfig(configDir); err != nil {
+		return fmt.Errorf("failed to capture deployment configuration: %w", err)
+	}
+
+	logrus.Info("Deployment configuration captured")
+	return nil
+}
@@ -91,17 +91,29 @@ func (iops *InfrahubOps) waitForRunningTasks() error {
 	}
 }
 
+// logServicesLeftStopped tells the operator which services --no-restart left down and how to start them.
+func (iops *InfrahubOps) logServicesLeftStopped(services []string) {
+	logrus.Warnf("--no-restart: services remain STOPPED: %s", strings.Join(services, ", "))
+	switch {
+	case iops.backend != nil && iops.backend.Name() == "kubernetes":
+		logrus.Warnf("Scale their deployments/statefulsets back up in namespace %s when maintenance is complete", iops.backend.Info())
+	case iops.backend != nil:
+		logrus.Warnf("Start them when maintenance is complete: docker compose -p %s start %s", iops.backend.Info(), strings.Join(services, " "))
+	}
+}
+
+// appContainerServices lists the application services stopped around backups and restores
+var appContainerServices = []string{
+	"infrahub-server", "task-worker", "task-manager",
+	"task-manager-background-svc", "cache", "message-queue",
+}
+
 func (iops *InfrahubOps) stopAppContainers() ([]string, error) {
 	logrus.Info("Stopping Infrahub application services...")
 
-	services := []string{
-		"infrahub-server", "task-worker", "task-manager",
-		"task-manager-background-svc", "cache", "message-queue",
-	}
-
 	stopped := []string{}
 
-	for _, service := range services {
+	for _, service := range appContainerServices {
 		running, err := iops.IsServiceRunning(service)
 		if err != nil {
 			logrus.Debugf("Could not determine status of %s: %v", service, err)
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"slices"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestStopAppContainersStopsOnlyRunningServices(t *testing.T) {
+	fake := apptest.NewFakeBackend("database", "infrahub-server", "task-worker", "cache")
+	iops := newTestOps(t, fake)
+
+	stopped, err := iops.stopAppContainers()
+	if err != nil {
+		t.Fatalf("stopAppContainers: %v", err)
+	}
+	want := []string{"infrahub-server", "task-worker", "cache"}
+	if !slices.Equal(stopped, want) {
+		t.Errorf("stopped = %v, want %v", stopped, want)
+	}
+	var calls [][]string
+	for _, call := range fake.CallsTo("Stop") {
+		calls = append(calls, call.Args)
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("Stop calls = %v, want one per service in %v", calls, want)
+	}
+	for i, call := range calls {
+		if !slices.Equal(call, []string{want[i]}) {
+			t.Errorf("Stop call %d = %v, want [%s]", i, call, want[i])
+		}
+	}
+	if running, _ := fake.IsRunning("database"); !running {
+		t.Error("database was stopped; only the application services may be")
+	}
+}
+
+func TestStartAppContainersStartsDependenciesFirst(t *testing.T) {
+	fake := apptest.NewFakeBackend("database")
+	iops := newTestOps(t, fake)
+
+	services := []string{"task-worker", "infrahub-server", "task-manager", "message-queue", "cache"}
+	if err := iops.startAppContainers(services); err != nil {
+		t.Fatalf("startAppContainers: %v", err)
+	}
+	var started []string
+	for _, call := range fake.CallsTo("Start") {
+		started = append(started, call.Args...)
+	}
+	want := []string{"cache", "message-queue", "task-manager", "infrahub-server", "task-worker"}
+	if !slices.Equal(started, want) {
+		t.Errorf("start order = %v, want %v", started, want)
+	}
+}
+
+func TestStopThenStartRestoresTheStoppedServices(t *testing.T) {
+	fake := apptest.NewFakeBackend("database", "infrahub-server", "task-worker", "task-manager", "message-queue")
+	iops := newTestOps(t, fake)
+
+	stopped, err := iops.stopAppContainers()
+	if err != nil {
+		t.Fatalf("stopAppContainers: %v", err)
+	}
+	if err := iops.startAppContainers(stopped); err != nil {
+		t.Fatalf("startAppContainers: %v", err)
+	}
+
+	calls := fake.Calls()
+	lastStop, firstStart := -1, len(calls)
+	for i, call := range calls {
+		switch call.Method {
+		case "Stop":
+			lastStop = i
+		case "Start":
+			firstStart = min(firstStart, i)
+		}
+	}
+	if lastStop > firstStart {
+		t.Errorf("a service was started before every service was stopped: %v", calls)
+	}
+	for _, service := range stopped {
+		if running, _ := fake.IsRunning(service); !running {
+			t.Errorf("%s is still stopped", service)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// archiveSummary is the metadata of one archive compared by diff.
+type archiveSummary struct {
+	Reference string
+	Size      int64
+	Metadata  BackupMetadata
+}
+
+// DiffBackups prints the differences between the metadata of two backups, each given as a
+// local path or an s3://bucket/key reference. Only the metadata is read from each archive.
+// The metadata signature is not verified.
+func (iops *InfrahubOps) DiffBackups(referenceA, referenceB string) error {
+	a, err := iops.readArchiveSummary(referenceA)
+	if err != nil {
+		return err
+	}
+	b, err := iops.readArchiveSummary(referenceB)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("A: %s\nB: %s\n\n", a.Reference, b.Reference)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tFIELD\tA\tB")
+	row := func(field, valueA, valueB string) {
+		marker := ""
+		if valueA != valueB {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, field, valueOrDash(valueA), valueOrDash(valueB))
+	}
+	row("Backup ID", a.Metadata.BackupID, b.Metadata.BackupID)
+	row("Created at", a.Metadata.CreatedAt, b.Metadata.CreatedAt)
+	row("Label", a.Metadata.Label, b.Metadata.Label)
+	row("Archive size", formatBytes(a.Size), formatBytes(b.Size))
+	row("Metadata version", strconv.Itoa(a.Metadata.MetadataVersion), strconv.Itoa(b.Metadata.MetadataVersion))
+	row("Tool version", a.Metadata.ToolVersion, b.Metadata.ToolVersion)
+	row("Infrahub version", a.Metadata.InfrahubVersion, b.Metadata.InfrahubVersion)
+	row("Source environment", a.Metadata.SourceEnvironment, b.Metadata.SourceEnvironment)
+	row("Neo4j edition", a.Metadata.Neo4jEdition, b.Metadata.Neo4jEdition)
+	row("Neo4j metadata", a.Metadata.Neo4jMetadata, b.Metadata.Neo4jMetadata)
+	row("Integrity key", a.Metadata.IntegrityKeyID, b.Metadata.IntegrityKeyID)
+	row("Graph stats", graphStatsString(a.Metadata.GraphStats), graphStatsString(b.Metadata.GraphStats))
+	for _, name := range sortedUnion(a.Metadata.DumpSizes, b.Metadata.DumpSizes) {
+		row("Dump size "+name, formatDumpSize(a.Metadata.DumpSizes, name), formatDumpSize(b.Metadata.DumpSizes, name))
+	}
+	for _, name := range sortedUnion(a.Metadata.ComponentSizes, b.Metadata.ComponentSizes) {
+		row("Component size "+name, formatDumpSize(a.Metadata.ComponentSizes, name), formatDumpSize(b.Metadata.ComponentSizes, name))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	onlyA, onlyB := diffStrings(a.Metadata.Components, b.Metadata.Components)
+	if len(onlyA)+len(onlyB) > 0 {
+		fmt.Println("\nComponents:")
+		for _, component := range onlyA {
+			fmt.Printf("  - %s (only in A)\n", component)
+		}
+		for _, component := range onlyB {
+			fmt.Printf("  + %s (only in B)\n", component)
+		}
+	}
+
+	fileChanges := 0
+	fmt.Println("\nFiles:")
+	for _, file := range sortedUnion(a.Metadata.Checksums, b.Metadata.Checksums) {
+		sumA, inA := a.Metadata.Checksums[file]
+		sumB, inB := b.Metadata.Checksums[file]
+		switch {
+		case !inB:
+			fmt.Printf("  - %s (only in A)\n", file)
+		case !inA:
+			fmt.Printf("  + %s (only in B)\n", file)
+		case sumA != sumB:
+			fmt.Printf("  ~ %s (checksum changed)\n", file)
+		default:
+			continue
+		}
+		fileChanges++
+	}
+	if fileChanges == 0 {
+		fmt.Println("  no differences")
+	}
+	return nil
+}
+
+// readArchiveSummary reads the metadata of a local or S3 archive. S3 objects are streamed
+// and the download stops as soon as the metadata entry has been read.
+func (iops *InfrahubOps) readArchiveSummary(reference string) (*archiveSummary, error) {
+	summary := &archiveSummary{Reference: reference}
+	var reader io.ReadCloser
+	if bucket, key, ok := parseS3Reference(reference); ok {
+		iops.config.S3Bucket = bucket
+		if err := iops.validateS3Config(); err != nil {
+			return nil, err
+		}
+		ctx := context.Background()
+		client, err := iops.createS3Client(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 client: %w", err)
+		}
+		output, err := client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", reference, err)
+		}
+		summary.Size = aws.ToInt64(output.ContentLength)
+		reader = output.Body
+	} else {
+		file, err := os.Open(reference)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open backup: %w", err)
+		}
+		if stat, err := file.Stat(); err == nil {
+			summary.Size = stat.Size()
+		}
+		reader = file
+	}
+	defer reader.Close()
+
+	metadata, err := readArchiveMetadataStream(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", reference, err)
+	}
+	summary.Metadata = *metadata
+	return summary, nil
+}
+
+// diffStrings returns the values only present in a and only present in b.
+func diffStrings(a, b []string) (onlyA, onlyB []string) {
+	for _, value := range a {
+		if !slices.Contains(b, value) {
+			onlyA = append(onlyA, value)
+		}
+	}
+	for _, value := range b {
+		if !slices.Contains(a, value) {
+			onlyB = append(onlyB, value)
+		}
+	}
+	return onlyA, onlyB
+}
+
+// sortedUnion returns the keys of both maps, sorted.
+func sortedUnion[V any](a, b map[string]V) []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func formatDumpSize(sizes map[string]int64, name string) string {
+	if size, ok := sizes[name]; ok {
+		return formatBytes(size)
+	}
+	return ""
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	dumpDirPrefix       = "infrahub_dump_"
+	dumpInformationFile = "dump_information.json"
+)
+
+// DumpInformation describes a --dump-only database snapshot. Such snapshots are meant to be
+// shared or loaded by hand and cannot be restored with the restore command.
+type DumpInformation struct {
+	DumpID          string           `json:"dump_id"`
+	CreatedAt       string           `json:"created_at"`
+	ToolVersion     string           `json:"tool_version"`
+	InfrahubVersion string           `json:"infrahub_version"`
+	Neo4jEdition    string           `json:"neo4j_edition"`
+	Neo4jDatabase   string           `json:"neo4j_database"`
+	Format          string           `json:"format"`
+	Files           map[string]int64 `json:"files"`
+}
+
+// createDatabaseDump writes only the Neo4j dump (or backup, or export) and a small
+// dump_information.json into a new directory, without the task manager database or an archive.
+func (iops *InfrahubOps) createDatabaseDump(neo4jMetadata, infrahubVersion, neo4jEdition string) error {
+	parent := iops.config.DumpDir
+	if parent == "" {
+		parent = iops.config.BackupDir
+	}
+	stamp := trimBackupArchiveSuffix(strings.TrimPrefix(iops.generateBackupFilename(), backupFilenamePrefix))
+	dumpID := dumpDirPrefix + stamp
+	dumpDir := filepath.Join(parent, dumpID)
+	if _, err := os.Stat(dumpDir); err == nil {
+		return fmt.Errorf("dump directory %s already exists", dumpDir)
+	}
+	if err := os.MkdirAll(dumpDir, 0755); err != nil {
+		return fmt.Errorf("failed to create dump directory: %w", err)
+	}
+
+	logrus.WithField("path", dumpDir).Info("Creating database dump (--dump-only)")
+	if iops.config.S3Upload {
+		logrus.Warn("--s3-upload is ignored with --dump-only")
+	}
+	if err := iops.backupDatabase(dumpDir, neo4jMetadata, neo4jEdition); err != nil {
+		if removeErr := os.RemoveAll(dumpDir); removeErr != nil {
+			logrus.Warnf("Failed to remove incomplete dump %s: %v", dumpDir, removeErr)
+		}
+		return err
+	}
+
+	info := DumpInformation{
+		DumpID:          dumpID,
+		CreatedAt:       iops.backupTime().Format(time.RFC3339),
+		ToolVersion:     BuildRevision(),
+		InfrahubVersion: infrahubVersion,
+		Neo4jEdition:    strings.ToLower(neo4jEdition),
+		Neo4jDatabase:   iops.config.Neo4jDatabase,
+		Format:          neo4jDumpFormat(neo4jEdition),
+		Files:           map[string]int64{},
+	}
+	var total int64
+	err := filepath.WalkDir(dumpDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dumpDir, path)
+		if err != nil {
+			return err
+		}
+		info.Files[filepath.ToSlash(rel)] = stat.Size()
+		total += stat.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list dump files: %w", err)
+	}
+
+	data, err := json.MarshalIndent(info, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dump information: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dumpDir, dumpInformationFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write dump information: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":   dumpDir,
+		"format": info.Format,
+		"size":   formatBytes(total),
+	}).Info("Database dump created; it is not restorable with the restore command")
+	if iops.config.Quiet {
+		fmt.Printf("%s (%s)\n", dumpDir, formatBytes(total))
+	}
+	return nil
+}
+
+// neo4jDumpFormat names the tool that produced the files, so they can be loaded by hand.
+func neo4jDumpFormat(neo4jEdition string) string {
+	switch strings.ToLower(neo4jEdition) {
+	case neo4jEditionCommunity:
+		return "neo4j-admin database dump"
+	case neo4jEditionExternal:
+		return "apoc cypher export"
+	default:
+		return "neo4j-admin database backup"
+	}
+}
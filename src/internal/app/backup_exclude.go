@@ -0,0 +1,96 @@
+package app
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// validateExcludePatterns rejects malformed --exclude-file patterns before any work is done.
+func validateExcludePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		for _, segment := range strings.Split(pattern, "/") {
+			if segment == "**" {
+				continue
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid --exclude-file pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchExcludePattern matches a slash-separated relative path against a glob pattern in which
+// a "**" segment matches any number of path segments, including none.
+func matchExcludePattern(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// pruneExcludedFiles removes files and directories under componentDir whose path relative to
+// componentDir matches one of the patterns, and returns the removed paths.
+func pruneExcludedFiles(componentDir string, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	var excluded []string
+	err := filepath.WalkDir(componentDir, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if current == componentDir {
+			return nil
+		}
+		rel, err := filepath.Rel(componentDir, current)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, pattern := range patterns {
+			if !matchExcludePattern(pattern, rel) {
+				continue
+			}
+			if err := os.RemoveAll(current); err != nil {
+				return fmt.Errorf("failed to remove excluded path %s: %w", rel, err)
+			}
+			logrus.WithFields(logrus.Fields{"path": rel, "pattern": pattern}).Info("Excluded from backup")
+			excluded = append(excluded, rel)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply exclude patterns in %s: %w", filepath.Base(componentDir), err)
+	}
+	return excluded, nil
+}
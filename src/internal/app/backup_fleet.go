@@ -0,0 +1,157 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// namespaceBackupResult is the outcome of one namespace in a multi-namespace backup.
+type namespaceBackupResult struct {
+	Namespace string
+	BackupDir string
+	Duration  time.Duration
+	Err       error
+}
+
+// CreateNamespaceBackups backs up the Infrahub deployment of every given Kubernetes
+// namespace, or of every namespace with Infrahub pods when namespaces is empty. Each
+// namespace writes to <backup-dir>/<namespace> and, when uploading, to
+// <s3-prefix><namespace>/. A failing namespace does not stop the others; the failures are
+// summarized and returned once all namespaces have been processed.
+func (iops *InfrahubOps) CreateNamespaceBackups(namespaces []string, force bool, neo4jMetadata string, excludeTaskManager bool) error {
+	if iops.config.RecordBackend != "" || iops.config.ReplayBackend != "" {
+		return fmt.Errorf("%w: --record-backend and --replay-backend cannot be used with a multi-namespace backup", ErrPrerequisites)
+	}
+	if len(namespaces) == 0 {
+		discovered, err := ListKubernetesNamespaces(iops.executor, iops.config.tool(kubectlTool))
+		if err != nil {
+			return fmt.Errorf("failed to list namespaces with Infrahub deployments: %w", err)
+		}
+		if len(discovered) == 0 {
+			return fmt.Errorf("no Kubernetes namespaces with Infrahub pods (app.kubernetes.io/name=infrahub) found")
+		}
+		namespaces = discovered
+	}
+	if iops.config.AuditLog != "" && iops.auditLog == nil {
+		log, err := openAuditLog(iops.config.AuditLog)
+		if err != nil {
+			return err
+		}
+		iops.auditLog = log
+	}
+	namespaces = unique(namespaces)
+	logrus.WithField("namespaces", strings.Join(namespaces, ", ")).Infof("Backing up %d Infrahub namespaces", len(namespaces))
+
+	results := make([]namespaceBackupResult, 0, len(namespaces))
+	for i, namespace := range namespaces {
+		logrus.WithFields(logrus.Fields{
+			"namespace": namespace,
+			"progress":  fmt.Sprintf("%d/%d", i+1, len(namespaces)),
+		}).Info("Starting namespace backup")
+
+		start := time.Now()
+		ops := iops.namespaceOps(namespace)
+		err := ops.detectNamespace()
+		if err == nil {
+			err = ops.CreateBackupWithRetries(force, neo4jMetadata, excludeTaskManager)
+		}
+		if err != nil {
+			logrus.WithField("namespace", namespace).Errorf("Namespace backup failed: %v", err)
+		}
+		results = append(results, namespaceBackupResult{
+			Namespace: namespace,
+			BackupDir: ops.archiveDir(),
+			Duration:  time.Since(start),
+			Err:       err,
+		})
+	}
+
+	printNamespaceBackupSummary(results)
+
+	return namespaceBackupError(results)
+}
+
+// namespaceBackupError joins the errors of the failed namespaces under a summary. Partial
+// backups only count when no namespace failed outright, so that the exit code of a real
+// failure is never hidden behind the partial status of another namespace.
+func namespaceBackupError(results []namespaceBackupResult) error {
+	failed := []string{}
+	var errs, partialErrs []error
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		failed = append(failed, result.Namespace)
+		err := fmt.Errorf("%s: %w", result.Namespace, result.Err)
+		if errors.Is(result.Err, ErrPartialBackup) {
+			partialErrs = append(partialErrs, err)
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	if len(errs) == 0 {
+		errs = partialErrs
+	}
+	summary := fmt.Errorf("backup failed for %d of %d namespaces: %s", len(failed), len(results), strings.Join(failed, ", "))
+	return errors.Join(append([]error{summary}, errs...)...)
+}
+
+// namespaceOps returns an InfrahubOps bound to one namespace. It works on a copy of the
+// configuration so that credentials read from one deployment never leak into the next.
+func (iops *InfrahubOps) namespaceOps(namespace string) *InfrahubOps {
+	cfg := *iops.config
+	cfg.K8sNamespace = namespace
+	cfg.BackupDir = filepath.Join(iops.config.BackupDir, namespace)
+	cfg.S3Prefix = iops.config.S3Prefix + namespace + "/"
+	if cfg.OutputDir != "" {
+		cfg.OutputDir = filepath.Join(iops.config.OutputDir, namespace)
+	}
+	if cfg.SummaryFile != "" {
+		ext := filepath.Ext(cfg.SummaryFile)
+		cfg.SummaryFile = strings.TrimSuffix(cfg.SummaryFile, ext) + "_" + namespace + ext
+	}
+	ops := NewInfrahubOps()
+	ops.config = &cfg
+	ops.executor = iops.executor
+	ops.auditLog = iops.auditLog
+	ops.files = iops.files
+	return ops
+}
+
+// detectNamespace pins the backend to Kubernetes so that a namespace that cannot be reached
+// fails instead of falling back to a local Docker Compose deployment.
+func (iops *InfrahubOps) detectNamespace() error {
+	backend := iops.getKubernetesBackend()
+	if err := backend.Detect(); err != nil {
+		return fmt.Errorf("namespace %s: %w", iops.config.K8sNamespace, err)
+	}
+	iops.backend = backend
+	if err := os.MkdirAll(iops.config.BackupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory %s: %w", iops.config.BackupDir, err)
+	}
+	return nil
+}
+
+func printNamespaceBackupSummary(results []namespaceBackupResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tRESULT\tDURATION\tBACKUP DIR")
+	for _, result := range results {
+		status := "ok"
+		if result.Err != nil {
+			message, _, _ := strings.Cut(result.Err.Error(), "\n")
+			status = "FAILED: " + message
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Namespace, status, result.Duration.Round(time.Second), result.BackupDir)
+	}
+	w.Flush()
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// graphStatsQuery returns the node and relationship counts as one "nodes,relationships"
+// string, so cypher-shell and the Bolt driver read it the same way. Each count runs in its own
+// subquery: chained after the node count, the relationship MATCH would return no row at all on
+// a graph without relationships, and would be planned as a scan instead of a count store lookup.
+const graphStatsQuery = "CALL { MATCH (n) RETURN count(n) AS nodes } " +
+	"CALL { MATCH ()-[r]->() RETURN count(r) AS rels } " +
+	"RETURN toString(nodes) + ',' + toString(rels)"
+
+// GraphStats are the node and relationship totals of the Infrahub database, recorded in the
+// backup metadata and compared by restore --neo4j-restore-verify.
+type GraphStats struct {
+	Nodes         int64 `json:"nodes"`
+	Relationships int64 `json:"relationships"`
+}
+
+func (s GraphStats) String() string {
+	return fmt.Sprintf("%d nodes, %d relationships", s.Nodes, s.Relationships)
+}
+
+// collectGraphStats counts the nodes and relationships of the Infrahub database, over Bolt for
+// an external Neo4j and with cypher-shell otherwise.
+func (iops *InfrahubOps) collectGraphStats() (GraphStats, error) {
+	var values []string
+	if iops.isExternalNeo4j() {
+		records, err := iops.queryNeo4jExternal(graphStatsQuery)
+		if err != nil {
+			return GraphStats{}, fmt.Errorf("failed to count neo4j nodes: %w", err)
+		}
+		values = records
+	} else {
+		output, err := iops.cypherQuery(graphStatsQuery)
+		if err != nil {
+			return GraphStats{}, fmt.Errorf("failed to count neo4j nodes: %w\nOutput: %v", err, output)
+		}
+		values = parseCypherStrings(output)
+	}
+	if len(values) != 1 {
+		return GraphStats{}, fmt.Errorf("unexpected neo4j count result %q", values)
+	}
+	nodes, relationships, _ := strings.Cut(values[0], ",")
+	var counts GraphStats
+	var err error
+	if counts.Nodes, err = strconv.ParseInt(nodes, 10, 64); err != nil {
+		return GraphStats{}, fmt.Errorf("unexpected neo4j node count %q: %w", nodes, err)
+	}
+	if counts.Relationships, err = strconv.ParseInt(relationships, 10, 64); err != nil {
+		return GraphStats{}, fmt.Errorf("unexpected neo4j relationship count %q: %w", relationships, err)
+	}
+	return counts, nil
+}
+
+// graphStatsString formats recorded stats, or "" for backups without them.
+func graphStatsString(stats *GraphStats) string {
+	if stats == nil {
+		return ""
+	}
+	return stats.String()
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestCollectGraphStats(t *testing.T) {
+	header := "toString(nodes) + ',' + toString(rels)\n"
+	tests := []struct {
+		name     string
+		response apptest.Response
+		want     GraphStats
+		wantErr  bool
+	}{
+		{name: "counts", response: apptest.Response{Output: header + "\"1250,4312\"\n"}, want: GraphStats{Nodes: 1250, Relationships: 4312}},
+		{name: "no relationships", response: apptest.Response{Output: header + "\"12,0\"\n"}, want: GraphStats{Nodes: 12}},
+		{name: "empty graph", response: apptest.Response{Output: header + "\"0,0\"\n"}},
+		{name: "no row", response: apptest.Response{Output: header}, wantErr: true},
+		{name: "not a number", response: apptest.Response{Output: header + "\"12,\"\n"}, wantErr: true},
+		{name: "query fails", response: apptest.Response{Err: errors.New("connection refused")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := apptest.NewFakeBackend("database")
+			fake.On("database", []string{cypherShellTool}, tt.response)
+			iops := newTestOps(t, fake)
+
+			got, err := iops.collectGraphStats()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("collectGraphStats() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("collectGraphStats() = %+v, want %+v", got, tt.want)
+			}
+			calls := fake.CallsTo("Exec")
+			if len(calls) != 1 || !slices.Contains(calls[0].Args, graphStatsQuery) {
+				t.Errorf("Exec calls = %+v, want one cypher-shell call with graphStatsQuery", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultHealthTimeout  = 5 * time.Minute
+	healthPollInterval    = 5 * time.Second
+	infrahubServerService = "infrahub-server"
+	// infrahubHealthURL is the endpoint the upstream compose healthcheck probes.
+	infrahubHealthURL = "http://localhost:8000/api/config"
+)
+
+// infrahubHealthScript exits non-zero unless the URL answers with a 2xx status.
+// Python is used because it is always present in the Infrahub image, unlike curl.
+const infrahubHealthScript = `import sys, urllib.request
+urllib.request.urlopen(sys.argv[1], timeout=5)`
+
+// waitForInfrahubHealthy polls infrahub-server from inside its container until the API
+// responds or the timeout expires.
+func (iops *InfrahubOps) waitForInfrahubHealthy(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	logrus.WithFields(logrus.Fields{
+		"url":     infrahubHealthURL,
+		"timeout": timeout,
+	}).Info("Waiting for infrahub-server to become healthy...")
+
+	deadline := time.Now().Add(timeout)
+	attempts := 0
+	var lastErr string
+	for {
+		attempts++
+		output, err := iops.Exec(infrahubServerService, []string{"python", "-c", infrahubHealthScript, infrahubHealthURL}, nil)
+		if err == nil {
+			logrus.WithField("attempts", attempts).Info("infrahub-server is healthy")
+			return nil
+		}
+		lastErr = strings.TrimSpace(err.Error() + " " + output)
+		logrus.Debugf("infrahub-server not ready yet (attempt %d): %s", attempts, lastErr)
+
+		if time.Now().Add(healthPollInterval).After(deadline) {
+			return fmt.Errorf("infrahub-server did not become healthy within %s: %w: %s", timeout, ErrTimeout, lastErr)
+		}
+		time.Sleep(healthPollInterval)
+	}
+}
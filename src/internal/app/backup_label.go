@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxBackupLabelLength = 64
+	// s3LabelMetadataKey is the user metadata key, x-amz-meta-infrahub-label, of uploaded archives.
+	s3LabelMetadataKey = "infrahub-label"
+	// s3KeyIDMetadataKey carries the integrity key identifier of uploaded archives.
+	s3KeyIDMetadataKey = "infrahub-integrity-key-id"
+)
+
+// backupLabelPattern keeps labels readable in listings and valid as S3 metadata, which only
+// carries ASCII.
+var backupLabelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ._:/@+=-]*$`)
+
+// validateBackupLabel checks --label; an empty label is allowed and means no label.
+func validateBackupLabel(label string) error {
+	if label == "" {
+		return nil
+	}
+	if len(label) > maxBackupLabelLength {
+		return fmt.Errorf("invalid --label %q: longer than %d characters", label, maxBackupLabelLength)
+	}
+	if !backupLabelPattern.MatchString(label) {
+		return fmt.Errorf("invalid --label %q: must start with a letter or digit and contain only letters, digits, spaces and . _ : / @ + = -", label)
+	}
+	return nil
+}
+
+// annotateBackups fills in the label and integrity key identifier of each entry, from the
+// archive metadata for local backups and from the object metadata for S3, and keeps only those
+// labeled label when it is set. Metadata that cannot be read is logged and left empty.
+func (iops *InfrahubOps) annotateBackups(entries []backupEntry, label string) []backupEntry {
+	var client *s3.Client
+	var clientErr error
+	ctx := context.Background()
+	labeled := make([]backupEntry, 0, len(entries))
+	for _, entry := range entries {
+		var err error
+		if entry.Location == backupLocationS3 {
+			if client == nil && clientErr == nil {
+				client, clientErr = iops.createS3Client(ctx)
+			}
+			if err = clientErr; client != nil {
+				entry.Label, entry.KeyID, err = iops.s3BackupAnnotations(ctx, client, entry.Name)
+			}
+		} else {
+			var metadata *BackupMetadata
+			if metadata, err = readArchiveMetadata(filepath.Join(iops.config.BackupDir, entry.Name)); err == nil {
+				entry.Label, entry.KeyID = metadata.Label, metadata.IntegrityKeyID
+			}
+		}
+		if err != nil {
+			logrus.Debugf("Failed to read the metadata of %s: %v", entry.Name, err)
+		}
+		labeled = append(labeled, entry)
+	}
+	return filterBackupsByLabel(labeled, label)
+}
+
+// filterBackupsByLabel keeps the entries labeled label, or all of them when label is empty.
+func filterBackupsByLabel(entries []backupEntry, label string) []backupEntry {
+	if label == "" {
+		return entries
+	}
+	filtered := make([]backupEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Label == label {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// s3BackupAnnotations returns the label and integrity key identifier stored in the user
+// metadata of an uploaded archive.
+func (iops *InfrahubOps) s3BackupAnnotations(ctx context.Context, client *s3.Client, key string) (string, string, error) {
+	output, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(iops.config.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return output.Metadata[s3LabelMetadataKey], output.Metadata[s3KeyIDMetadataKey], nil
+}
+
+// s3ObjectMetadata returns the user metadata of the archive at backupPath for its upload: the
+// label and integrity key identifier recorded in the archive, which also covers archives
+// uploaded later with upload.
+func s3ObjectMetadata(backupPath string) map[string]string {
+	metadata, err := readArchiveMetadata(backupPath)
+	if err != nil {
+		logrus.Debugf("Uploading %s without a label or key identifier: %v", backupPath, err)
+		return nil
+	}
+	objectMetadata := map[string]string{}
+	if metadata.Label != "" {
+		objectMetadata[s3LabelMetadataKey] = metadata.Label
+	}
+	if metadata.IntegrityKeyID != "" {
+		objectMetadata[s3KeyIDMetadataKey] = metadata.IntegrityKeyID
+	}
+	if len(objectMetadata) == 0 {
+		return nil
+	}
+	return objectMetadata
+}
@@ -0,0 +1,277 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BackupListOptions selects where to look for backups and how to filter them.
+type BackupListOptions struct {
+	Local  bool
+	S3     bool
+	Since  string
+	Before string
+	Latest bool
+	Label  string
+	Labels bool
+	Keys   bool
+}
+
+// ListBackups prints the backups found locally and/or in S3, newest first.
+func (iops *InfrahubOps) ListBackups(opts BackupListOptions) error {
+	now := time.Now()
+	var since, before time.Time
+	if opts.Since != "" {
+		parsed, err := parseTimeBound(opts.Since, now)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		since = parsed
+	}
+	if opts.Before != "" {
+		parsed, err := parseTimeBound(opts.Before, now)
+		if err != nil {
+			return fmt.Errorf("invalid --before: %w", err)
+		}
+		before = parsed
+	}
+
+	entries, err := iops.collectBackups(opts.Local, opts.S3)
+	if err != nil {
+		return err
+	}
+	entries = filterBackups(entries, since, before)
+	// Labels and keys cost a metadata read per archive, or a HEAD request per object in S3
+	showLabels := opts.Labels && !opts.Latest
+	showKeys := opts.Keys && !opts.Latest
+	if opts.Label != "" || showLabels || showKeys {
+		entries = iops.annotateBackups(entries, opts.Label)
+	}
+
+	if opts.Latest {
+		latest, ok := latestBackup(entries)
+		if !ok {
+			return fmt.Errorf("no backups found")
+		}
+		fmt.Println(iops.backupReference(latest))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		logrus.Info("No backups found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "LOCATION\tBACKUP\tCREATED\tSIZE")
+	if showLabels {
+		fmt.Fprint(w, "\tLABEL")
+	}
+	if showKeys {
+		fmt.Fprint(w, "\tKEY")
+	}
+	fmt.Fprintln(w)
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s", entry.Location, entry.Name, entry.CreatedAt.Format(time.RFC3339), formatBytes(entry.Size))
+		if showLabels {
+			fmt.Fprintf(w, "\t%s", valueOrDash(entry.Label))
+		}
+		if showKeys {
+			fmt.Fprintf(w, "\t%s", valueOrDash(entry.KeyID))
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// collectBackups gathers backups from the selected locations, sorted newest first.
+func (iops *InfrahubOps) collectBackups(includeLocal, includeS3 bool) ([]backupEntry, error) {
+	if !includeLocal && !includeS3 {
+		return nil, fmt.Errorf("no backup location selected: enable local and/or S3")
+	}
+
+	var entries []backupEntry
+	if includeLocal {
+		local, err := iops.listLocalBackups()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, local...)
+	}
+
+	if includeS3 {
+		if err := iops.validateS3Config(); err != nil {
+			return nil, err
+		}
+		ctx := context.Background()
+		client, err := iops.createS3Client(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 client: %w", err)
+		}
+		remote, err := iops.listS3Backups(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, remote...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// filterBackups keeps entries created at or after since and strictly before before.
+// Zero bounds are ignored.
+func filterBackups(entries []backupEntry, since, before time.Time) []backupEntry {
+	filtered := make([]backupEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !since.IsZero() && entry.CreatedAt.Before(since) {
+			continue
+		}
+		if !before.IsZero() && !entry.CreatedAt.Before(before) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// latestBackup returns the newest entry.
+func latestBackup(entries []backupEntry) (backupEntry, bool) {
+	var latest backupEntry
+	found := false
+	for _, entry := range entries {
+		if !found || entry.CreatedAt.After(latest.CreatedAt) {
+			latest = entry
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// latestCompleteBackup returns the newest entry that isn't a partial backup.
+func latestCompleteBackup(entries []backupEntry) (backupEntry, bool) {
+	complete := make([]backupEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !isPartialBackupName(entry.Name) {
+			complete = append(complete, entry)
+		}
+	}
+	return latestBackup(complete)
+}
+
+// selectLatestBackup returns the newest backup in the backup directory (or S3 bucket).
+// Partial backups are skipped, since they may lack a database, unless allowPartial is set.
+func (iops *InfrahubOps) selectLatestBackup(fromS3 bool, allowPartial bool) (backupEntry, error) {
+	entries, err := iops.collectBackups(!fromS3, fromS3)
+	if err != nil {
+		return backupEntry{}, err
+	}
+	source := iops.config.BackupDir
+	if fromS3 {
+		source = "s3://" + iops.config.S3Bucket + "/" + iops.config.S3Prefix
+	}
+	newest, ok := latestBackup(entries)
+	if !ok {
+		return backupEntry{}, fmt.Errorf("no backups found in %s", source)
+	}
+	if allowPartial {
+		return newest, nil
+	}
+	latest, ok := latestCompleteBackup(entries)
+	if !ok {
+		return backupEntry{}, fmt.Errorf("no complete backups found in %s; the newest backup %s is partial, pass --allow-partial to restore it", source, iops.backupReference(newest))
+	}
+	if latest.Name != newest.Name {
+		logrus.WithField("backup", iops.backupReference(newest)).Warn("Skipping newer partial backup; pass --allow-partial to restore it")
+	}
+	return latest, nil
+}
+
+// RestoreLatestBackup locates the newest backup in the backup directory (or S3 bucket)
+// and restores it. S3 archives are downloaded to a temporary directory first.
+func (iops *InfrahubOps) RestoreLatestBackup(fromS3 bool, allowPartial bool, excludeTaskManager bool, restoreMigrateFormat bool) error {
+	latest, err := iops.selectLatestBackup(fromS3, allowPartial)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"backup":     iops.backupReference(latest),
+		"created_at": latest.CreatedAt.Format(time.RFC3339),
+		"size":       formatBytes(latest.Size),
+	}).Info("Selected latest backup for restore")
+
+	if latest.Location != backupLocationS3 {
+		return iops.RestoreBackup(iops.backupReference(latest), excludeTaskManager, restoreMigrateFormat)
+	}
+	return iops.restoreS3Backup(latest.Name, excludeTaskManager, restoreMigrateFormat)
+}
+
+// restoreS3Backup downloads the archive stored under key to a temporary directory and restores it.
+func (iops *InfrahubOps) restoreS3Backup(key string, excludeTaskManager bool, restoreMigrateFormat bool) error {
+	downloadDir, err := os.MkdirTemp("", "infrahub_download_*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer iops.cleanupWorkDir(downloadDir)
+
+	localPath := filepath.Join(downloadDir, path.Base(key))
+	if err := iops.downloadBackupFromS3(key, localPath); err != nil {
+		return err
+	}
+	return iops.restore(localPath, fmt.Sprintf("s3://%s/%s", iops.config.S3Bucket, key), false, excludeTaskManager, restoreMigrateFormat)
+}
+
+// parseS3Reference splits an s3://bucket/key reference as printed by backup list.
+func parseS3Reference(reference string) (bucket, key string, ok bool) {
+	rest, found := strings.CutPrefix(reference, "s3://")
+	if !found {
+		return "", "", false
+	}
+	bucket, key, _ = strings.Cut(rest, "/")
+	return bucket, key, bucket != "" && key != ""
+}
+
+// backupReference returns a path for local backups and an s3:// URI for remote ones.
+func (iops *InfrahubOps) backupReference(entry backupEntry) string {
+	if entry.Location == backupLocationS3 {
+		return fmt.Sprintf("s3://%s/%s", iops.config.S3Bucket, entry.Name)
+	}
+	return filepath.Join(iops.config.BackupDir, entry.Name)
+}
+
+// parseTimeBound accepts an RFC3339 timestamp or a duration relative to now
+// (Go durations such as 36h, or a day count such as 7d).
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count < 0 {
+			return time.Time{}, fmt.Errorf("invalid day count %q", value)
+		}
+		return now.AddDate(0, 0, -count), nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC3339 timestamp or a duration like 7d or 36h, got %q", value)
+	}
+	if duration < 0 {
+		return time.Time{}, fmt.Errorf("duration must not be negative: %q", value)
+	}
+	return now.Add(-duration), nil
+}
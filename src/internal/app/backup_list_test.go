@@ -0,0 +1,131 @@
+package app
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+// writeMetadataArchive writes a plain tar archive holding only metadata.
+func writeMetadataArchive(t *testing.T, dir, name string, metadata BackupMetadata) {
+	t.Helper()
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	tw := tar.NewWriter(file)
+	header := &tar.Header{Name: "backup/" + backupMetadataFilename, Mode: 0644, Size: int64(len(content))}
+	if err := tw.WriteHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// captureStdout returns what fn prints to the standard output.
+func captureStdout(t *testing.T, fn func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		output, _ := io.ReadAll(r)
+		done <- string(output)
+	}()
+	fnErr := fn()
+	w.Close()
+	output := <-done
+	if fnErr != nil {
+		t.Fatalf("unexpected error: %v", fnErr)
+	}
+	return output
+}
+
+func TestListBackupsColumns(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	writeMetadataArchive(t, iops.config.BackupDir, "infrahub_backup_20250601T020000Z.tar", BackupMetadata{Label: "pre-upgrade"})
+	writeMetadataArchive(t, iops.config.BackupDir, "infrahub_backup_20250602T020000Z.tar", BackupMetadata{IntegrityKeyID: "sha256:0123abcd"})
+
+	output := captureStdout(t, func() error { return iops.ListBackups(BackupListOptions{Local: true}) })
+	if strings.Contains(output, "LABEL") || strings.Contains(output, "pre-upgrade") || strings.Contains(output, "KEY") {
+		t.Errorf("list without --labels = %q, want no label or key column", output)
+	}
+	if got := strings.Count(output, "infrahub_backup_"); got != 2 {
+		t.Errorf("list without --labels shows %d backups, want 2:\n%s", got, output)
+	}
+
+	output = captureStdout(t, func() error { return iops.ListBackups(BackupListOptions{Local: true, Labels: true}) })
+	if !strings.Contains(output, "LABEL") || !strings.Contains(output, "pre-upgrade") || strings.Contains(output, "KEY") {
+		t.Errorf("list --labels = %q, want only the label column", output)
+	}
+
+	output = captureStdout(t, func() error { return iops.ListBackups(BackupListOptions{Local: true, Keys: true}) })
+	if !strings.Contains(output, "KEY") || !strings.Contains(output, "sha256:0123abcd") || strings.Contains(output, "LABEL") {
+		t.Errorf("list --keys = %q, want only the key column", output)
+	}
+
+	output = captureStdout(t, func() error { return iops.ListBackups(BackupListOptions{Local: true, Label: "pre-upgrade"}) })
+	if !strings.Contains(output, "20250601T020000Z") || strings.Contains(output, "20250602T020000Z") {
+		t.Errorf("list --label pre-upgrade = %q, want only the labeled backup", output)
+	}
+}
+
+func TestS3ObjectMetadata(t *testing.T) {
+	dir := t.TempDir()
+	writeMetadataArchive(t, dir, "signed.tar", BackupMetadata{Label: "nightly", IntegrityKeyID: "sha256:0123abcd"})
+	writeMetadataArchive(t, dir, "plain.tar", BackupMetadata{})
+
+	got := s3ObjectMetadata(filepath.Join(dir, "signed.tar"))
+	if got[s3LabelMetadataKey] != "nightly" || got[s3KeyIDMetadataKey] != "sha256:0123abcd" {
+		t.Errorf("object metadata = %v, want the label and the key identifier", got)
+	}
+	if got := s3ObjectMetadata(filepath.Join(dir, "plain.tar")); got != nil {
+		t.Errorf("object metadata of an unlabeled, unsigned archive = %v, want none", got)
+	}
+}
+
+func TestSelectLatestBackupSkipsPartial(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	dir := iops.config.BackupDir
+	writeMetadataArchive(t, dir, "infrahub_backup_20250603T020000Z_partial.tar", BackupMetadata{Partial: true})
+
+	if _, err := iops.selectLatestBackup(false, false); err == nil || !strings.Contains(err.Error(), "--allow-partial") {
+		t.Errorf("only partial backups = %v, want an error naming --allow-partial", err)
+	}
+	latest, err := iops.selectLatestBackup(false, true)
+	if err != nil || latest.Name != "infrahub_backup_20250603T020000Z_partial.tar" {
+		t.Errorf("--allow-partial with only partial backups = %q, %v, want the partial backup", latest.Name, err)
+	}
+
+	writeMetadataArchive(t, dir, "infrahub_backup_20250601T020000Z.tar", BackupMetadata{})
+	writeMetadataArchive(t, dir, "infrahub_backup_20250602T020000Z.tar", BackupMetadata{})
+	latest, err = iops.selectLatestBackup(false, false)
+	if err != nil || latest.Name != "infrahub_backup_20250602T020000Z.tar" {
+		t.Errorf("latest = %q, %v, want the newest complete backup", latest.Name, err)
+	}
+	latest, err = iops.selectLatestBackup(false, true)
+	if err != nil || latest.Name != "infrahub_backup_20250603T020000Z_partial.tar" {
+		t.Errorf("latest with --allow-partial = %q, %v, want the newer partial backup", latest.Name, err)
+	}
+}
@@ -1,10 +1,16 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,16 +21,38 @@ const (
 	neo4jEditionCommunity  = "community"
 )
 
+// ErrEditionMismatch is returned when a backup cannot be restored into the detected Neo4j edition,
+// or when the edition is not one the tool knows how to back up or restore.
+var ErrEditionMismatch = errors.New("neo4j edition mismatch")
+
 // BackupMetadata represents the backup metadata structure
 type BackupMetadata struct {
-	MetadataVersion int               `json:"metadata_version"`
-	BackupID        string            `json:"backup_id"`
-	CreatedAt       string            `json:"created_at"`
-	ToolVersion     string            `json:"tool_version"`
-	InfrahubVersion string            `json:"infrahub_version"`
-	Components      []string          `json:"components"`
-	Checksums       map[string]string `json:"checksums,omitempty"`
-	Neo4jEdition    string            `json:"neo4j_edition,omitempty"`
+	MetadataVersion       int               `json:"metadata_version"`
+	BackupID              string            `json:"backup_id"`
+	CreatedAt             string            `json:"created_at"`
+	ToolVersion           string            `json:"tool_version"`
+	InfrahubVersion       string            `json:"infrahub_version"`
+	Components            []string          `json:"components"`
+	Checksums             map[string]string `json:"checksums,omitempty"`
+	Neo4jEdition          string            `json:"neo4j_edition,omitempty"`
+	DumpSizes             map[string]int64  `json:"dump_sizes,omitempty"`
+	ComponentSizes        map[string]int64  `json:"component_sizes,omitempty"` // bytes of each component before archiving
+	ExcludePatterns       []string          `json:"exclude_patterns,omitempty"`
+	PgExcludedTables      []string          `json:"pg_excluded_tables,omitempty"`      // --pg-exclude-table: left out of the task manager dumps
+	PgExcludedTableData   []string          `json:"pg_excluded_table_data,omitempty"`  // --pg-exclude-table-data: dumped without rows
+	Neo4jBackupCompressed *bool             `json:"neo4j_backup_compressed,omitempty"` // Enterprise only
+	Neo4jMetadata         string            `json:"neo4j_metadata,omitempty"`          // --neo4j-metadata used for an Enterprise backup
+	Neo4jBackupMode       string            `json:"neo4j_backup_mode,omitempty"`       // Enterprise only: online, or offline with --neo4j-offline-enterprise
+	IntegrityKeyID        string            `json:"integrity_key_id,omitempty"`        // identifier of the key that signed the metadata
+	ArchiveCompression    string            `json:"archive_compression,omitempty"`     // codec of the archive: gzip, or none for a plain tar
+	ArchiveOwner          string            `json:"archive_owner,omitempty"`           // UID:GID stored for every entry by --archive-owner
+	ArchivePermissions    string            `json:"archive_permissions,omitempty"`     // file mode stored for every file by --archive-permissions
+	SourceEnvironment     string            `json:"source_environment,omitempty"`      // backend and project or namespace the backup was taken from
+	Label                 string            `json:"label,omitempty"`                   // free-form --label, such as pre-upgrade
+	TaskManagerAbsence    string            `json:"task_manager_absence,omitempty"`    // why the task manager database was left out without --exclude-taskmanager
+	GraphStats            *GraphStats       `json:"graph_stats,omitempty"`             // Neo4j node and relationship counts, unless --no-graph-stats
+	Partial               bool              `json:"partial,omitempty"`                 // --best-effort backup that misses FailedComponents
+	FailedComponents      map[string]string `json:"failed_components,omitempty"`       // component -> error of a --best-effort backup
 }
 
 // Neo4jEditionInfo encapsulates information about the detected Neo4j edition
@@ -54,7 +82,9 @@ func NewNeo4jEditionInfo(edition string, err error) *Neo4jEditionInfo {
 
 // LogDetection logs the detection result
 func (info *Neo4jEditionInfo) LogDetection(context string) {
-	if !info.IsDetected {
+	if info.Edition == neo4jEditionExternal {
+		logrus.Infof("Using external Neo4j (logical export) for %s", context)
+	} else if !info.IsDetected {
 		logrus.Warnf("Could not determine Neo4j edition during %s; defaulting to community workflow", context)
 	} else {
 		logrus.Infof("Detected Neo4j %s edition for %s", info.Edition, context)
@@ -64,6 +94,20 @@ func (info *Neo4jEditionInfo) LogDetection(context string) {
 // ResolveRestoreEdition determines the correct edition to use for restore
 func (info *Neo4jEditionInfo) ResolveRestoreEdition(backupEdition string) (string, error) {
 	backupNormalized := strings.ToLower(backupEdition)
+	if backupNormalized != "" && checkNeo4jEdition(backupNormalized) != nil {
+		return "", fmt.Errorf("%w: backup metadata records an unrecognized Neo4j edition %q", ErrEditionMismatch, backupEdition)
+	}
+	if err := checkNeo4jEdition(info.Edition); err != nil {
+		return "", err
+	}
+
+	// Logical exports and neo4j-admin backups are not interchangeable
+	if backupNormalized == neo4jEditionExternal && info.Edition != neo4jEditionExternal {
+		return "", fmt.Errorf("%w: backup is a logical export of an external Neo4j; restore it with --neo4j-host", ErrEditionMismatch)
+	}
+	if info.Edition == neo4jEditionExternal && backupNormalized != neo4jEditionExternal && backupNormalized != "" {
+		return "", fmt.Errorf("%w: cannot restore a %s neo4j-admin backup into an external Neo4j", ErrEditionMismatch, backupNormalized)
+	}
 
 	// If backup is community and detected is enterprise, always use community method
 	if backupNormalized == neo4jEditionCommunity && info.Edition == neo4jEditionEnterprise {
@@ -73,13 +117,24 @@ func (info *Neo4jEditionInfo) ResolveRestoreEdition(backupEdition string) (strin
 
 	// Cannot restore Enterprise backup on Community edition
 	if backupNormalized == neo4jEditionEnterprise && info.Edition == neo4jEditionCommunity {
-		return "", fmt.Errorf("cannot restore Enterprise backup on Community edition Neo4j")
+		return "", fmt.Errorf("%w: cannot restore Enterprise backup on Community edition Neo4j", ErrEditionMismatch)
 	}
 
 	// Use detected edition
 	return info.Edition, nil
 }
 
+// checkNeo4jEdition rejects an edition that is not community, enterprise or external, so that
+// an unexpected dbms.components() answer never selects the Enterprise procedures by default.
+func checkNeo4jEdition(edition string) error {
+	switch strings.ToLower(edition) {
+	case neo4jEditionCommunity, neo4jEditionEnterprise, neo4jEditionExternal:
+		return nil
+	default:
+		return fmt.Errorf("%w: unrecognized Neo4j edition %q; set --neo4j-edition to community or enterprise", ErrEditionMismatch, edition)
+	}
+}
+
 // detectNeo4jEditionInfo detects the Neo4j edition and returns structured information
 func (iops *InfrahubOps) detectNeo4jEditionInfo(context string) *Neo4jEditionInfo {
 	edition, err := iops.detectNeo4jEdition()
@@ -88,9 +143,34 @@ func (iops *InfrahubOps) detectNeo4jEditionInfo(context string) *Neo4jEditionInf
 	return info
 }
 
+// normalizeNeo4jEdition validates a --neo4j-edition value; empty keeps auto-detection.
+func normalizeNeo4jEdition(value string) (string, error) {
+	switch edition := strings.ToLower(strings.TrimSpace(value)); edition {
+	case "", neo4jEditionCommunity, neo4jEditionEnterprise:
+		return edition, nil
+	default:
+		return "", fmt.Errorf("invalid --neo4j-edition %q: must be %s or %s", value, neo4jEditionCommunity, neo4jEditionEnterprise)
+	}
+}
+
 func (iops *InfrahubOps) detectNeo4jEdition() (string, error) {
+	if iops.isExternalNeo4j() {
+		if iops.config.Neo4jEdition != "" {
+			logrus.Warnf("Ignoring --neo4j-edition %s for an external Neo4j", iops.config.Neo4jEdition)
+		}
+		return neo4jEditionExternal, nil
+	}
+	override, err := normalizeNeo4jEdition(iops.config.Neo4jEdition)
+	if err != nil {
+		return "", err
+	}
+	if override != "" {
+		logrus.WithField("edition", override).Warn("Neo4j edition detection overridden by --neo4j-edition")
+		return override, nil
+	}
+
 	output, err := iops.Exec("database", []string{
-		"cypher-shell",
+		iops.config.tool(cypherShellTool),
 		"-u", iops.config.Neo4jUsername,
 		"-p" + iops.config.Neo4jPassword,
 		"-d", "system",
@@ -109,6 +189,26 @@ func (iops *InfrahubOps) detectNeo4jEdition() (string, error) {
 	return edition, nil
 }
 
+// detectNeo4jVersion returns the version of the running Neo4j, or "unknown".
+func (iops *InfrahubOps) detectNeo4jVersion() string {
+	if iops.isExternalNeo4j() {
+		return "unknown"
+	}
+	output, err := iops.Exec("database", []string{
+		iops.config.tool(cypherShellTool),
+		"-u", iops.config.Neo4jUsername,
+		"-p" + iops.config.Neo4jPassword,
+		"-d", "system",
+		"--format", "plain",
+		"CALL dbms.components() YIELD versions RETURN versions[0]",
+	}, nil)
+	if version := extractNeo4jEdition(output); err == nil && version != "" {
+		return version
+	}
+	logrus.Debugf("Failed to query neo4j version: %v", err)
+	return "unknown"
+}
+
 func extractNeo4jEdition(output string) string {
 	lines := strings.Split(output, "\n")
 	for i := len(lines) - 1; i >= 0; i-- {
@@ -121,23 +221,159 @@ func extractNeo4jEdition(output string) string {
 }
 
 func (iops *InfrahubOps) generateBackupFilename() string {
-	timestamp := time.Now().Format("20060102_150405")
-	return fmt.Sprintf("infrahub_backup_%s.tar.gz", timestamp)
+	return backupFilenamePrefix + iops.formatBackupTimestamp(iops.backupTime()) + iops.archiveSuffix()
 }
 
-func (iops *InfrahubOps) createBackupMetadata(backupID string, includeTaskManager bool, infrahubVersion string, neo4jEdition string) *BackupMetadata {
-	components := []string{"database"}
+// archiveSuffix returns the extension of the archives written with the configured --compression.
+func (iops *InfrahubOps) archiveSuffix() string {
+	if iops.config.Compression == archiveCompressionNone {
+		return backupFilenameTarSuffix
+	}
+	return backupFilenameSuffix
+}
+
+// backupTime returns the current time in UTC, or in the local time zone with --local-time.
+func (iops *InfrahubOps) backupTime() time.Time {
+	if iops.config.LocalTime {
+		return time.Now()
+	}
+	return time.Now().UTC()
+}
+
+// formatBackupTimestamp formats t for a backup or dump name.
+func (iops *InfrahubOps) formatBackupTimestamp(t time.Time) string {
+	if iops.config.LocalTime {
+		return t.Format(backupTimestampFmt)
+	}
+	return t.Format(backupTimestampUTCFmt)
+}
+
+// archiveDir is where the archive of a new backup is written: --output-dir for a one-off
+// export, otherwise the managed backup directory.
+func (iops *InfrahubOps) archiveDir() string {
+	if iops.config.OutputDir != "" {
+		return iops.config.OutputDir
+	}
+	return iops.config.BackupDir
+}
+
+// checkOutputDir creates --output-dir if needed and checks that an archive can be written to it,
+// before any service is stopped. It rejects --s3-upload, since the bucket prefix is managed like
+// the backup directory.
+func (iops *InfrahubOps) checkOutputDir() error {
+	dir := iops.config.OutputDir
+	if dir == "" {
+		return nil
+	}
+	outputIsBackupDir := false
+	if managed, err := filepath.Abs(iops.config.BackupDir); err == nil {
+		if output, err := filepath.Abs(dir); err == nil && output == managed {
+			outputIsBackupDir = true
+		}
+	}
+	if iops.config.S3Upload && !outputIsBackupDir {
+		return fmt.Errorf("%w: --output-dir cannot be combined with --s3-upload: objects under --s3-prefix %q are listed and pruned with the managed backups; run upload with another --s3-prefix to store the export in the bucket", ErrPrerequisites, iops.config.S3Prefix)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%w: failed to create output directory %s: %w", ErrPrerequisites, dir, err)
+	}
+	probe, err := os.CreateTemp(dir, ".infrahub_write_check_*")
+	if err != nil {
+		return fmt.Errorf("%w: output directory %s is not writable: %w", ErrPrerequisites, dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if outputIsBackupDir {
+		logrus.Warn("--output-dir is the backup directory; the archive will be part of the managed backup set")
+	}
+	return nil
+}
+
+// maxBackupNameSuffix bounds the search for a free backup filename.
+const maxBackupNameSuffix = 99
+
+// resolveBackupFilename returns a backup filename that does not clash with an existing local
+// backup (or S3 object, when uploading). A clash gets a numeric suffix, or fails with --no-overwrite.
+func (iops *InfrahubOps) resolveBackupFilename() (string, error) {
+	base := iops.generateBackupFilename()
+	exists := iops.backupNameChecker()
+
+	if !exists(base) {
+		return base, nil
+	}
+	if iops.config.NoOverwrite {
+		return "", fmt.Errorf("backup %s already exists and --no-overwrite is set", base)
+	}
+
+	suffix := backupArchiveSuffix(base)
+	stem := strings.TrimSuffix(base, suffix)
+	for i := 1; i <= maxBackupNameSuffix; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", stem, i, suffix)
+		if !exists(candidate) {
+			logrus.Warnf("Backup %s already exists; writing %s instead", base, candidate)
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a free backup filename for %s", base)
+}
+
+// backupNameChecker returns a function reporting whether a backup name is already taken locally
+// or, when uploading, in S3. S3 lookup failures are logged and treated as "not taken".
+func (iops *InfrahubOps) backupNameChecker() func(string) bool {
+	var s3Client *s3.Client
+	ctx := context.Background()
+	if iops.config.S3Upload {
+		if err := iops.validateS3Config(); err == nil {
+			if client, err := iops.createS3Client(ctx); err == nil {
+				s3Client = client
+			} else {
+				logrus.Warnf("Cannot check S3 for an existing backup with the same name: %v", err)
+			}
+		}
+	}
+
+	return func(name string) bool {
+		if fileExists(filepath.Join(iops.archiveDir(), name)) {
+			return true
+		}
+		if s3Client == nil {
+			return false
+		}
+		exists, err := iops.s3BackupExists(ctx, s3Client, iops.s3Key(name))
+		if err != nil {
+			logrus.Warnf("Cannot check S3 for an existing backup named %s: %v", name, err)
+		}
+		return exists
+	}
+}
+
+func (iops *InfrahubOps) createBackupMetadata(backupID string, includeNeo4j, includeTaskManager bool, infrahubVersion string, neo4jEdition string) *BackupMetadata {
+	var components []string
+	if includeNeo4j {
+		components = append(components, "database")
+	}
 	if includeTaskManager {
 		components = append(components, "task-manager-db")
 	}
 
-	return &BackupMetadata{
+	metadata := &BackupMetadata{
 		MetadataVersion: metadataVersion,
 		BackupID:        backupID,
-		CreatedAt:       time.Now().UTC().Format(time.RFC3339),
+		CreatedAt:       iops.backupTime().Format(time.RFC3339),
 		ToolVersion:     BuildRevision(),
 		InfrahubVersion: infrahubVersion,
 		Components:      components,
 		Neo4jEdition:    strings.ToLower(neo4jEdition),
 	}
+	metadata.Label = iops.config.Label
+	if iops.config.Compression != "" {
+		metadata.ArchiveCompression = iops.config.Compression
+	}
+	metadata.ArchiveOwner = iops.config.ArchiveOwner
+	metadata.ArchivePermissions = iops.config.ArchivePermissions
+	if backend, err := iops.ensureBackend(); err == nil {
+		metadata.SourceEnvironment = backend.Name() + " " + backend.Info()
+	}
+	return metadata
 }
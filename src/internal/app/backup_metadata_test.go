@@ -0,0 +1,121 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestDetectNeo4jEditionInfo(t *testing.T) {
+	tests := []struct {
+		name          string
+		override      string
+		response      apptest.Response
+		wantEdition   string
+		wantCommunity bool
+		wantDetected  bool
+		wantQuery     bool
+	}{
+		{name: "community", response: apptest.Response{Output: "edition\n\"community\"\n"}, wantEdition: "community", wantCommunity: true, wantDetected: true, wantQuery: true},
+		{name: "enterprise", response: apptest.Response{Output: "edition\n\"enterprise\"\n"}, wantEdition: "enterprise", wantDetected: true, wantQuery: true},
+		{name: "query fails", response: apptest.Response{Err: errors.New("connection refused")}, wantEdition: "community", wantCommunity: true, wantQuery: true},
+		{name: "empty output", response: apptest.Response{Output: "\n"}, wantEdition: "community", wantCommunity: true, wantQuery: true},
+		{name: "override", override: "Enterprise", wantEdition: "enterprise", wantDetected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := apptest.NewFakeBackend("database")
+			fake.On("database", []string{cypherShellTool}, tt.response)
+			iops := newTestOps(t, fake)
+			iops.config.Neo4jEdition = tt.override
+
+			info := iops.detectNeo4jEditionInfo("backup")
+			if info.Edition != tt.wantEdition || info.IsCommunity != tt.wantCommunity || info.IsDetected != tt.wantDetected {
+				t.Errorf("got %+v, want edition %s, community %t, detected %t", *info, tt.wantEdition, tt.wantCommunity, tt.wantDetected)
+			}
+			if queried := len(fake.CallsTo("Exec")) > 0; queried != tt.wantQuery {
+				t.Errorf("queried Neo4j = %t, want %t", queried, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestResolveRestoreEdition(t *testing.T) {
+	tests := []struct {
+		detected string
+		backup   string
+		want     string
+		wantErr  bool
+	}{
+		{detected: "community", backup: "community", want: "community"},
+		{detected: "enterprise", backup: "enterprise", want: "enterprise"},
+		{detected: "enterprise", backup: "community", want: "community"},
+		{detected: "community", backup: "enterprise", wantErr: true},
+		{detected: "external", backup: "external", want: "external"},
+		{detected: "community", backup: "external", wantErr: true},
+		{detected: "external", backup: "enterprise", wantErr: true},
+		{detected: "enterprise", backup: "", want: "enterprise"},
+		{detected: "aura", backup: "enterprise", wantErr: true},
+		{detected: "enterprise", backup: "aura", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.detected+"/"+tt.backup, func(t *testing.T) {
+			info := NewNeo4jEditionInfo(tt.detected, nil)
+			got, err := info.ResolveRestoreEdition(tt.backup)
+			if tt.wantErr {
+				if !errors.Is(err, ErrEditionMismatch) {
+					t.Fatalf("err = %v, want ErrEditionMismatch", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckOutputDirRejectsUpload(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	iops.config.S3Upload = true
+	iops.config.OutputDir = filepath.Join(t.TempDir(), "export")
+	if err := iops.checkOutputDir(); !errors.Is(err, ErrPrerequisites) {
+		t.Fatalf("checkOutputDir with --s3-upload = %v, want ErrPrerequisites", err)
+	}
+
+	iops.config.S3Upload = false
+	if err := iops.checkOutputDir(); err != nil {
+		t.Fatalf("checkOutputDir: %v", err)
+	}
+	if info, err := os.Stat(iops.config.OutputDir); err != nil || !info.IsDir() {
+		t.Errorf("output directory %s was not created", iops.config.OutputDir)
+	}
+
+	iops.config.S3Upload = true
+	iops.config.OutputDir = iops.config.BackupDir
+	if err := iops.checkOutputDir(); err != nil {
+		t.Fatalf("checkOutputDir: %v", err)
+	}
+	if !iops.config.S3Upload {
+		t.Error("--output-dir set to the backup directory disabled the upload")
+	}
+}
+
+func TestUnknownNeo4jEditionFails(t *testing.T) {
+	fake := apptest.NewFakeBackend("database")
+	iops := newTestOps(t, fake)
+	dir := t.TempDir()
+
+	if err := iops.backupDatabase(dir, "all", "aura"); !errors.Is(err, ErrEditionMismatch) {
+		t.Errorf("backupDatabase with an unknown edition = %v, want ErrEditionMismatch", err)
+	}
+	if err := iops.restoreNeo4j(dir, dir, "aura", "", false, false, false); !errors.Is(err, ErrEditionMismatch) {
+		t.Errorf("restoreNeo4j with an unknown edition = %v, want ErrEditionMismatch", err)
+	}
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Errorf("backend calls = %v, want none for an unknown edition", calls)
+	}
+}
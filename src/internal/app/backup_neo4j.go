@@ -1,9 +1,11 @@
 package app
 
 import (
+	"compress/gzip"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,34 +17,64 @@ const (
 	neo4jTempBackupDir       = "/tmp/infrahubops"
 	neo4jWatchdogInitTimeout = 5 * time.Second
 	neo4jProcessStopTimeout  = 120 * time.Second
-	neo4jMetadataScriptPath  = "/data/scripts/neo4j/restore_metadata.cypher"
+	// neo4jMetadataScriptName is written by neo4j-admin database restore to
+	// <data directory>/scripts/<database>/ from the metadata captured with --include-metadata.
+	neo4jMetadataScriptName = "restore_metadata.cypher"
 )
 
+// Enterprise backup modes recorded in the metadata.
+const (
+	neo4jBackupModeOnline  = "online"
+	neo4jBackupModeOffline = "offline"
+)
+
+// neo4jMetadataValues are the --include-metadata values of neo4j-admin database backup.
+var neo4jMetadataValues = []string{"all", "none", "users", "roles"}
+
+// normalizeNeo4jMetadata validates a --neo4j-metadata value. true and false are accepted as
+// all and none; an empty value selects the default, all.
+func normalizeNeo4jMetadata(value string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	switch normalized {
+	case "", "true":
+		return "all", nil
+	case "false":
+		return "none", nil
+	}
+	if !slices.Contains(neo4jMetadataValues, normalized) {
+		return "", fmt.Errorf("invalid --neo4j-metadata %q: must be one of %s, true or false", value, strings.Join(neo4jMetadataValues, ", "))
+	}
+	return normalized, nil
+}
+
 func (iops *InfrahubOps) backupDatabase(backupDir string, backupMetadata string, neo4jEdition string) error {
 	edition := strings.ToLower(neo4jEdition)
 	switch edition {
+	case neo4jEditionExternal:
+		return iops.backupNeo4jExternal(backupDir, backupMetadata)
 	case neo4jEditionCommunity:
 		return iops.backupNeo4jCommunity(backupDir)
-	default:
+	case neo4jEditionEnterprise:
 		return iops.backupNeo4jEnterprise(backupDir, backupMetadata)
+	default:
+		return checkNeo4jEdition(neo4jEdition)
 	}
 }
 
 func (iops *InfrahubOps) backupNeo4jEnterprise(backupDir string, backupMetadata string) error {
+	if iops.config.Neo4jOfflineEnterprise {
+		return iops.backupNeo4jEnterpriseOffline(backupDir)
+	}
 	logrus.Info("Backing up Neo4j database (Enterprise Edition online backup)...")
 
 	if _, err := iops.Exec("database", []string{"mkdir", "-p", neo4jTempBackupDir}, nil); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
-	defer func() {
-		if _, err := iops.Exec("database", []string{"rm", "-rf", neo4jTempBackupDir}, nil); err != nil {
-			logrus.Warnf("Failed to remove temporary Neo4j backup directory: %v", err)
-		}
-	}()
+	defer iops.removeRemotePaths("database", neo4jTempBackupDir)
 
 	if output, err := iops.Exec(
 		"database",
-		[]string{"neo4j-admin", "database", "backup", "--expand-commands", "--include-metadata=" + backupMetadata, "--to-path=" + neo4jTempBackupDir, iops.config.Neo4jDatabase},
+		[]string{iops.config.tool(neo4jAdminTool), "database", "backup", "--expand-commands", "--include-metadata=" + backupMetadata, "--compress=" + strconv.FormatBool(iops.config.Neo4jBackupCompress), "--to-path=" + neo4jTempBackupDir, iops.config.Neo4jDatabase},
 		nil,
 	); err != nil {
 		return fmt.Errorf("failed to backup neo4j: %w\nOutput: %v", err, output)
@@ -56,6 +88,90 @@ func (iops *InfrahubOps) backupNeo4jEnterprise(backupDir string, backupMetadata
 	return nil
 }
 
+// backupNeo4jEnterpriseOffline stops the Infrahub database, dumps it with neo4j-admin database
+// dump and starts it again (--neo4j-offline-enterprise). The dump is taken with no transaction
+// in flight, at the cost of the database being unavailable while it is written. Only the
+// database is stopped; the Neo4j server and its other databases keep running.
+func (iops *InfrahubOps) backupNeo4jEnterpriseOffline(backupDir string) (retErr error) {
+	logrus.Info("Backing up Neo4j database (Enterprise Edition offline dump)...")
+
+	if _, err := iops.Exec("database", []string{"mkdir", "-p", neo4jTempBackupDir}, nil); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	defer iops.removeRemotePaths("database", neo4jTempBackupDir)
+
+	if err := iops.stopNeo4jDatabase(); err != nil {
+		return err
+	}
+	defer func() {
+		if err := iops.startNeo4jDatabase(); err != nil {
+			logrus.Errorf("Failed to start neo4j database %s after the dump; start it with: START DATABASE %s", iops.config.Neo4jDatabase, iops.config.Neo4jDatabase)
+			if retErr == nil {
+				retErr = err
+			}
+		}
+	}()
+
+	if output, err := iops.Exec(
+		"database",
+		[]string{iops.config.tool(neo4jAdminTool), "database", "dump", "--expand-commands", "--overwrite-destination=true", "--to-path=" + neo4jTempBackupDir, iops.config.Neo4jDatabase},
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to dump neo4j database: %w\nOutput: %v", err, output)
+	}
+
+	databaseDir := filepath.Join(backupDir, "database")
+	if err := os.MkdirAll(databaseDir, 0755); err != nil {
+		return fmt.Errorf("failed to prepare local dump directory: %w", err)
+	}
+	dumpFilename := iops.config.Neo4jDatabase + ".dump"
+	if err := iops.CopyFrom("database", neo4jTempBackupDir+"/"+dumpFilename, filepath.Join(databaseDir, dumpFilename)); err != nil {
+		return fmt.Errorf("failed to copy neo4j dump: %w", err)
+	}
+
+	logrus.Info("Neo4j dump completed")
+	return nil
+}
+
+// stopNeo4jDatabase stops the Infrahub database on an Enterprise server, leaving the server running.
+func (iops *InfrahubOps) stopNeo4jDatabase() error {
+	if _, err := iops.Exec(
+		"database",
+		[]string{iops.config.tool(cypherShellTool), "-u", iops.config.Neo4jUsername, "-p" + iops.config.Neo4jPassword, "-d", "system", "stop database " + iops.config.Neo4jDatabase},
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to stop neo4j database: %w", err)
+	}
+	return nil
+}
+
+// startNeo4jDatabase starts the Infrahub database stopped by stopNeo4jDatabase.
+func (iops *InfrahubOps) startNeo4jDatabase() error {
+	if _, err := iops.Exec(
+		"database",
+		[]string{iops.config.tool(cypherShellTool), "-u", iops.config.Neo4jUsername, "-p" + iops.config.Neo4jPassword, "-d", "system", "start database " + iops.config.Neo4jDatabase},
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to start neo4j database: %w", err)
+	}
+	return nil
+}
+
+// archiveCompressionLevel lowers the archive gzip level when the Neo4j Enterprise backup is
+// already compressed: gzip cannot skip members of a single stream, so the fastest level avoids
+// spending CPU on data that will not shrink further.
+func (iops *InfrahubOps) archiveCompressionLevel(neo4jEdition string) int {
+	if iops.config.Neo4jBackupCompress && isNeo4jEnterpriseEdition(neo4jEdition) {
+		return gzip.BestSpeed
+	}
+	return gzip.DefaultCompression
+}
+
+// isNeo4jEnterpriseEdition reports whether the backup uses neo4j-admin database backup.
+func isNeo4jEnterpriseEdition(neo4jEdition string) bool {
+	return strings.EqualFold(neo4jEdition, neo4jEditionEnterprise)
+}
+
 func (iops *InfrahubOps) backupNeo4jCommunity(backupDir string) (retErr error) {
 	logrus.Info("Backing up Neo4j database (Community Edition offline dump)...")
 
@@ -64,21 +180,29 @@ func (iops *InfrahubOps) backupNeo4jCommunity(backupDir string) (retErr error) {
 		return err
 	}
 
-	err = iops.stopNeo4jCommunity(pidStr)
+	// Keep the watchdog log next to the backup staging directory so it survives a failure.
+	diagDir := filepath.Dir(backupDir)
+
+	stopHeartbeat, err := iops.stopNeo4jCommunity(pidStr)
 	if err != nil {
+		iops.collectWatchdogLog(diagDir)
+		iops.collectNeo4jLogs(diagDir, true)
 		return err
 	}
 
 	defer func() {
-		if _, err := iops.Exec("database", []string{"rm", "-f", neo4jRemoteWatchdogBinary, neo4jRemoteWatchdogReady, neo4jRemoteWatchdogLog}, nil); err != nil {
-			logrus.Debugf("Failed to remove watchdog artifacts: %v", err)
+		stopHeartbeat()
+		if retErr != nil {
+			iops.collectWatchdogLog(diagDir)
 		}
+		iops.removeRemotePaths("database", neo4jRemoteWatchdogBinary, neo4jRemoteWatchdogReady, neo4jRemoteWatchdogLog, neo4jRemoteWatchdogHeartbeat)
 		if _, err := iops.Exec("database", []string{"kill", "-CONT", pidStr}, nil); err != nil {
 			logrus.Errorf("Failed to send SIGCONT to neo4j (pid %s): %v", pidStr, err)
 			if retErr == nil {
 				retErr = fmt.Errorf("failed to resume neo4j process: %w", err)
 			}
 		}
+		iops.collectNeo4jLogs(diagDir, retErr != nil)
 	}()
 
 	if _, err := iops.Exec("database", []string{"mkdir", "-p", neo4jRemoteWorkDir}, nil); err != nil {
@@ -91,7 +215,7 @@ func (iops *InfrahubOps) backupNeo4jCommunity(backupDir string) (retErr error) {
 	}
 
 	dumpCmd := []string{
-		"neo4j-admin", "database", "dump",
+		iops.config.tool(neo4jAdminTool), "database", "dump",
 		"--overwrite-destination=true",
 		"--to-path=" + neo4jRemoteWorkDir,
 		iops.config.Neo4jDatabase,
@@ -105,62 +229,81 @@ func (iops *InfrahubOps) backupNeo4jCommunity(backupDir string) (retErr error) {
 		return fmt.Errorf("failed to copy neo4j dump: %w", err)
 	}
 
-	logrus.Info("Neo4j dump completed")
-	return nil
-}
-
-func (iops *InfrahubOps) stopNeo4jCommunity(pidStr string) error {
-	if _, err := iops.Exec("database", []string{"mkdir", "-p", neo4jRemoteWorkDir}, nil); err != nil {
-		return fmt.Errorf("failed to prepare remote work directory: %w", err)
-	}
-
-	arch, err := iops.detectNeo4jArchitecture()
-	if err != nil {
+	if iops.config.ExcludeNeo4jAuth {
+		logrus.Info("Skipping Neo4j users backup as requested")
+	} else if err := iops.backupNeo4jAuth(backupDir); err != nil {
 		return err
 	}
 
-	watchdogBytes, err := selectWatchdogBinary(arch)
-	if err != nil {
-		return err
-	}
+	logrus.Info("Neo4j dump completed")
+	return nil
+}
 
-	localWatchdog, cleanup, err := writeEmbeddedWatchdog(watchdogBytes)
+// stopNeo4jCommunity starts the watchdog, stops Neo4j and waits for it to be suspended.
+// The returned function stops the watchdog heartbeat and must be called once the caller
+// is done with the suspended process. On error the heartbeat is stopped but its file is
+// left in place so the watchdog resumes Neo4j once it goes stale.
+func (iops *InfrahubOps) stopNeo4jCommunity(pidStr string) (func(), error) {
+	mode, err := resolveWatchdogMode(iops.config.WatchdogMode)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer cleanup()
 
-	if err := iops.CopyTo("database", localWatchdog, neo4jRemoteWatchdogBinary); err != nil {
-		return fmt.Errorf("failed to deploy watchdog binary: %w", err)
-	}
-
-	if _, err := iops.Exec("database", []string{"chmod", "+x", neo4jRemoteWatchdogBinary}, nil); err != nil {
-		return fmt.Errorf("failed to mark watchdog executable: %w", err)
+	if _, err := iops.Exec("database", []string{"mkdir", "-p", neo4jRemoteWorkDir}, nil); err != nil {
+		return nil, fmt.Errorf("failed to prepare remote work directory: %w", err)
 	}
 
-	if _, err := iops.Exec("database", []string{"rm", "-f", neo4jRemoteWatchdogReady, neo4jRemoteWatchdogLog}, nil); err != nil {
-		logrus.Debugf("Could not clear watchdog markers: %v", err)
+	heartbeatTimeout := watchdogHeartbeatTimeout(iops.config.WatchdogHeartbeatInterval)
+	stopHeartbeat := func() {}
+	if mode != watchdogModeNone && heartbeatTimeout > 0 {
+		stopHeartbeat, err = iops.startWatchdogHeartbeat(iops.config.WatchdogHeartbeatInterval)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	watchdogCmd := fmt.Sprintf("nohup %s --ready-file %s >%s 2>&1 &", neo4jRemoteWatchdogBinary, neo4jRemoteWatchdogReady, neo4jRemoteWatchdogLog)
-	if _, err := iops.Exec("database", []string{"sh", "-c", watchdogCmd}, nil); err != nil {
-		return fmt.Errorf("failed to start watchdog: %w", err)
+	switch mode {
+	case watchdogModeBinary:
+		if err := iops.startBinaryWatchdog(heartbeatTimeout); err != nil {
+			logrus.Warnf("Could not start watchdog binary (%v); falling back to shell watchdog", err)
+			if err := iops.startShellWatchdog(pidStr, heartbeatTimeout); err != nil {
+				stopHeartbeat()
+				return nil, err
+			}
+		}
+	case watchdogModeShell:
+		if err := iops.startShellWatchdog(pidStr, heartbeatTimeout); err != nil {
+			stopHeartbeat()
+			return nil, err
+		}
+	case watchdogModeNone:
+		logrus.Warn("Watchdog disabled (--watchdog-mode=none); Neo4j may be left stopped if this tool is interrupted")
 	}
 
-	if err := iops.waitForRemoteFile(neo4jRemoteWatchdogReady, neo4jWatchdogInitTimeout); err != nil {
-		return fmt.Errorf("watchdog failed to initialize: %w", err)
+	if _, err := iops.Exec("database", []string{"kill", pidStr}, nil); err != nil {
+		stopHeartbeat()
+		return nil, fmt.Errorf("failed to stop neo4j: %w", err)
 	}
 
-	if _, err := iops.Exec("database", []string{"kill", pidStr}, nil); err != nil {
-		return fmt.Errorf("failed to stop neo4j: %w", err)
+	if mode == watchdogModeNone {
+		if err := iops.suspendNeo4jAfterShutdown(pidStr, neo4jProcessStopTimeout); err != nil {
+			return nil, err
+		}
 	}
 
 	logrus.Info("Waiting for Neo4j process to stop...")
 	if err := iops.waitForProcessStopped(pidStr, neo4jProcessStopTimeout); err != nil {
-		return err
+		stopHeartbeat()
+		return nil, err
+	}
+	if iops.config.Neo4jPortCheck {
+		if err := iops.waitForNeo4jPortsClosed(neo4jPortCloseTimeout); err != nil {
+			stopHeartbeat()
+			return nil, err
+		}
 	}
 
-	return nil
+	return stopHeartbeat, nil
 }
 
 func (iops *InfrahubOps) readNeo4jPID() (string, error) {
@@ -190,46 +333,77 @@ func (iops *InfrahubOps) detectNeo4jArchitecture() (string, error) {
 	return arch, nil
 }
 
-func (iops *InfrahubOps) restoreNeo4j(workDir, neo4jEdition string, restoreMigrateFormat bool) error {
+// stageNeo4jBackup copies the Neo4j backup into the database container ahead of the restore.
+// The returned cleanup removes it again and must be called even if the restore fails.
+func (iops *InfrahubOps) stageNeo4jBackup(workDir, neo4jEdition string) (func(), error) {
+	if strings.EqualFold(neo4jEdition, neo4jEditionExternal) {
+		return func() {}, nil
+	}
+
+	logrus.Info("Copying Neo4j backup into the database container...")
+	cleanup := func() {
+		iops.removeRemotePaths("database", neo4jTempBackupDir)
+	}
+
 	backupPath := filepath.Join(workDir, "backup", "database")
 	if err := iops.CopyTo("database", backupPath, neo4jTempBackupDir); err != nil {
-		return fmt.Errorf("failed to copy backup to container: %w", err)
+		cleanup()
+		return nil, fmt.Errorf("failed to copy backup to container: %w", err)
 	}
-	defer func() {
-		if _, err := iops.Exec("database", []string{"rm", "-rf", neo4jTempBackupDir}, nil); err != nil {
-			logrus.Warnf("Failed to cleanup temporary Neo4j backup data (this is expected for community restore method): %v", err)
-		}
-	}()
 
 	if _, err := iops.Exec("database", []string{"chown", "-R", "neo4j:neo4j", neo4jTempBackupDir}, nil); err != nil {
-		return fmt.Errorf("failed to change backup ownership: %w", err)
+		cleanup()
+		return nil, fmt.Errorf("failed to change backup ownership: %w", err)
+	}
+	return cleanup, nil
+}
+
+// restoreNeo4j restores the database from the backup staged by stageNeo4jBackup, or with
+// streamDump from the dump in workDir.
+func (iops *InfrahubOps) restoreNeo4j(workDir, diagDir, neo4jEdition, backupMode string, restoreMigrateFormat, replayMetadata, streamDump bool) error {
+	if strings.EqualFold(neo4jEdition, neo4jEditionExternal) {
+		if restoreMigrateFormat {
+			logrus.Warn("--migrate-format does not apply to an external Neo4j; ignoring")
+		}
+		return iops.restoreNeo4jExternal(workDir)
 	}
 
 	edition := strings.ToLower(neo4jEdition)
 	switch edition {
 	case neo4jEditionCommunity:
-		return iops.restoreNeo4jCommunity(restoreMigrateFormat)
+		return iops.restoreNeo4jCommunity(workDir, diagDir, restoreMigrateFormat, streamDump)
+	case neo4jEditionEnterprise:
+		return iops.restoreNeo4jEnterprise(backupMode == neo4jBackupModeOffline, restoreMigrateFormat, replayMetadata)
 	default:
-		return iops.restoreNeo4jEnterprise(restoreMigrateFormat)
+		return checkNeo4jEdition(neo4jEdition)
 	}
 }
 
-func (iops *InfrahubOps) restoreNeo4jEnterprise(restoreMigrateFormat bool) error {
+// restoreNeo4jEnterprise restores the online backup, or loads the dump of a backup taken with
+// --neo4j-offline-enterprise, and, when replayMetadata is set, replays the users and roles that
+// neo4j-admin extracted from it.
+func (iops *InfrahubOps) restoreNeo4jEnterprise(offlineDump, restoreMigrateFormat, replayMetadata bool) error {
 	logrus.Info("Restoring Neo4j database (Enterprise Edition)...")
 
 	opts := &ExecOptions{User: "neo4j"}
 
-	if _, err := iops.Exec(
-		"database",
-		[]string{"cypher-shell", "-u", iops.config.Neo4jUsername, "-p" + iops.config.Neo4jPassword, "-d", "system", "stop database " + iops.config.Neo4jDatabase},
-		nil,
-	); err != nil {
-		return fmt.Errorf("failed to stop neo4j database: %w", err)
+	if err := iops.stopNeo4jDatabase(); err != nil {
+		return err
 	}
 
-	if output, err := iops.Exec(
+	iops.removeStaleNeo4jMetadataScripts(opts)
+
+	if offlineDump {
+		if output, err := iops.Exec(
+			"database",
+			[]string{iops.config.tool(neo4jAdminTool), "database", "load", "--expand-commands", "--overwrite-destination=true", "--from-path=" + neo4jTempBackupDir, iops.config.Neo4jDatabase},
+			opts,
+		); err != nil {
+			return fmt.Errorf("failed to load neo4j dump: %w\nOutput: %v", err, output)
+		}
+	} else if output, err := iops.Exec(
 		"database",
-		[]string{"neo4j-admin", "database", "restore", "--expand-commands", "--overwrite-destination=true", "--from-path=" + neo4jTempBackupDir, iops.config.Neo4jDatabase},
+		[]string{iops.config.tool(neo4jAdminTool), "database", "restore", "--expand-commands", "--overwrite-destination=true", "--from-path=" + neo4jTempBackupDir, iops.config.Neo4jDatabase},
 		opts,
 	); err != nil {
 		return fmt.Errorf("failed to restore neo4j: %w\nOutput: %v", err, output)
@@ -238,33 +412,80 @@ func (iops *InfrahubOps) restoreNeo4jEnterprise(restoreMigrateFormat bool) error
 	if restoreMigrateFormat {
 		if output, err := iops.Exec(
 			"database",
-			[]string{"neo4j-admin", "database", "migrate", "--expand-commands", "--to-format=block", iops.config.Neo4jDatabase},
+			[]string{iops.config.tool(neo4jAdminTool), "database", "migrate", "--expand-commands", "--to-format=block", iops.config.Neo4jDatabase},
 			opts,
 		); err != nil {
 			return fmt.Errorf("failed to migrate neo4j to block format: %w\nOutput: %v", err, output)
 		}
 	}
 
+	if replayMetadata {
+		if err := iops.replayNeo4jMetadata(opts); err != nil {
+			return err
+		}
+	} else {
+		logrus.Info("Skipping Neo4j metadata (users and roles) restore")
+	}
+
+	return iops.startNeo4jDatabase()
+}
+
+// neo4jDataDirCandidates are the data directories searched for the extracted metadata script.
+const neo4jDataDirCandidates = `/data "${NEO4J_HOME:-/var/lib/neo4j}/data" /var/lib/neo4j/data`
+
+func (iops *InfrahubOps) neo4jMetadataScriptRelPath() string {
+	return "scripts/" + iops.config.Neo4jDatabase + "/" + neo4jMetadataScriptName
+}
+
+// removeStaleNeo4jMetadataScripts deletes metadata scripts left by an earlier restore, so the
+// script replayed afterwards is guaranteed to come from this backup.
+func (iops *InfrahubOps) removeStaleNeo4jMetadataScripts(opts *ExecOptions) {
+	remove := fmt.Sprintf(`for dir in %s; do rm -f "$dir/%s"; done`, neo4jDataDirCandidates, iops.neo4jMetadataScriptRelPath())
+	if output, err := iops.Exec("database", []string{"sh", "-c", remove}, opts); err != nil {
+		logrus.Warnf("Failed to remove stale Neo4j metadata scripts: %v %s", err, strings.TrimSpace(output))
+	}
+}
+
+// resolveNeo4jMetadataScript returns the metadata script to replay after an Enterprise restore.
+// An explicit --neo4j-metadata-script is used when it exists; otherwise the script that
+// neo4j-admin extracted from the backup is looked up in the usual data directories.
+func (iops *InfrahubOps) resolveNeo4jMetadataScript(opts *ExecOptions) (string, error) {
+	configured := iops.config.Neo4jMetadataScript
+	if configured != "" {
+		if _, err := iops.Exec("database", []string{"test", "-f", configured}, opts); err == nil {
+			logrus.WithField("path", configured).Info("Using Neo4j metadata script from --neo4j-metadata-script")
+			return configured, nil
+		}
+		logrus.Warnf("Neo4j metadata script %s not found; looking for the script extracted from the backup", configured)
+	}
+
+	relative := iops.neo4jMetadataScriptRelPath()
+	search := fmt.Sprintf(`for dir in %s; do if [ -f "$dir/%s" ]; then echo "$dir/%s"; exit 0; fi; done; exit 1`, neo4jDataDirCandidates, relative, relative)
+	output, err := iops.Exec("database", []string{"sh", "-c", search}, opts)
+	if path := strings.TrimSpace(output); err == nil && path != "" {
+		logrus.WithField("path", path).Info("Using Neo4j metadata script extracted from the backup")
+		return path, nil
+	}
+	return "", fmt.Errorf("neo4j metadata script %s not found in the database container; set --neo4j-metadata-script to its location", relative)
+}
+
+// replayNeo4jMetadata runs the extracted metadata script against the system database.
+func (iops *InfrahubOps) replayNeo4jMetadata(opts *ExecOptions) error {
+	metadataScript, err := iops.resolveNeo4jMetadataScript(opts)
+	if err != nil {
+		return err
+	}
 	if output, err := iops.Exec(
 		"database",
-		[]string{"sh", "-c", "cat " + neo4jMetadataScriptPath + " | cypher-shell -u " + iops.config.Neo4jUsername + " -p" + iops.config.Neo4jPassword + " -d system --param \"database => '" + iops.config.Neo4jDatabase + "'\""},
+		[]string{"sh", "-c", "cat " + metadataScript + " | " + shellQuote(iops.config.tool(cypherShellTool)) + " -u " + iops.config.Neo4jUsername + " -p" + iops.config.Neo4jPassword + " -d system --param \"database => '" + iops.config.Neo4jDatabase + "'\""},
 		opts,
 	); err != nil {
 		return fmt.Errorf("failed to restore neo4j metadata: %w\nOutput: %v", err, output)
 	}
-
-	if _, err := iops.Exec(
-		"database",
-		[]string{"cypher-shell", "-u", iops.config.Neo4jUsername, "-p" + iops.config.Neo4jPassword, "-d", "system", "start database " + iops.config.Neo4jDatabase},
-		nil,
-	); err != nil {
-		return fmt.Errorf("failed to start neo4j database: %w", err)
-	}
-
 	return nil
 }
 
-func (iops *InfrahubOps) restoreNeo4jCommunity(restoreMigrateFormat bool) (retErr error) {
+func (iops *InfrahubOps) restoreNeo4jCommunity(workDir, diagDir string, restoreMigrateFormat, streamDump bool) (retErr error) {
 	logrus.Info("Restoring Neo4j database (Community Edition dump)...")
 
 	pidStr, err := iops.readNeo4jPID()
@@ -272,30 +493,37 @@ func (iops *InfrahubOps) restoreNeo4jCommunity(restoreMigrateFormat bool) (retEr
 		return err
 	}
 
-	err = iops.stopNeo4jCommunity(pidStr)
+	stopHeartbeat, err := iops.stopNeo4jCommunity(pidStr)
 	if err != nil {
+		iops.collectWatchdogLog(diagDir)
+		iops.collectNeo4jLogs(diagDir, true)
 		return err
 	}
 
 	defer func() {
-		if _, err := iops.Exec("database", []string{"rm", "-rf", neo4jTempBackupDir}, nil); err != nil {
-			logrus.Warnf("Failed to cleanup temporary Neo4j backup data: %v", err)
-		}
-		if _, err := iops.Exec("database", []string{"rm", "-f", neo4jRemoteWatchdogBinary, neo4jRemoteWatchdogReady, neo4jRemoteWatchdogLog}, nil); err != nil {
-			logrus.Debugf("Failed to remove watchdog artifacts: %v", err)
+		stopHeartbeat()
+		if retErr != nil {
+			iops.collectWatchdogLog(diagDir)
 		}
+		iops.removeRemotePaths("database", neo4jTempBackupDir, neo4jRemoteWatchdogBinary, neo4jRemoteWatchdogReady, neo4jRemoteWatchdogLog, neo4jRemoteWatchdogHeartbeat)
 		if _, err := iops.Exec("database", []string{"kill", "-CONT", pidStr}, nil); err != nil {
 			logrus.Errorf("Failed to send SIGCONT to neo4j (pid %s): %v", pidStr, err)
 			if retErr == nil {
 				retErr = fmt.Errorf("failed to resume neo4j process: %w", err)
 			}
 		}
+		iops.collectNeo4jLogs(diagDir, retErr != nil)
 	}()
 
 	opts := &ExecOptions{User: "neo4j"}
-	if output, err := iops.Exec(
+	if streamDump {
+		dumpPath := filepath.Join(workDir, "backup", "database", iops.config.Neo4jDatabase+".dump")
+		if err := iops.loadNeo4jDumpFromStdin(iops.config.Neo4jDatabase, dumpPath, opts); err != nil {
+			return err
+		}
+	} else if output, err := iops.Exec(
 		"database",
-		[]string{"neo4j-admin", "database", "load", "--overwrite-destination=true", "--from-path=" + neo4jTempBackupDir, iops.config.Neo4jDatabase},
+		[]string{iops.config.tool(neo4jAdminTool), "database", "load", "--overwrite-destination=true", "--from-path=" + neo4jTempBackupDir, iops.config.Neo4jDatabase},
 		opts,
 	); err != nil {
 		return fmt.Errorf("failed to load neo4j dump: %w\nOutput: %v", err, output)
@@ -304,13 +532,19 @@ func (iops *InfrahubOps) restoreNeo4jCommunity(restoreMigrateFormat bool) (retEr
 	if restoreMigrateFormat {
 		if output, err := iops.Exec(
 			"database",
-			[]string{"neo4j-admin", "database", "migrate", "--to-format=block", iops.config.Neo4jDatabase},
+			[]string{iops.config.tool(neo4jAdminTool), "database", "migrate", "--to-format=block", iops.config.Neo4jDatabase},
 			opts,
 		); err != nil {
 			return fmt.Errorf("failed to migrate neo4j to block format: %w\nOutput: %v", err, output)
 		}
 	}
 
+	if iops.shouldRestoreNeo4jAuth(workDir) {
+		if err := iops.restoreNeo4jAuth(workDir, opts, streamDump); err != nil {
+			return err
+		}
+	}
+
 	logrus.Info("Neo4j dump restored successfully")
 	return nil
 }
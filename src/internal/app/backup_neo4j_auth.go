@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// neo4jSystemDatabase is where Neo4j keeps users and passwords.
+const neo4jSystemDatabase = "system"
+
+// neo4jAuthFiles are the files in <data>/dbms that hold the initial password and, on older
+// Neo4j versions, the user store.
+var neo4jAuthFiles = []string{"auth", "auth.ini"}
+
+// backupNeo4jAuth captures the users of a Community instance into the neo4j-auth component.
+// It runs while Neo4j is suspended, like the database dump itself.
+func (iops *InfrahubOps) backupNeo4jAuth(backupDir string) error {
+	logrus.Info("Backing up Neo4j users (system database and auth files)...")
+	authDir := filepath.Join(backupDir, neo4jAuthDirName)
+	if err := os.MkdirAll(authDir, 0755); err != nil {
+		return fmt.Errorf("failed to prepare neo4j auth directory: %w", err)
+	}
+
+	if output, err := iops.Exec("database", []string{
+		iops.config.tool(neo4jAdminTool), "database", "dump",
+		"--overwrite-destination=true",
+		"--to-path=" + neo4jRemoteWorkDir,
+		neo4jSystemDatabase,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to dump neo4j system database: %w\nOutput: %v", err, output)
+	}
+	dumpFilename := neo4jSystemDatabase + ".dump"
+	if err := iops.CopyFrom("database", neo4jRemoteWorkDir+"/"+dumpFilename, filepath.Join(authDir, dumpFilename)); err != nil {
+		return fmt.Errorf("failed to copy neo4j system database dump: %w", err)
+	}
+
+	dbmsDir, err := iops.neo4jDbmsDir()
+	if err != nil {
+		logrus.Debugf("No Neo4j dbms directory found: %v", err)
+		return nil
+	}
+	for _, name := range neo4jAuthFiles {
+		remote := dbmsDir + "/" + name
+		if _, err := iops.Exec("database", []string{"test", "-f", remote}, nil); err != nil {
+			continue
+		}
+		if err := iops.CopyFrom("database", remote, filepath.Join(authDir, name)); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", remote, err)
+		}
+	}
+	return nil
+}
+
+// restoreNeo4jAuth loads the system database and puts back the auth files captured by
+// backupNeo4jAuth. It runs while Neo4j is suspended, after the Infrahub database was loaded.
+// With streamDump the system dump is streamed like the Infrahub one, since nothing was staged.
+func (iops *InfrahubOps) restoreNeo4jAuth(workDir string, opts *ExecOptions, streamDump bool) error {
+	logrus.Info("Restoring Neo4j users (system database and auth files)...")
+	authDir := filepath.Join(workDir, "backup", neo4jAuthDirName)
+
+	dumpFilename := neo4jSystemDatabase + ".dump"
+	if streamDump {
+		if err := iops.loadNeo4jDumpFromStdin(neo4jSystemDatabase, filepath.Join(authDir, dumpFilename), opts); err != nil {
+			return err
+		}
+	} else {
+		if err := iops.CopyTo("database", filepath.Join(authDir, dumpFilename), neo4jTempBackupDir+"/"+dumpFilename); err != nil {
+			return fmt.Errorf("failed to copy neo4j system database dump: %w", err)
+		}
+		if _, err := iops.Exec("database", []string{"chown", "neo4j:neo4j", neo4jTempBackupDir + "/" + dumpFilename}, nil); err != nil {
+			return fmt.Errorf("failed to change system database dump ownership: %w", err)
+		}
+		if output, err := iops.Exec(
+			"database",
+			[]string{iops.config.tool(neo4jAdminTool), "database", "load", "--overwrite-destination=true", "--from-path=" + neo4jTempBackupDir, neo4jSystemDatabase},
+			opts,
+		); err != nil {
+			return fmt.Errorf("failed to load neo4j system database: %w\nOutput: %v", err, output)
+		}
+	}
+
+	dbmsDir, err := iops.neo4jDbmsDir()
+	if err != nil {
+		return err
+	}
+	for _, name := range neo4jAuthFiles {
+		local := filepath.Join(authDir, name)
+		if !fileExists(local) {
+			continue
+		}
+		remote := dbmsDir + "/" + name
+		if err := iops.CopyTo("database", local, remote); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", remote, err)
+		}
+		if _, err := iops.Exec("database", []string{"chown", "neo4j:neo4j", remote}, nil); err != nil {
+			logrus.Warnf("Failed to give %s to the neo4j user: %v", remote, err)
+		}
+	}
+	return nil
+}
+
+// shouldRestoreNeo4jAuth reports whether the archive extracted in workDir holds Neo4j users to
+// restore and --exclude-neo4j-auth is not set.
+func (iops *InfrahubOps) shouldRestoreNeo4jAuth(workDir string) bool {
+	if !fileExists(filepath.Join(workDir, "backup", neo4jAuthDirName, neo4jSystemDatabase+".dump")) {
+		return false
+	}
+	if iops.config.ExcludeNeo4jAuth {
+		logrus.Info("Skipping Neo4j users restore as requested")
+		return false
+	}
+	return true
+}
+
+// neo4jDbmsDir returns the <data>/dbms directory of the database container.
+func (iops *InfrahubOps) neo4jDbmsDir() (string, error) {
+	search := fmt.Sprintf(`for dir in %s; do if [ -d "$dir/dbms" ]; then echo "$dir/dbms"; exit 0; fi; done; exit 1`, neo4jDataDirCandidates)
+	output, err := iops.Exec("database", []string{"sh", "-c", search}, nil)
+	if dir := strings.TrimSpace(output); err == nil && dir != "" {
+		return dir, nil
+	}
+	return "", fmt.Errorf("neo4j dbms directory not found under %s", neo4jDataDirCandidates)
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	neo4jModeContainer = "container"
+	neo4jModeExternal  = "external"
+
+	// neo4jEditionExternal marks backups taken as a logical export of an external Neo4j.
+	neo4jEditionExternal = "external"
+	neo4jExportFilename  = "neo4j-export.cypher"
+
+	neo4jExternalProgressEvery = 500
+)
+
+// neo4jExportQuery streams the whole database as Cypher statements. Schema statements use
+// IF NOT EXISTS so the export can be replayed into a database that already has the schema.
+const neo4jExportQuery = `CALL apoc.export.cypher.all(null, {
+  stream: true,
+  format: "plain",
+  ifNotExists: true,
+  useOptimizations: {type: "UNWIND_BATCH", unwindBatchSize: 1000}
+}) YIELD cypherStatements
+RETURN cypherStatements`
+
+// neo4jWipeQuery deletes all nodes and relationships in batches to bound transaction size.
+const neo4jWipeQuery = `MATCH (n) CALL { WITH n DETACH DELETE n } IN TRANSACTIONS OF 10000 ROWS`
+
+// resolveNeo4jMode returns "container" or "external". Setting --neo4j-host alone selects external.
+func (iops *InfrahubOps) resolveNeo4jMode() (string, error) {
+	switch strings.ToLower(iops.config.Neo4jMode) {
+	case "":
+		if iops.config.Neo4jHost != "" {
+			return neo4jModeExternal, nil
+		}
+		return neo4jModeContainer, nil
+	case neo4jModeContainer:
+		return neo4jModeContainer, nil
+	case neo4jModeExternal:
+		if iops.config.Neo4jHost == "" {
+			return "", fmt.Errorf("--neo4j-mode=external requires --neo4j-host")
+		}
+		return neo4jModeExternal, nil
+	default:
+		return "", fmt.Errorf("invalid neo4j mode %q (expected %s or %s)", iops.config.Neo4jMode, neo4jModeContainer, neo4jModeExternal)
+	}
+}
+
+// isExternalNeo4j reports whether the database is reached over Bolt instead of through the database container.
+func (iops *InfrahubOps) isExternalNeo4j() bool {
+	mode, err := iops.resolveNeo4jMode()
+	return err == nil && mode == neo4jModeExternal
+}
+
+// openNeo4jDriver connects to the external Neo4j endpoint and verifies connectivity.
+func (iops *InfrahubOps) openNeo4jDriver(ctx context.Context) (neo4j.DriverWithContext, error) {
+	driver, err := neo4j.NewDriverWithContext(
+		iops.config.Neo4jHost,
+		neo4j.BasicAuth(iops.config.Neo4jUsername, iops.config.Neo4jPassword, ""),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create neo4j driver for %s: %w", iops.config.Neo4jHost, err)
+	}
+	if err := driver.VerifyConnectivity(ctx); err != nil {
+		driver.Close(ctx)
+		if isAuthFailure(err) {
+			return nil, fmt.Errorf("failed to connect to neo4j at %s: %w: %w", iops.config.Neo4jHost, ErrAuthFailed, err)
+		}
+		return nil, fmt.Errorf("failed to connect to neo4j at %s: %w", iops.config.Neo4jHost, err)
+	}
+	return driver, nil
+}
+
+// backupNeo4jExternal writes a logical Cypher export of an external Neo4j (e.g. Aura),
+// where neo4j-admin is not available.
+func (iops *InfrahubOps) backupNeo4jExternal(backupDir string, backupMetadata string) error {
+	logrus.WithField("host", iops.config.Neo4jHost).Info("Backing up Neo4j database (external logical export)...")
+	if backupMetadata != "none" {
+		logrus.Warn("Users and roles are not included in logical exports of an external Neo4j")
+	}
+
+	ctx := context.Background()
+	driver, err := iops.openNeo4jDriver(ctx)
+	if err != nil {
+		return err
+	}
+	defer driver.Close(ctx)
+
+	exportDir := filepath.Join(backupDir, neo4jBackupDirName)
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+	file, err := os.Create(filepath.Join(exportDir, neo4jExportFilename))
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+
+	session := driver.NewSession(ctx, neo4j.SessionConfig{
+		DatabaseName: iops.config.Neo4jDatabase,
+		AccessMode:   neo4j.AccessModeRead,
+	})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, neo4jExportQuery, nil)
+	if err != nil {
+		return fmt.Errorf("failed to export neo4j (requires APOC apoc.export.cypher.all): %w", err)
+	}
+	batches := 0
+	for result.Next(ctx) {
+		value, _ := result.Record().Get("cypherStatements")
+		statements, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if _, err := writer.WriteString(statements); err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+		batches++
+	}
+	if err := result.Err(); err != nil {
+		return fmt.Errorf("failed to export neo4j (requires APOC apoc.export.cypher.all): %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	logrus.WithField("batches", batches).Info("Neo4j backup completed")
+	return nil
+}
+
+// restoreNeo4jExternal wipes the external database and replays the logical export.
+func (iops *InfrahubOps) restoreNeo4jExternal(workDir string) error {
+	logrus.WithField("host", iops.config.Neo4jHost).Info("Restoring Neo4j database (external logical import)...")
+
+	// Streamed statement by statement: the export of a large graph doesn't fit in memory
+	export, err := os.Open(filepath.Join(workDir, "backup", neo4jBackupDirName, neo4jExportFilename))
+	if err != nil {
+		return fmt.Errorf("failed to read neo4j export: %w", err)
+	}
+	defer export.Close()
+
+	ctx := context.Background()
+	driver, err := iops.openNeo4jDriver(ctx)
+	if err != nil {
+		return err
+	}
+	defer driver.Close(ctx)
+
+	session := driver.NewSession(ctx, neo4j.SessionConfig{
+		DatabaseName: iops.config.Neo4jDatabase,
+		AccessMode:   neo4j.AccessModeWrite,
+	})
+	defer session.Close(ctx)
+
+	logrus.Info("Deleting existing Neo4j data...")
+	if err := runCypher(ctx, session, neo4jWipeQuery); err != nil {
+		return fmt.Errorf("failed to wipe neo4j: %w", err)
+	}
+
+	replayed := 0
+	scanner := newCypherStatementScanner(export)
+	for scanner.Scan() {
+		if err := runCypher(ctx, session, scanner.Statement()); err != nil {
+			return fmt.Errorf("failed to replay statement %d: %w", replayed+1, err)
+		}
+		replayed++
+		if replayed%neo4jExternalProgressEvery == 0 {
+			logrus.Infof("Replayed %d statements", replayed)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read neo4j export after %d statements: %w", replayed, err)
+	}
+
+	logrus.WithField("statements", replayed).Info("Neo4j restore completed")
+	return nil
+}
+
+// runCypher executes a statement in an auto-commit transaction and discards the result.
+func runCypher(ctx context.Context, session neo4j.SessionWithContext, statement string) error {
+	result, err := session.Run(ctx, statement, nil)
+	if err != nil {
+		return err
+	}
+	_, err = result.Consume(ctx)
+	return err
+}
+
+// splitCypherStatements splits Cypher statements separated by ";", such as an APOC "plain" export.
+func splitCypherStatements(content string) []string {
+	var statements []string
+	scanner := newCypherStatementScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		statements = append(statements, scanner.Statement())
+	}
+	return statements
+}
+
+// cypherStatementScanner reads Cypher statements one at a time. A ";" only ends a statement
+// outside string literals and backquoted names, so a property value holding ";" and a newline
+// stays in its statement. The last statement may omit its ";".
+type cypherStatementScanner struct {
+	reader    *bufio.Reader
+	statement string
+	err       error
+}
+
+func newCypherStatementScanner(r io.Reader) *cypherStatementScanner {
+	return &cypherStatementScanner{reader: bufio.NewReader(r)}
+}
+
+// Scan advances to the next non-empty statement and reports whether there is one.
+func (s *cypherStatementScanner) Scan() bool {
+	var current strings.Builder
+	var quote rune // the open ', " or `, or 0 outside quotes
+	escaped := false
+	for {
+		r, _, err := s.reader.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+				return false
+			}
+			s.statement = strings.TrimSpace(current.String())
+			return s.statement != ""
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && quote != '`' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ';':
+			if s.statement = strings.TrimSpace(current.String()); s.statement != "" {
+				return true
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+}
+
+// Statement returns the statement read by the last successful Scan, without its ";".
+func (s *cypherStatementScanner) Statement() string {
+	return s.statement
+}
+
+// Err returns the read error that stopped Scan, if any.
+func (s *cypherStatementScanner) Err() error {
+	return s.err
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/iotest"
+)
+
+func TestSplitCypherStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "apoc plain export",
+			content: "CREATE (:A {id: 1});\nCREATE (:A {id: 2});\n",
+			want:    []string{"CREATE (:A {id: 1})", "CREATE (:A {id: 2})"},
+		},
+		{
+			name:    "separator in a string",
+			content: "CREATE (:A {text: 'a;\nb'});\nCREATE (:A {text: \"c;\nd\"});\n",
+			want:    []string{"CREATE (:A {text: 'a;\nb'})", "CREATE (:A {text: \"c;\nd\"})"},
+		},
+		{
+			name:    "escaped quotes",
+			content: `CREATE (:A {text: 'it\'s;'});` + "\n" + `CREATE (:A {text: "say \"hi;\""});` + "\n" + `CREATE (:A {text: 'back\\'});`,
+			want:    []string{`CREATE (:A {text: 'it\'s;'})`, `CREATE (:A {text: "say \"hi;\""})`, `CREATE (:A {text: 'back\\'})`},
+		},
+		{
+			name:    "backquoted name",
+			content: "CREATE (:`odd;\nlabel` {id: 1});\nMATCH (n) RETURN n",
+			want:    []string{"CREATE (:`odd;\nlabel` {id: 1})", "MATCH (n) RETURN n"},
+		},
+		{
+			name:    "empty statements",
+			content: "\n;\n  ;CREATE (:A);;\n",
+			want:    []string{"CREATE (:A)"},
+		},
+		{name: "empty", content: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitCypherStatements(tt.content); !slices.Equal(got, tt.want) {
+				t.Errorf("splitCypherStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCypherStatementScannerReadError(t *testing.T) {
+	readErr := errors.New("disk error")
+	scanner := newCypherStatementScanner(iotest.ErrReader(readErr))
+	if scanner.Scan() {
+		t.Fatalf("Scan() = true on a failing reader, statement %q", scanner.Statement())
+	}
+	if !errors.Is(scanner.Err(), readErr) {
+		t.Errorf("Err() = %v, want %v", scanner.Err(), readErr)
+	}
+}
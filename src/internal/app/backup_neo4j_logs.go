@@ -0,0 +1,87 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// neo4jLogsDirname holds the captured Neo4j logs, in the working directory and the bundle.
+const neo4jLogsDirname = "neo4j_logs"
+
+// defaultNeo4jLogLines is how much of each Neo4j log --include-neo4j-logs keeps.
+const defaultNeo4jLogLines = 500
+
+// defaultNeo4jLogPaths are the file logs of the official Neo4j image, which links
+// $NEO4J_HOME/logs to /logs.
+var defaultNeo4jLogPaths = []string{"/logs/neo4j.log", "/logs/debug.log"}
+
+// collectNeo4jLogs copies the tail of each --neo4j-log-paths file in the database container
+// into localDir/neo4j_logs and the diagnostic bundle, when --include-neo4j-logs is set. It runs
+// after a Neo4j Community backup or restore, so the logs cover the stop and dump sequence.
+// Files that don't exist or are empty are skipped, since the paths vary by image.
+func (iops *InfrahubOps) collectNeo4jLogs(localDir string, failed bool) {
+	if !iops.config.IncludeNeo4jLogs {
+		return
+	}
+	lines := iops.config.Neo4jLogLines
+	if lines < 1 {
+		lines = defaultNeo4jLogLines
+	}
+
+	seen := map[string]int{}
+	var collected []string
+	for _, remote := range iops.config.Neo4jLogPaths {
+		if remote = strings.TrimSpace(remote); remote == "" {
+			continue
+		}
+		// A missing file is not a failed command: it must not replace the one in the bundle
+		output, err := iops.Exec("database", []string{"sh", "-c", `[ ! -r "$1" ] || tail -n "$2" "$1"`, "sh", remote, strconv.Itoa(lines)}, nil)
+		if err != nil {
+			logrus.Warnf("Could not read Neo4j log %s: %v", remote, err)
+			continue
+		}
+		if strings.TrimSpace(output) == "" {
+			logrus.Debugf("Neo4j log %s is missing or empty", remote)
+			continue
+		}
+
+		name := path.Base(remote)
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%d_%s", seen[name], name)
+		}
+		iops.report.Load().attach(neo4jLogsDirname+"/"+name, output)
+		collected = append(collected, remote)
+
+		if localDir == "" {
+			continue
+		}
+		localPath := filepath.Join(localDir, neo4jLogsDirname, name)
+		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			logrus.Warnf("Could not save Neo4j log %s: %v", remote, err)
+			continue
+		}
+		if err := os.WriteFile(localPath, []byte(output), 0600); err != nil {
+			logrus.Warnf("Could not save Neo4j log %s: %v", remote, err)
+		}
+	}
+
+	if len(collected) == 0 {
+		logrus.Warnf("No Neo4j log found at %s; set --neo4j-log-paths for this image", strings.Join(iops.config.Neo4jLogPaths, ", "))
+		return
+	}
+	entry := logrus.WithField("logs", strings.Join(collected, ", "))
+	if localDir != "" {
+		entry = entry.WithField("path", filepath.Join(localDir, neo4jLogsDirname))
+	}
+	if failed {
+		entry.Warnf("Captured the last %d lines of the Neo4j logs", lines)
+	} else {
+		entry.Infof("Captured the last %d lines of the Neo4j logs", lines)
+	}
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	neo4jSchemaFilename  = "neo4j_schema.cypher"
+	neo4jSchemaComponent = "neo4j-schema"
+)
+
+// neo4jSchemaQueries return the DDL of the constraints and of the indexes that are not backing
+// a constraint, which are recreated with their constraint.
+var neo4jSchemaQueries = []string{
+	"SHOW CONSTRAINTS YIELD createStatement RETURN createStatement",
+	"SHOW INDEXES YIELD createStatement, owningConstraint WHERE owningConstraint IS NULL RETURN createStatement",
+}
+
+// neo4jSchemaNamePattern matches the head of a CREATE INDEX or CREATE CONSTRAINT statement up to
+// the schema name, so IF NOT EXISTS can be inserted after it.
+var neo4jSchemaNamePattern = regexp.MustCompile("^(CREATE (?:[A-Z]+ )*(?:INDEX|CONSTRAINT) (?:`[^`]+`|[^ `]+))( IF NOT EXISTS)?")
+
+// backupNeo4jSchema writes the constraints and indexes of the Infrahub database as Cypher
+// statements that can be replayed with restore --schema-only.
+func (iops *InfrahubOps) backupNeo4jSchema(backupDir string) (int, error) {
+	logrus.Info("Capturing Neo4j schema (constraints and indexes)...")
+	statements, err := iops.neo4jSchemaStatements()
+	if err != nil {
+		return 0, err
+	}
+	var content strings.Builder
+	for _, statement := range statements {
+		content.WriteString(statement + ";\n")
+	}
+	if err := os.WriteFile(filepath.Join(backupDir, neo4jSchemaFilename), []byte(content.String()), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write neo4j schema: %w", err)
+	}
+	return len(statements), nil
+}
+
+// neo4jSchemaStatements reads the schema DDL of the Infrahub database, made idempotent with
+// IF NOT EXISTS.
+func (iops *InfrahubOps) neo4jSchemaStatements() ([]string, error) {
+	var statements []string
+	for _, query := range neo4jSchemaQueries {
+		var values []string
+		if iops.isExternalNeo4j() {
+			records, err := iops.queryNeo4jExternal(query)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read neo4j schema: %w", err)
+			}
+			values = records
+		} else {
+			output, err := iops.cypherQuery(query)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read neo4j schema: %w\nOutput: %v", err, output)
+			}
+			values = parseCypherStrings(output)
+		}
+		for _, value := range values {
+			statements = append(statements, idempotentSchemaStatement(value))
+		}
+	}
+	return statements, nil
+}
+
+// queryNeo4jExternal returns the first column of every record of a read query against the
+// external Neo4j.
+func (iops *InfrahubOps) queryNeo4jExternal(query string) ([]string, error) {
+	ctx := context.Background()
+	driver, err := iops.openNeo4jDriver(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer driver.Close(ctx)
+
+	session := driver.NewSession(ctx, neo4j.SessionConfig{
+		DatabaseName: iops.config.Neo4jDatabase,
+		AccessMode:   neo4j.AccessModeRead,
+	})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for result.Next(ctx) {
+		if value, ok := result.Record().Values[0].(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values, result.Err()
+}
+
+// parseCypherStrings reads a single string column from plain cypher-shell output, where each
+// value is quoted and the first line is the header.
+func parseCypherStrings(output string) []string {
+	var values []string
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for _, line := range lines[min(1, len(lines)):] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if value, err := strconv.Unquote(line); err == nil {
+			line = value
+		} else {
+			line = strings.Trim(line, `"`)
+		}
+		values = append(values, line)
+	}
+	return values
+}
+
+func idempotentSchemaStatement(statement string) string {
+	return neo4jSchemaNamePattern.ReplaceAllString(strings.TrimSuffix(strings.TrimSpace(statement), ";"), "$1 IF NOT EXISTS")
+}
+
+// restoreNeo4jSchema applies the constraints and indexes captured in the backup to the current
+// database (restore --schema-only). Data and existing schema are left untouched.
+func (iops *InfrahubOps) restoreNeo4jSchema(workDir string, metadata *BackupMetadata) error {
+	schemaPath := filepath.Join(workDir, "backup", neo4jSchemaFilename)
+	if !fileExists(schemaPath) {
+		return fmt.Errorf("backup %s has no captured Neo4j schema (%s); it was created by a version without schema capture", metadata.BackupID, neo4jSchemaFilename)
+	}
+	if expected, ok := metadata.Checksums[neo4jSchemaFilename]; ok {
+		if err := validateFileChecksum(context.Background(), iops.files, schemaPath, neo4jSchemaFilename, expected); err != nil {
+			return err
+		}
+	}
+	content, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read neo4j schema: %w", err)
+	}
+	statements := splitCypherStatements(string(content))
+
+	if iops.config.ValidateOnly {
+		fmt.Printf("Backup archive is valid: %d schema statements would be applied\n", len(statements))
+		return nil
+	}
+
+	logrus.WithField("statements", len(statements)).Info("Applying Neo4j schema from the backup...")
+	apply := func(statement string) error {
+		if output, err := iops.cypherQuery(statement); err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(output))
+		}
+		return nil
+	}
+	if iops.isExternalNeo4j() {
+		ctx := context.Background()
+		driver, err := iops.openNeo4jDriver(ctx)
+		if err != nil {
+			return err
+		}
+		defer driver.Close(ctx)
+		session := driver.NewSession(ctx, neo4j.SessionConfig{
+			DatabaseName: iops.config.Neo4jDatabase,
+			AccessMode:   neo4j.AccessModeWrite,
+		})
+		defer session.Close(ctx)
+		apply = func(statement string) error {
+			return runCypher(ctx, session, statement)
+		}
+	}
+
+	failed := 0
+	for _, statement := range statements {
+		if err := apply(statement); err != nil {
+			failed++
+			logrus.WithField("statement", statement).Errorf("Failed to apply schema statement: %v", err)
+			continue
+		}
+		logrus.Debugf("Applied: %s", statement)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d schema statements failed; existing data may violate a constraint", failed, len(statements))
+	}
+	fmt.Printf("Applied %d schema statements from backup %s\n", len(statements), metadata.BackupID)
+	return nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// neo4jStreamRestore reports whether the Community dump can be streamed from the extracted
+// archive into neo4j-admin database load --from-stdin instead of being copied into the
+// database container first. Neo4j versions whose neo4j-admin has no --from-stdin, and
+// --no-stream-restore, keep the copy.
+func (iops *InfrahubOps) neo4jStreamRestore(workDir, neo4jEdition string) bool {
+	if iops.config.NoStreamRestore || neo4jEdition != neo4jEditionCommunity {
+		return false
+	}
+	dumpPath := filepath.Join(workDir, "backup", "database", iops.config.Neo4jDatabase+".dump")
+	if !fileExists(dumpPath) {
+		logrus.Debugf("No %s in the backup; copying the Neo4j backup into the container", filepath.Base(dumpPath))
+		return false
+	}
+	output, _ := iops.Exec("database", []string{iops.config.tool(neo4jAdminTool), "database", "load", "--help"}, nil)
+	if !strings.Contains(output, "--from-stdin") {
+		logrus.Info("neo4j-admin does not support database load --from-stdin; copying the Neo4j dump into the container")
+		return false
+	}
+	return true
+}
+
+// loadNeo4jDumpFromStdin pipes a local dump into neo4j-admin database load --from-stdin.
+func (iops *InfrahubOps) loadNeo4jDumpFromStdin(database, dumpPath string, opts *ExecOptions) error {
+	dump, err := os.Open(dumpPath)
+	if err != nil {
+		return fmt.Errorf("failed to open neo4j dump: %w", err)
+	}
+	defer dump.Close()
+
+	logrus.WithField("dump", filepath.Base(dumpPath)).Infof("Streaming Neo4j dump into database %s...", database)
+	streamOpts := *opts
+	streamOpts.Stdin = dump
+	if output, err := iops.Exec(
+		"database",
+		[]string{iops.config.tool(neo4jAdminTool), "database", "load", "--overwrite-destination=true", "--from-stdin", database},
+		&streamOpts,
+	); err != nil {
+		return fmt.Errorf("failed to load neo4j dump %s: %w\nOutput: %v", filepath.Base(dumpPath), err, output)
+	}
+	return nil
+}
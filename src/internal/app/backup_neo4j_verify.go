@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultNeo4jVerifyTolerance = 1.0 // percent
+
+// ErrRestoreVerification marks a restore whose Neo4j counts diverge from the backup.
+var ErrRestoreVerification = errors.New("restore verification failed")
+
+// verifyNeo4jRestore waits for the restored database to accept queries, logs its totals and,
+// when the backup recorded its own, fails if either differs by more than tolerance percent
+// (--neo4j-restore-verify).
+func (iops *InfrahubOps) verifyNeo4jRestore(expected *GraphStats, tolerance float64, timeout time.Duration) error {
+	if !iops.isExternalNeo4j() {
+		if err := iops.waitForNeo4jQueries(timeout); err != nil {
+			return err
+		}
+	}
+	counts, err := iops.collectGraphStats()
+	if err != nil {
+		return err
+	}
+	iops.report.Load().set("Graph stats", counts.String())
+	fields := logrus.Fields{"nodes": counts.Nodes, "relationships": counts.Relationships}
+	if expected == nil {
+		if counts.Nodes == 0 {
+			logrus.WithFields(fields).Warn("Restored Neo4j database is empty; the backup recorded no counts to compare with")
+		} else {
+			logrus.WithFields(fields).Info("Restored Neo4j database counts; the backup recorded no counts to compare with")
+		}
+		return nil
+	}
+
+	fields["expected_nodes"] = expected.Nodes
+	fields["expected_relationships"] = expected.Relationships
+	if !withinTolerance(counts.Nodes, expected.Nodes, tolerance) || !withinTolerance(counts.Relationships, expected.Relationships, tolerance) {
+		logrus.WithFields(fields).Error("Restored Neo4j database counts diverge from the backup")
+		return fmt.Errorf("%w: neo4j has %s, the backup recorded %s (tolerance %g%%)", ErrRestoreVerification, counts, expected, tolerance)
+	}
+	logrus.WithFields(fields).Info("Restored Neo4j database counts match the backup")
+	return nil
+}
+
+// withinTolerance reports whether actual is within tolerance percent of expected.
+func withinTolerance(actual, expected int64, tolerance float64) bool {
+	diff := float64(actual - expected)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= float64(expected)*tolerance/100
+}
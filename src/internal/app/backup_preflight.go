@@ -0,0 +1,176 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const preflightProbeFile = neo4jTempBackupDir + "/.infrahubops_preflight"
+
+// checkContainerPermissions probes the privileged operations a backup or restore performs in
+// the database container, so that missing privileges fail upfront with a specific remedy
+// instead of mid-way through with a cryptic error.
+func (iops *InfrahubOps) checkContainerPermissions(neo4jEdition string, restore bool) error {
+	if iops.isExternalNeo4j() || strings.EqualFold(neo4jEdition, neo4jEditionExternal) {
+		return nil
+	}
+	logrus.Info("Checking database container permissions...")
+
+	var problems []string
+	probe := func(command []string, hint string) bool {
+		output, err := iops.Exec("database", command, nil)
+		if err != nil {
+			logrus.Debugf("Permission probe %v failed: %v (%s)", command, err, strings.TrimSpace(output))
+			problems = append(problems, hint)
+			return false
+		}
+		return true
+	}
+
+	stagingOK := probe(
+		[]string{"sh", "-c", "mkdir -p " + neo4jTempBackupDir + " && touch " + preflightProbeFile},
+		fmt.Sprintf("cannot write to the staging directory %s; if the root filesystem is read-only, mount a writable volume (e.g. an emptyDir) at /tmp", neo4jTempBackupDir),
+	)
+
+	if strings.EqualFold(neo4jEdition, neo4jEditionCommunity) {
+		pid, err := iops.readNeo4jPID()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("cannot read the Neo4j PID file %s (%v); run the exec as the neo4j user or root", neo4jPIDFile, err))
+		} else {
+			probe(
+				[]string{"kill", "-0", pid},
+				fmt.Sprintf("cannot signal the Neo4j process (pid %s), which is required to pause it; exec as the user running Neo4j or as root, and do not drop CAP_KILL in the container securityContext", pid),
+			)
+		}
+	}
+
+	if restore {
+		if stagingOK {
+			probe(
+				[]string{"chown", "neo4j:neo4j", preflightProbeFile},
+				"cannot chown files to neo4j:neo4j; the restore needs root or CAP_CHOWN in the database container (securityContext runAsUser: 0, or do not drop CHOWN)",
+			)
+		}
+		probe(
+			[]string{"test", "-w", "/data"},
+			"/data is not writable; neo4j-admin writes the restored database and the metadata script (/data/scripts) there, so check the volume permissions and fsGroup",
+		)
+	}
+
+	if stagingOK {
+		if _, err := iops.Exec("database", []string{"rm", "-f", preflightProbeFile}, nil); err != nil {
+			logrus.Debugf("Failed to remove permission probe file: %v", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: database container permission check failed:\n  - %s", ErrPrerequisites, strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// checkBackupServices fails fast when a service the backup execs into is scaled down or
+// unhealthy, and when Neo4j is up but not yet accepting connections, instead of letting
+// the first exec fail with a confusing error.
+func (iops *InfrahubOps) checkBackupServices(force, excludeTaskManager bool) error {
+	if !iops.isExternalNeo4j() {
+		if err := iops.requireServiceReady("database", "back up Neo4j"); err != nil {
+			return err
+		}
+		if err := iops.probeNeo4jConnections(); err != nil {
+			return err
+		}
+	}
+	if !force {
+		if err := iops.requireServiceReady("task-worker", "check for running tasks (use --force to skip the check)"); err != nil {
+			return err
+		}
+	}
+	if !excludeTaskManager && !iops.config.DumpOnly {
+		if service, err := iops.postgresClient(iops.config.tool(pgDumpTool)); err == nil && service != "" {
+			if err := iops.requireServiceReady(service, "dump the task manager database"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRestoreServices only requires the database container to run: a restore must be
+// able to replace a database that no longer starts cleanly.
+func (iops *InfrahubOps) checkRestoreServices() error {
+	if iops.isExternalNeo4j() {
+		return nil
+	}
+	running, err := iops.IsServiceRunning("database")
+	if err != nil {
+		return fmt.Errorf("could not determine whether service database is running: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("%w: service database is not running, but the restore runs neo4j-admin inside it; %s", ErrPrerequisites, iops.startServiceHint("database"))
+	}
+	return nil
+}
+
+// requireServiceReady returns an error naming the service, what it is needed for and how to
+// fix it when the service is not running or fails its health/readiness check.
+func (iops *InfrahubOps) requireServiceReady(service, purpose string) error {
+	running, err := iops.IsServiceRunning(service)
+	if err != nil {
+		return fmt.Errorf("could not determine whether service %s is running: %w", service, err)
+	}
+	if !running {
+		return fmt.Errorf("%w: service %s is not running but is needed to %s; %s", ErrPrerequisites, service, purpose, iops.startServiceHint(service))
+	}
+	ready, err := iops.IsServiceReady(service)
+	if err != nil {
+		return fmt.Errorf("could not determine whether service %s is ready: %w", service, err)
+	}
+	if !ready {
+		return fmt.Errorf("%w: service %s is running but not ready (failing its health or readiness check, e.g. restarting in a loop) and is needed to %s; %s", ErrPrerequisites, service, purpose, iops.serviceLogsHint(service))
+	}
+	return nil
+}
+
+// probeNeo4jConnections runs a trivial query to tell a Neo4j that is still starting or
+// recovering apart from a container that is merely up.
+func (iops *InfrahubOps) probeNeo4jConnections() error {
+	output, err := iops.Exec("database", []string{
+		iops.config.tool(cypherShellTool),
+		"-u", iops.config.Neo4jUsername,
+		"-p" + iops.config.Neo4jPassword,
+		"-d", "system",
+		"--format", "plain",
+		"RETURN 1",
+	}, nil)
+	if err == nil {
+		return nil
+	}
+	if isAuthFailure(fmt.Errorf("%w: %s", err, output)) {
+		return fmt.Errorf("%w: Neo4j rejected the configured credentials; check INFRAHUB_DB_USERNAME and INFRAHUB_DB_PASSWORD or --neo4j-password-file", ErrAuthFailed)
+	}
+	logrus.Debugf("Neo4j connection probe output: %s", strings.TrimSpace(output))
+	return fmt.Errorf("%w: service database is running but Neo4j is not accepting connections (%v); it may still be starting or recovering, %s", ErrPrerequisites, err, iops.serviceLogsHint("database"))
+}
+
+func (iops *InfrahubOps) startServiceHint(service string) string {
+	switch {
+	case iops.backend != nil && iops.backend.Name() == "kubernetes":
+		return fmt.Sprintf("scale its deployment or statefulset up in namespace %s and wait for the pod to become ready", iops.backend.Info())
+	case iops.backend != nil:
+		return fmt.Sprintf("start it with: docker compose -p %s start %s", iops.backend.Info(), service)
+	}
+	return "start it and try again"
+}
+
+func (iops *InfrahubOps) serviceLogsHint(service string) string {
+	switch {
+	case iops.backend != nil && iops.backend.Name() == "kubernetes":
+		return fmt.Sprintf("check its pod events and logs in namespace %s (kubectl describe pod / kubectl logs)", iops.backend.Info())
+	case iops.backend != nil:
+		return fmt.Sprintf("check its logs with: docker compose -p %s logs %s", iops.backend.Info(), service)
+	}
+	return "check its logs and try again"
+}
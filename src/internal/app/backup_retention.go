@@ -0,0 +1,328 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	backupFilenamePrefix = "infrahub_backup_"
+	backupFilenameSuffix = ".tar.gz"
+	// backupFilenameTarSuffix names the uncompressed archives written with --compression none.
+	backupFilenameTarSuffix = ".tar"
+
+	archiveCompressionGzip = "gzip"
+	archiveCompressionNone = "none"
+	// backupTimestampUTCFmt is the UTC timestamp in backup names: ISO 8601 basic format, which
+	// sorts chronologically and has no colons. backupTimestampFmt is the local time of older
+	// backups and of --local-time.
+	backupTimestampUTCFmt = "20060102T150405Z"
+	backupTimestampFmt    = "20060102_150405"
+
+	backupLocationLocal = "local"
+	backupLocationS3    = "s3"
+
+	retentionReasonKept         = "within-policy"
+	retentionReasonExceedsCount = "exceeds-count"
+	retentionReasonExceedsAge   = "exceeds-age"
+	retentionReasonUnknownAge   = "unknown-age"
+	// retentionReasonNewestComplete keeps the newest complete backup of a location whatever
+	// the rules say, so that pruning never leaves nothing to restore
+	retentionReasonNewestComplete = "newest-complete"
+	retentionReasonNewestPartial  = "newest-partial" // a location with only partial backups
+)
+
+// RetentionPolicy describes which backups to keep. A zero value disables that rule.
+type RetentionPolicy struct {
+	KeepLast int
+	MaxAge   time.Duration
+}
+
+// Validate ensures the policy has at least one usable rule.
+func (p RetentionPolicy) Validate() error {
+	if p.KeepLast < 0 {
+		return fmt.Errorf("keep-last must not be negative")
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("max-age must not be negative")
+	}
+	if p.KeepLast == 0 && p.MaxAge == 0 {
+		return fmt.Errorf("retention policy requires --keep-last and/or --max-age")
+	}
+	return nil
+}
+
+// backupEntry is a backup archive found locally or in S3.
+type backupEntry struct {
+	Location  string
+	Name      string
+	CreatedAt time.Time
+	Size      int64
+	Label     string // only filled in by list
+	KeyID     string // integrity key identifier, only filled in by list
+}
+
+// retentionDecision records whether a backup is kept or deleted, and why.
+type retentionDecision struct {
+	backupEntry
+	Delete bool
+	Reason string
+}
+
+// backupArchiveSuffix returns the archive extension name ends with, .tar.gz or .tar, or "".
+func backupArchiveSuffix(name string) string {
+	for _, suffix := range []string{backupFilenameSuffix, backupFilenameTarSuffix} {
+		if strings.HasSuffix(name, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// trimBackupArchiveSuffix removes the archive extension from name.
+func trimBackupArchiveSuffix(name string) string {
+	return strings.TrimSuffix(name, backupArchiveSuffix(name))
+}
+
+// isBackupArchiveName reports whether base is named like a backup archive, compressed or not.
+func isBackupArchiveName(base string) bool {
+	return strings.HasPrefix(base, backupFilenamePrefix) && backupArchiveSuffix(base) != ""
+}
+
+// parseBackupTimestamp extracts the creation time encoded in a backup filename.
+func parseBackupTimestamp(name string) (time.Time, bool) {
+	base := filepath.Base(name)
+	if !isBackupArchiveName(base) {
+		return time.Time{}, false
+	}
+	stamp := trimBackupArchiveSuffix(strings.TrimPrefix(base, backupFilenamePrefix))
+	stamp = strings.TrimSuffix(stamp, partialBackupMarker)
+	layouts := []struct {
+		format   string
+		location *time.Location
+	}{
+		{backupTimestampUTCFmt, time.UTC},
+		{backupTimestampFmt, time.Local},
+	}
+	for _, layout := range layouts {
+		candidate := stamp
+		// Names that collided with an existing backup carry a "_<n>" suffix
+		if len(candidate) > len(layout.format) && candidate[len(layout.format)] == '_' {
+			if _, err := strconv.Atoi(candidate[len(layout.format)+1:]); err == nil {
+				candidate = candidate[:len(layout.format)]
+			}
+		}
+		if parsed, err := time.ParseInLocation(layout.format, candidate, layout.location); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// planRetention applies the policy to the entries of a single location. Entries are
+// ordered newest first; the newest KeepLast complete backups are eligible to be kept, then
+// MaxAge is applied. Partial backups don't count toward KeepLast: one is kept by count only
+// while fewer than KeepLast complete backups are newer than it. The newest complete backup,
+// or the newest backup when none is complete, is always kept.
+func planRetention(entries []backupEntry, policy RetentionPolicy, now time.Time) []retentionDecision {
+	sorted := append([]backupEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	floor := slices.IndexFunc(sorted, func(entry backupEntry) bool { return !isPartialBackupName(entry.Name) })
+	if floor < 0 {
+		floor = 0
+	}
+
+	decisions := make([]retentionDecision, 0, len(sorted))
+	complete := 0
+	for i, entry := range sorted {
+		decision := retentionDecision{backupEntry: entry, Reason: retentionReasonKept}
+		switch {
+		case i == floor:
+			// No complete backup is newer, so only the age rule could delete it
+			if policy.MaxAge > 0 && !entry.CreatedAt.IsZero() && now.Sub(entry.CreatedAt) > policy.MaxAge {
+				decision.Reason = retentionReasonNewestComplete
+				if isPartialBackupName(entry.Name) {
+					decision.Reason = retentionReasonNewestPartial
+				}
+			}
+		case policy.KeepLast > 0 && complete >= policy.KeepLast:
+			decision.Delete = true
+			decision.Reason = retentionReasonExceedsCount
+		case policy.MaxAge > 0 && entry.CreatedAt.IsZero():
+			decision.Reason = retentionReasonUnknownAge
+		case policy.MaxAge > 0 && now.Sub(entry.CreatedAt) > policy.MaxAge:
+			decision.Delete = true
+			decision.Reason = retentionReasonExceedsAge
+		}
+		if !isPartialBackupName(entry.Name) {
+			complete++
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
+
+// PruneBackups applies the retention policy to local and/or S3 backups on demand.
+func (iops *InfrahubOps) PruneBackups(policy RetentionPolicy, pruneLocal, pruneS3, dryRun bool) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if !pruneLocal && !pruneS3 {
+		return fmt.Errorf("nothing to prune: enable local and/or S3 pruning")
+	}
+
+	now := time.Now()
+	var decisions []retentionDecision
+
+	if pruneLocal {
+		entries, err := iops.listLocalBackups()
+		if err != nil {
+			return err
+		}
+		decisions = append(decisions, planRetention(entries, policy, now)...)
+	}
+
+	ctx := context.Background()
+	var s3Client *s3.Client
+	if pruneS3 {
+		if err := iops.validateS3Config(); err != nil {
+			return err
+		}
+		client, err := iops.createS3Client(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create S3 client: %w", err)
+		}
+		s3Client = client
+		entries, err := iops.listS3Backups(ctx, s3Client)
+		if err != nil {
+			return err
+		}
+		decisions = append(decisions, planRetention(entries, policy, now)...)
+	}
+
+	printRetentionDecisions(decisions, dryRun)
+
+	if dryRun {
+		var count, local, remote int
+		var size int64
+		for _, decision := range decisions {
+			if !decision.Delete {
+				continue
+			}
+			target := decision.Name
+			if decision.Location == backupLocationS3 {
+				target = fmt.Sprintf("s3://%s/%s", iops.config.S3Bucket, decision.Name)
+				remote++
+			} else {
+				target = filepath.Join(iops.config.BackupDir, decision.Name)
+				local++
+			}
+			printDryRunDeletion(target, decision.Size)
+			count++
+			size += decision.Size
+		}
+		fmt.Printf("[dry-run] %d backup(s) (%s) would be deleted: %d local, %d S3; nothing was deleted\n", count, formatBytes(size), local, remote)
+		return nil
+	}
+
+	deleted := 0
+	for _, decision := range decisions {
+		if !decision.Delete {
+			continue
+		}
+		switch decision.Location {
+		case backupLocationLocal:
+			if err := os.Remove(filepath.Join(iops.config.BackupDir, decision.Name)); err != nil {
+				return fmt.Errorf("failed to delete local backup %s: %w", decision.Name, err)
+			}
+		case backupLocationS3:
+			if err := iops.deleteS3Backup(ctx, s3Client, decision.Name); err != nil {
+				return fmt.Errorf("failed to delete S3 backup %s: %w", decision.Name, err)
+			}
+		}
+		deleted++
+	}
+
+	logrus.WithField("deleted", deleted).Info("Backup pruning completed")
+	return nil
+}
+
+// listLocalBackups returns the backup archives stored in BackupDir.
+func (iops *InfrahubOps) listLocalBackups() ([]backupEntry, error) {
+	dirEntries, err := os.ReadDir(iops.config.BackupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	entries := []backupEntry{}
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !isBackupArchiveName(name) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+		createdAt, ok := parseBackupTimestamp(name)
+		if !ok {
+			createdAt = info.ModTime()
+		}
+		entries = append(entries, backupEntry{
+			Location:  backupLocationLocal,
+			Name:      name,
+			CreatedAt: createdAt,
+			Size:      info.Size(),
+		})
+	}
+	return entries, nil
+}
+
+// printDryRunDeletion prints the line that --dry-run shows in place of a deletion.
+func printDryRunDeletion(target string, size int64) {
+	fmt.Printf("[dry-run] would delete %s (%s)\n", target, formatBytes(size))
+}
+
+func printRetentionDecisions(decisions []retentionDecision, dryRun bool) {
+	if len(decisions) == 0 {
+		logrus.Info("No backups found")
+		return
+	}
+
+	deleteLabel := "delete"
+	if dryRun {
+		deleteLabel = "would-delete"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCATION\tBACKUP\tCREATED\tSIZE\tACTION\tREASON")
+	for _, d := range decisions {
+		action := "keep"
+		if d.Delete {
+			action = deleteLabel
+		}
+		created := "-"
+		if !d.CreatedAt.IsZero() {
+			created = d.CreatedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Location, d.Name, created, formatBytes(d.Size), action, d.Reason)
+	}
+	w.Flush()
+}
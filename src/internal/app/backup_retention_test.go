@@ -0,0 +1,170 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestRetentionPolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy  RetentionPolicy
+		wantErr bool
+	}{
+		{policy: RetentionPolicy{KeepLast: 3}},
+		{policy: RetentionPolicy{MaxAge: time.Hour}},
+		{policy: RetentionPolicy{KeepLast: 3, MaxAge: time.Hour}},
+		{policy: RetentionPolicy{}, wantErr: true},
+		{policy: RetentionPolicy{KeepLast: -1}, wantErr: true},
+		{policy: RetentionPolicy{MaxAge: -time.Hour}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, want error %t", tt.policy, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPlanRetention(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	entry := func(name string, age time.Duration) backupEntry {
+		created := time.Time{}
+		if age >= 0 {
+			created = now.Add(-age)
+		}
+		return backupEntry{Location: backupLocationLocal, Name: name, CreatedAt: created}
+	}
+	tests := []struct {
+		name    string
+		entries []backupEntry
+		policy  RetentionPolicy
+		want    map[string]string // name -> reason, "delete:" prefixed when deleted
+	}{
+		{
+			name:    "count",
+			entries: []backupEntry{entry("a.tar.gz", 3*day), entry("b.tar.gz", day), entry("c.tar.gz", 2*day)},
+			policy:  RetentionPolicy{KeepLast: 2},
+			want:    map[string]string{"b.tar.gz": "within-policy", "c.tar.gz": "within-policy", "a.tar.gz": "delete:exceeds-count"},
+		},
+		{
+			name:    "age",
+			entries: []backupEntry{entry("new.tar.gz", day), entry("old.tar.gz", 40*day)},
+			policy:  RetentionPolicy{MaxAge: 30 * day},
+			want:    map[string]string{"new.tar.gz": "within-policy", "old.tar.gz": "delete:exceeds-age"},
+		},
+		{
+			name:    "unknown age",
+			entries: []backupEntry{entry("new.tar.gz", day), entry("custom.tar.gz", -1)},
+			policy:  RetentionPolicy{MaxAge: 30 * day},
+			want:    map[string]string{"new.tar.gz": "within-policy", "custom.tar.gz": "unknown-age"},
+		},
+		{
+			name:    "all expired",
+			entries: []backupEntry{entry("a.tar.gz", 40*day), entry("b.tar.gz", 35*day), entry("c_partial.tar.gz", 32*day)},
+			policy:  RetentionPolicy{MaxAge: 30 * day},
+			want:    map[string]string{"c_partial.tar.gz": "delete:exceeds-age", "b.tar.gz": "newest-complete", "a.tar.gz": "delete:exceeds-age"},
+		},
+		{
+			name:    "only partial backups",
+			entries: []backupEntry{entry("a_partial.tar.gz", 40*day), entry("b_partial.tar.gz", 35*day)},
+			policy:  RetentionPolicy{MaxAge: 30 * day},
+			want:    map[string]string{"b_partial.tar.gz": "newest-partial", "a_partial.tar.gz": "delete:exceeds-age"},
+		},
+		{
+			name: "partial backups don't count toward keep-last",
+			entries: []backupEntry{
+				entry("p3_partial.tar.gz", day), entry("p2_partial.tar.gz", 2*day), entry("p1_partial.tar.gz", 3*day),
+				entry("c2.tar.gz", 4*day), entry("p0_partial.tar.gz", 5*day), entry("c1.tar.gz", 6*day), entry("c0.tar.gz", 7*day),
+			},
+			policy: RetentionPolicy{KeepLast: 2},
+			want: map[string]string{
+				"p3_partial.tar.gz": "within-policy", "p2_partial.tar.gz": "within-policy", "p1_partial.tar.gz": "within-policy",
+				"c2.tar.gz": "within-policy", "p0_partial.tar.gz": "within-policy", "c1.tar.gz": "within-policy",
+				"c0.tar.gz": "delete:exceeds-count",
+			},
+		},
+		{
+			name:    "partial older than the kept complete backups",
+			entries: []backupEntry{entry("c1.tar.gz", day), entry("c0.tar.gz", 2*day), entry("p_partial.tar.gz", 3*day)},
+			policy:  RetentionPolicy{KeepLast: 1},
+			want:    map[string]string{"c1.tar.gz": "within-policy", "c0.tar.gz": "delete:exceeds-count", "p_partial.tar.gz": "delete:exceeds-count"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions := planRetention(tt.entries, tt.policy, now)
+			if len(decisions) != len(tt.want) {
+				t.Fatalf("got %d decisions, want %d", len(decisions), len(tt.want))
+			}
+			for _, decision := range decisions {
+				got := decision.Reason
+				if decision.Delete {
+					got = "delete:" + got
+				}
+				if got != tt.want[decision.Name] {
+					t.Errorf("%s: got %s, want %s", decision.Name, got, tt.want[decision.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	old := time.Now().Add(-90 * 24 * time.Hour).UTC()
+	names := []string{
+		"infrahub_backup_" + old.Format(backupTimestampUTCFmt) + ".tar.gz",
+		"infrahub_backup_" + old.Add(time.Hour).Format(backupTimestampUTCFmt) + ".tar.gz",
+		"infrahub_backup_" + old.Add(2*time.Hour).Format(backupTimestampUTCFmt) + "_partial.tar.gz",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(iops.config.BackupDir, name), []byte("archive"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	policy := RetentionPolicy{MaxAge: 30 * 24 * time.Hour}
+
+	if err := iops.PruneBackups(RetentionPolicy{}, true, false, false); err == nil {
+		t.Error("PruneBackups without a rule succeeded")
+	}
+	if err := iops.PruneBackups(policy, false, false, false); err == nil {
+		t.Error("PruneBackups without a location succeeded")
+	}
+
+	output := captureStdout(t, func() error { return iops.PruneBackups(policy, true, false, true) })
+	if !strings.Contains(output, "2 backup(s)") {
+		t.Errorf("dry-run output = %q, want 2 backups to delete", output)
+	}
+	if left, _ := os.ReadDir(iops.config.BackupDir); len(left) != 3 {
+		t.Errorf("dry-run left %d archives, want all 3", len(left))
+	}
+
+	captureStdout(t, func() error { return iops.PruneBackups(policy, true, false, false) })
+	left, _ := os.ReadDir(iops.config.BackupDir)
+	if len(left) != 1 || left[0].Name() != names[1] {
+		t.Errorf("archives left = %v, want only the newest complete backup %s", left, names[1])
+	}
+}
+
+func TestDeleteLocalAfterUploadDryRun(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	iops.config.DryRun = true
+	iops.config.VerifyS3BeforeDeleteLocal = false
+	archive := filepath.Join(iops.config.BackupDir, "infrahub_backup_20250601T020000Z.tar.gz")
+	if err := os.WriteFile(archive, []byte("archive"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureStdout(t, func() error { return iops.deleteLocalAfterUpload(archive, "infrahub_backup_20250601T020000Z.tar.gz") })
+	if !strings.Contains(output, "[dry-run] would delete "+archive) {
+		t.Errorf("dry-run output = %q, want the archive listed", output)
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Errorf("dry-run deleted the local archive: %v", err)
+	}
+}
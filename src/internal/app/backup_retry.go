@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	operationRetryBaseDelay = 30 * time.Second
+	operationRetryMaxDelay  = 10 * time.Minute
+)
+
+// ErrAuthFailed marks errors caused by rejected credentials, which a retry cannot fix.
+var ErrAuthFailed = errors.New("authentication failed")
+
+// s3AuthErrorCodes are S3 error codes returned for invalid or insufficient credentials.
+var s3AuthErrorCodes = []string{
+	"AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch",
+	"InvalidToken", "ExpiredToken", "InvalidClientTokenId",
+}
+
+// authFailureMarkers are fragments of the messages pg_dump, cypher-shell and neo4j-admin
+// print when a password is rejected.
+var authFailureMarkers = []string{
+	"password authentication failed",
+	"neo.clienterror.security.unauthorized",
+	"the client is unauthorized due to authentication failure",
+}
+
+// CreateBackupWithRetries runs CreateBackup, retrying the whole operation up to
+// --operation-retries times with exponential backoff. Each failed attempt has already
+// restarted the services it stopped and removed its working directory when it returns.
+func (iops *InfrahubOps) CreateBackupWithRetries(force bool, neo4jMetadata string, excludeTaskManager bool) error {
+	return iops.createBackupWithRetries(context.Background(), force, neo4jMetadata, excludeTaskManager)
+}
+
+// createBackupWithRetries stops waiting for the next attempt when ctx is cancelled.
+func (iops *InfrahubOps) createBackupWithRetries(ctx context.Context, force bool, neo4jMetadata string, excludeTaskManager bool) error {
+	attempts := iops.config.OperationRetries + 1
+	delay := operationRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		if attempts > 1 {
+			logrus.WithFields(logrus.Fields{"attempt": attempt, "max_attempts": attempts}).Info("Starting backup attempt")
+		}
+		err := iops.CreateBackup(force, neo4jMetadata, excludeTaskManager)
+		if err == nil || attempt >= attempts {
+			if err != nil && attempts > 1 {
+				return fmt.Errorf("backup failed after %d attempts: %w", attempts, err)
+			}
+			return err
+		}
+		if !isRetryableOperationError(err) {
+			logrus.WithField("attempt", attempt).Errorf("Backup attempt failed with a non-retryable error: %v", err)
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"attempt":      attempt,
+			"max_attempts": attempts,
+			"retry_in":     delay.String(),
+		}).Warnf("Backup attempt failed: %v", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay = min(delay*2, operationRetryMaxDelay)
+	}
+}
+
+// isRetryableOperationError reports whether retrying the operation may succeed. Unmet
+// prerequisites, integrity problems, incompatible editions and rejected credentials fail the
+// same way every time, and a partial backup has already written its archive.
+func isRetryableOperationError(err error) bool {
+	return !errors.Is(err, ErrPrerequisites) &&
+		!errors.Is(err, ErrPartialBackup) &&
+		!errors.Is(err, ErrChecksumMismatch) &&
+		!errors.Is(err, ErrSignatureMismatch) &&
+		!errors.Is(err, ErrEditionMismatch) &&
+		!isAuthFailure(err)
+}
+
+// isAuthFailure recognizes rejected credentials from S3, the Neo4j driver and the
+// database command-line tools.
+func isAuthFailure(err error) bool {
+	if errors.Is(err, ErrAuthFailed) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		for _, code := range s3AuthErrorCodes {
+			if apiErr.ErrorCode() == code {
+				return true
+			}
+		}
+	}
+	var neo4jErr *neo4j.Neo4jError
+	if errors.As(err, &neo4jErr) && strings.HasPrefix(neo4jErr.Code, "Neo.ClientError.Security.") {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, marker := range authFailureMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
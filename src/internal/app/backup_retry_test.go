@@ -0,0 +1,30 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsRetryableOperationError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "transient", err: errors.New("connection reset by peer"), want: true},
+		{name: "prerequisites", err: fmt.Errorf("%w: service database is not running", ErrPrerequisites)},
+		{name: "partial backup", err: fmt.Errorf("backup written: %w", ErrPartialBackup)},
+		{name: "checksum", err: fmt.Errorf("dump: %w", ErrChecksumMismatch)},
+		{name: "signature", err: ErrSignatureMismatch},
+		{name: "edition", err: fmt.Errorf("restore: %w", ErrEditionMismatch)},
+		{name: "auth", err: fmt.Errorf("neo4j: %w", ErrAuthFailed)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableOperationError(tt.err); got != tt.want {
+				t.Errorf("isRetryableOperationError(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
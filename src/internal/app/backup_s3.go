@@ -1,19 +1,41 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/logging"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	defaultS3MaxRetries  = 4
+	defaultS3HTTPTimeout = 60 * time.Second
+)
+
 // uploadBackupToS3 uploads a backup file to S3
 func (iops *InfrahubOps) uploadBackupToS3(backupPath string) error {
 	if !iops.config.S3Upload {
@@ -50,7 +72,7 @@ func (iops *InfrahubOps) uploadBackupToS3(backupPath string) error {
 	}
 
 	filename := filepath.Base(backupPath)
-	key := filename
+	key := iops.s3Key(filename)
 
 	logrus.WithFields(logrus.Fields{
 		"file": filename,
@@ -58,13 +80,46 @@ func (iops *InfrahubOps) uploadBackupToS3(backupPath string) error {
 		"key":  key,
 	}).Info("Starting S3 upload...")
 
-	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:        aws.String(iops.config.S3Bucket),
-		Key:           aws.String(key),
-		Body:          file,
-		ContentLength: aws.Int64(stat.Size()),
-		ContentType:   aws.String("application/gzip"),
-	})
+	contentType, err := iops.archiveContentType(file)
+	if err != nil {
+		return err
+	}
+
+	if iops.config.S3ResumeUpload {
+		// A resumable upload keeps going past the 30 minutes of a single PutObject: a large
+		// archive may need longer, a stalled request still fails after --s3-http-timeout, and an
+		// interrupted upload is continued by the next run
+		if err := iops.uploadResumable(context.WithoutCancel(ctx), s3Client, file, stat, key, contentType); err != nil {
+			return err
+		}
+		logrus.WithFields(logrus.Fields{
+			"bucket": iops.config.S3Bucket,
+			"key":    key,
+			"size":   formatBytes(stat.Size()),
+		}).Info("Backup successfully uploaded to S3")
+		return nil
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:             aws.String(iops.config.S3Bucket),
+		Key:                aws.String(key),
+		Body:               file,
+		ContentLength:      aws.Int64(stat.Size()),
+		ContentType:        aws.String(contentType),
+		ContentDisposition: aws.String(contentDisposition(filename)),
+		Metadata:           s3ObjectMetadata(backupPath),
+	}
+	// S3 rejects the upload if the bytes don't match, and keeps the checksum so that
+	// --verify-s3-before-delete-local doesn't have to download the object again
+	if iops.config.S3DeleteLocal && iops.config.VerifyS3BeforeDeleteLocal {
+		checksum, err := s3ChecksumSHA256(backupPath)
+		if err != nil {
+			return err
+		}
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmSha256
+		input.ChecksumSHA256 = aws.String(checksum)
+	}
+	_, err = s3Client.PutObject(ctx, input)
 
 	if err != nil {
 		return fmt.Errorf("failed to upload to S3: %w", err)
@@ -79,69 +134,371 @@ func (iops *InfrahubOps) uploadBackupToS3(backupPath string) error {
 	return nil
 }
 
+// downloadBackupFromS3 downloads a backup archive from the configured bucket
+func (iops *InfrahubOps) downloadBackupFromS3(key, destPath string) error {
+	if err := iops.validateS3Config(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	s3Client, err := iops.createS3Client(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"bucket": iops.config.S3Bucket,
+		"key":    key,
+	}).Info("Downloading backup from S3...")
+
+	output, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(iops.config.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download from S3: %w", err)
+	}
+	defer output.Body.Close()
+
+	file, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", destPath, err)
+	}
+	defer file.Close()
+
+	written, err := io.Copy(file, output.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write downloaded backup: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path": destPath,
+		"size": formatBytes(written),
+	}).Info("Backup downloaded from S3")
+	return nil
+}
+
+// listS3Backups returns the backup archives stored in the configured bucket, both directly
+// under the prefix and in year=/month=/day= partitions written with --s3-date-partition.
+func (iops *InfrahubOps) listS3Backups(ctx context.Context, client *s3.Client) ([]backupEntry, error) {
+	entries := []backupEntry{}
+	for _, prefix := range []string{iops.config.S3Prefix + backupFilenamePrefix, iops.config.S3Prefix + "year="} {
+		paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(iops.config.S3Bucket),
+			Prefix: aws.String(prefix),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list S3 backups: %w", err)
+			}
+			for _, object := range page.Contents {
+				key := aws.ToString(object.Key)
+				if !isS3BackupKey(strings.TrimPrefix(key, iops.config.S3Prefix)) {
+					continue
+				}
+				createdAt, ok := parseBackupTimestamp(key)
+				if !ok {
+					createdAt = aws.ToTime(object.LastModified)
+				}
+				entries = append(entries, backupEntry{
+					Location:  backupLocationS3,
+					Name:      key,
+					CreatedAt: createdAt,
+					Size:      aws.ToInt64(object.Size),
+				})
+			}
+		}
+	}
+	return entries, nil
+}
+
+// s3DatePartitionPattern matches the partition directories written by --s3-date-partition.
+var s3DatePartitionPattern = regexp.MustCompile(`^year=\d{4}/month=\d{2}/day=\d{2}/$`)
+
+// isS3BackupKey reports whether a key relative to --s3-prefix names a backup archive, either
+// flat or inside a date partition. Keys in other sub-folders, such as the per-namespace
+// folders of a multi-namespace backup, belong to another prefix and are skipped.
+func isS3BackupKey(relative string) bool {
+	dir, base := path.Split(relative)
+	if !isBackupArchiveName(base) {
+		return false
+	}
+	return dir == "" || s3DatePartitionPattern.MatchString(dir)
+}
+
+// archiveContentType returns --s3-content-type, or a type detected from the leading bytes of
+// the archive so the object stays correct whatever compression or container format is used.
+func (iops *InfrahubOps) archiveContentType(file *os.File) (string, error) {
+	if iops.config.S3ContentType != "" {
+		return iops.config.S3ContentType, nil
+	}
+	header := make([]byte, 512)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read backup file: %w", err)
+	}
+	return detectArchiveContentType(header[:n]), nil
+}
+
+// detectArchiveContentType identifies gzip, zstd and plain tar archives by their magic numbers.
+func detectArchiveContentType(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return "application/gzip"
+	case bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "application/zstd"
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return "application/x-tar"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// contentDisposition makes browsers and download tools save the object under its archive name.
+func contentDisposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
+
+// normalizeS3Prefix turns a folder-like prefix such as "/prod/infrahub" into "prod/infrahub/".
+func normalizeS3Prefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// s3Key returns the object key of a backup archive, under --s3-prefix when set. With
+// --s3-date-partition, the date of the backup timestamp is inserted as year=/month=/day=.
+func (iops *InfrahubOps) s3Key(filename string) string {
+	if !iops.config.S3DatePartition {
+		return iops.config.S3Prefix + filename
+	}
+	timestamp, ok := parseBackupTimestamp(filename)
+	if !ok {
+		timestamp = iops.backupTime()
+	}
+	return iops.config.S3Prefix + timestamp.Format("year=2006/month=01/day=02/") + filename
+}
+
+// deleteS3Backup removes a backup archive from the configured bucket
+func (iops *InfrahubOps) deleteS3Backup(ctx context.Context, client *s3.Client, key string) error {
+	_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(iops.config.S3Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// s3BackupExists reports whether an object with the given key exists in the bucket
+func (iops *InfrahubOps) s3BackupExists(ctx context.Context, client *s3.Client, key string) (bool, error) {
+	_, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(iops.config.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err == nil {
+		return true, nil
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NotFound" || apiErr.ErrorCode() == "NoSuchKey") {
+		return false, nil
+	}
+	return false, err
+}
+
 // validateS3Config validates that all required S3 configuration is present
 func (iops *InfrahubOps) validateS3Config() error {
+	if err := iops.validateS3MaxRetries(); err != nil {
+		return err
+	}
 	if iops.config.S3Bucket == "" {
 		return fmt.Errorf("S3 bucket not configured (set S3_BUCKET environment variable)")
 	}
+	for _, shared := range []struct{ flag, file string }{
+		{"--aws-shared-credentials-file", iops.config.S3SharedCredentialsFile},
+		{"--aws-config-file", iops.config.S3SharedConfigFile},
+	} {
+		if shared.file == "" {
+			continue
+		}
+		if _, err := os.Stat(shared.file); err != nil {
+			return fmt.Errorf("%s: %w", shared.flag, err)
+		}
+	}
+	if iops.config.S3AccessKeyID == "" && iops.usesSharedAWSFiles() {
+		// Credentials come from the shared files through the default credential chain
+		return nil
+	}
 	if iops.config.S3AccessKeyID == "" {
-		return fmt.Errorf("S3 access key ID not configured (set S3_ACCESS_KEY_ID environment variable)")
+		return fmt.Errorf("S3 access key ID not configured (set S3_ACCESS_KEY_ID environment variable, or --aws-shared-credentials-file)")
+	}
+	if iops.config.S3SecretFile != "" {
+		secret, err := readSecretFile(iops.config.S3SecretFile)
+		if err != nil {
+			return fmt.Errorf("failed to read S3 secret file: %w", err)
+		}
+		iops.config.S3SecretKey = secret
 	}
 	if iops.config.S3SecretKey == "" {
-		return fmt.Errorf("S3 secret key not configured (set S3_SECRET_ACCESS_KEY environment variable)")
+		return fmt.Errorf("S3 secret key not configured (set S3_SECRET_ACCESS_KEY or S3_SECRET_ACCESS_KEY_FILE environment variable)")
 	}
 	return nil
 }
 
+// validateS3MaxRetries rejects a negative --s3-max-retries, which the SDK would not treat as
+// "no retries".
+func (iops *InfrahubOps) validateS3MaxRetries() error {
+	if iops.config.S3MaxRetries < 0 {
+		return fmt.Errorf("%w: --s3-max-retries must be 0 or more, got %d", ErrPrerequisites, iops.config.S3MaxRetries)
+	}
+	return nil
+}
+
+// usesSharedAWSFiles reports whether S3 credentials may come from --aws-shared-credentials-file
+// or --aws-config-file.
+func (iops *InfrahubOps) usesSharedAWSFiles() bool {
+	return iops.config.S3SharedCredentialsFile != "" || iops.config.S3SharedConfigFile != ""
+}
+
 // createS3Client creates an S3 client with the configured credentials
 func (iops *InfrahubOps) createS3Client(ctx context.Context) (*s3.Client, error) {
-	// Configure environment variables for S3-compatible services (non-AWS endpoints)
-	if iops.config.S3Endpoint != "" {
-		iops.configureS3CompatibilityMode()
+	httpClient, err := iops.s3HTTPClient()
+	if err != nil {
+		return nil, err
 	}
 
-	credProvider := credentials.NewStaticCredentialsProvider(
-		iops.config.S3AccessKeyID,
-		iops.config.S3SecretKey,
-		"",
-	)
-
-	cfg, err := config.LoadDefaultConfig(ctx,
+	maxAttempts := iops.config.S3MaxRetries + 1
+	loadOptions := []func(*config.LoadOptions) error{
 		config.WithRegion(iops.config.S3Region),
-		config.WithCredentialsProvider(credProvider),
-	)
+		config.WithHTTPClient(httpClient),
+		config.WithRetryer(func() aws.Retryer {
+			return retry.AddWithMaxAttempts(retry.NewStandard(func(o *retry.StandardOptions) {
+				if iops.config.S3SigningRegion == "" {
+					o.Retryables = append([]retry.IsErrorRetryable{iops.s3BucketRegionRetryable()}, o.Retryables...)
+				}
+			}), maxAttempts)
+		}),
+		config.WithClientLogMode(aws.LogRetries),
+		config.WithLogger(logging.LoggerFunc(func(classification logging.Classification, format string, v ...interface{}) {
+			logrus.Warnf("S3: "+format, v...)
+		})),
+	}
+	if iops.config.S3AccessKeyID != "" {
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			iops.config.S3AccessKeyID,
+			iops.config.S3SecretKey,
+			"",
+		)))
+	}
+	// Passed as load options rather than AWS_* variables so the process environment is unchanged
+	if iops.config.S3SharedCredentialsFile != "" {
+		loadOptions = append(loadOptions, config.WithSharedCredentialsFiles([]string{iops.config.S3SharedCredentialsFile}))
+	}
+	if iops.config.S3SharedConfigFile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigFiles([]string{iops.config.S3SharedConfigFile}))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
 	if err != nil {
 		return nil, err
 	}
 
 	var options []func(*s3.Options)
 	if iops.config.S3Endpoint != "" {
-		options = append(options, func(o *s3.Options) {
-			o.BaseEndpoint = aws.String(iops.config.S3Endpoint)
-			o.UsePathStyle = true // Required for MinIO and some S3-compatible services
-		})
+		options = append(options, s3CompatibilityOptions(iops.config.S3Endpoint))
+	}
+	if iops.config.S3SigningRegion != "" {
+		options = append(options, s3.WithSigV4SigningRegion(iops.config.S3SigningRegion))
+	} else {
+		options = append(options, iops.s3BucketRegionOption())
 	}
-
 	return s3.NewFromConfig(cfg, options...), nil
 }
 
-// configureS3CompatibilityMode sets environment variables for S3-compatible services
-func (iops *InfrahubOps) configureS3CompatibilityMode() {
-	logrus.Debug("Configuring S3 compatibility mode for non-AWS endpoint")
+// s3CompatibilityOptions configures a client for an S3-compatible service (non-AWS endpoint)
+// such as MinIO or Ceph. The settings apply to that client only, not to the process environment.
+func s3CompatibilityOptions(endpoint string) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+		o.UsePathStyle = true // Required for MinIO and some S3-compatible services
+		// Disable features not supported by all S3-compatible services: checksums and
+		// content-MD5 are only sent and validated when an operation requires them
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		o.UseARNRegion = true
+		o.DisableMultiRegionAccessPoints = true
+	}
+}
 
-	// Disable features not supported by all S3-compatible services
-	envVars := map[string]string{
-		"AWS_S3_DISABLE_CONTENT_MD5_VALIDATION":       "true",
-		"AWS_S3_DISABLE_MULTIREGION_ACCESS_POINTS":    "true",
-		"AWS_S3_US_EAST_1_REGIONAL_ENDPOINT":          "regional",
-		"AWS_S3_USE_ARN_REGION":                       "true",
-		"AWS_REQUEST_CHECKSUM_CALCULATION":            "when_required",
-		"AWS_RESPONSE_CHECKSUM_VALIDATION":            "when_required",
+// s3HTTPClient builds the HTTP client used by the S3 SDK. An explicit --s3-proxy is used
+// for every S3 request; otherwise HTTPS_PROXY/HTTP_PROXY/NO_PROXY are honored, with
+// NO_PROXY matched against the S3 endpoint host.
+func (iops *InfrahubOps) s3HTTPClient() (*awshttp.BuildableClient, error) {
+	tlsConfig, err := iops.s3TLSConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	for key, value := range envVars {
-		if err := os.Setenv(key, value); err != nil {
-			logrus.Warnf("Failed to set %s: %v", key, err)
+	proxy := http.ProxyFromEnvironment
+	if iops.config.S3Proxy != "" {
+		proxyURL, err := url.Parse(iops.config.S3Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid S3 proxy URL %q", iops.config.S3Proxy)
 		}
+		logrus.WithField("proxy", proxyURL.Redacted()).Debug("Using explicit proxy for S3")
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	// Bound connection setup and the wait for response headers, but not the request as a
+	// whole: a large PutObject body can legitimately take longer than S3HTTPTimeout. The
+	// caller's context still caps the overall operation.
+	timeout := iops.config.S3HTTPTimeout
+	client := awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		tr.Proxy = proxy
+		if tlsConfig != nil {
+			tr.TLSClientConfig = tlsConfig
+		}
+		if timeout > 0 {
+			tr.TLSHandshakeTimeout = timeout
+			tr.ResponseHeaderTimeout = timeout
+		}
+	})
+	if timeout > 0 {
+		client = client.WithDialerOptions(func(d *net.Dialer) {
+			d.Timeout = timeout
+		})
+	}
+	return client, nil
+}
+
+// s3TLSConfig returns the TLS settings for --s3-ca-bundle and --s3-insecure-skip-verify,
+// or nil to keep the default verification against the system roots.
+func (iops *InfrahubOps) s3TLSConfig() (*tls.Config, error) {
+	if iops.config.S3InsecureSkipVerify {
+		if iops.config.S3CABundle != "" {
+			return nil, fmt.Errorf("--s3-ca-bundle and --s3-insecure-skip-verify cannot be combined")
+		}
+		logrus.Warn("TLS CERTIFICATE VERIFICATION IS DISABLED for S3 (--s3-insecure-skip-verify): credentials and backups can be intercepted. Use --s3-ca-bundle outside of development and testing")
+		return &tls.Config{InsecureSkipVerify: true}, nil // #nosec G402 -- explicitly requested
+	}
+	if iops.config.S3CABundle == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(iops.config.S3CABundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read S3 CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		logrus.Debugf("System certificate pool unavailable, trusting only %s: %v", iops.config.S3CABundle, err)
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("S3 CA bundle %s contains no PEM certificates", iops.config.S3CABundle)
 	}
+	logrus.WithField("ca_bundle", iops.config.S3CABundle).Debug("Trusting custom CA certificates for S3")
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
 }
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/sirupsen/logrus"
+)
+
+// CleanupMultipartUploads aborts the incomplete multipart uploads under the S3 prefix that were
+// started more than olderThan ago (s3 cleanup-multipart). Younger uploads are left alone, since
+// a backup running elsewhere may still be writing them, and an upload that disappears between
+// the listing and the abort is treated as already cleaned up.
+func (iops *InfrahubOps) CleanupMultipartUploads(olderThan time.Duration, dryRun bool) error {
+	if olderThan <= 0 {
+		return fmt.Errorf("--older-than must be positive so that uploads in progress are not aborted")
+	}
+	if err := iops.validateS3Config(); err != nil {
+		return err
+	}
+	if iops.config.S3ResumeMaxAge > olderThan {
+		logrus.Warnf("--older-than %s is shorter than --resume-max-age %s; uploads that could still be resumed may be aborted", olderThan, iops.config.S3ResumeMaxAge)
+	}
+
+	ctx := context.Background()
+	client, err := iops.createS3Client(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	uploads, err := iops.listMultipartUploads(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if len(uploads) == 0 {
+		logrus.Infof("No incomplete multipart uploads under s3://%s/%s", iops.config.S3Bucket, iops.config.S3Prefix)
+		return nil
+	}
+
+	now := time.Now()
+	var stale []types.MultipartUpload
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tUPLOAD ID\tINITIATED\tACTION")
+	for _, upload := range uploads {
+		initiated := aws.ToTime(upload.Initiated)
+		action := "keep"
+		if now.Sub(initiated) > olderThan {
+			stale = append(stale, upload)
+			action = "abort"
+			if dryRun {
+				action = "would-abort"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", aws.ToString(upload.Key), aws.ToString(upload.UploadId), initiated.Format(time.RFC3339), action)
+	}
+	w.Flush()
+
+	if dryRun {
+		for _, upload := range stale {
+			fmt.Printf("[dry-run] would abort s3://%s/%s (upload %s)\n", iops.config.S3Bucket, aws.ToString(upload.Key), aws.ToString(upload.UploadId))
+		}
+		fmt.Printf("[dry-run] %d of %d incomplete multipart uploads would be aborted; nothing was aborted\n", len(stale), len(uploads))
+		return nil
+	}
+
+	aborted := 0
+	for _, upload := range stale {
+		_, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(iops.config.S3Bucket),
+			Key:      upload.Key,
+			UploadId: upload.UploadId,
+		})
+		if err != nil {
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchUpload" {
+				logrus.Debugf("Multipart upload %s of %s already completed or aborted", aws.ToString(upload.UploadId), aws.ToString(upload.Key))
+				continue
+			}
+			return fmt.Errorf("failed to abort multipart upload of %s: %w", aws.ToString(upload.Key), err)
+		}
+		aborted++
+	}
+	logrus.Infof("Aborted %d of %d incomplete multipart uploads", aborted, len(uploads))
+	return nil
+}
+
+// listMultipartUploads returns the incomplete multipart uploads under the S3 prefix.
+func (iops *InfrahubOps) listMultipartUploads(ctx context.Context, client *s3.Client) ([]types.MultipartUpload, error) {
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(iops.config.S3Bucket),
+		Prefix: aws.String(iops.config.S3Prefix),
+	}
+	var uploads []types.MultipartUpload
+	for {
+		output, err := client.ListMultipartUploads(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+		}
+		uploads = append(uploads, output.Uploads...)
+		if !aws.ToBool(output.IsTruncated) {
+			return uploads, nil
+		}
+		input.KeyMarker = output.NextKeyMarker
+		input.UploadIdMarker = output.NextUploadIdMarker
+	}
+}
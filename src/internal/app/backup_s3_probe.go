@@ -0,0 +1,116 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// s3ProbeCheck is one operation tried by s3 probe.
+type s3ProbeCheck struct {
+	Operation string
+	NeededFor string
+	Err       error
+	Skipped   string
+}
+
+// ProbeS3 checks that the configured bucket is reachable and that the credentials allow every
+// operation the tool relies on, using a small object under the S3 prefix (s3 probe). It prints
+// one line per operation and fails when any of them is denied or fails.
+func (iops *InfrahubOps) ProbeS3() error {
+	if err := iops.validateS3Config(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	client, err := iops.createS3Client(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	bucket := aws.String(iops.config.S3Bucket)
+	key := iops.config.S3Prefix + ".infrahub_backup_probe_" + time.Now().UTC().Format(backupTimestampUTCFmt)
+	payload := []byte("infrahub-backup connectivity probe\n")
+	logrus.WithField("key", key).Infof("Probing s3://%s", iops.config.S3Bucket)
+
+	checks := []*s3ProbeCheck{
+		{Operation: "HeadBucket", NeededFor: "reaching the bucket"},
+		{Operation: "PutObject", NeededFor: "create --s3-upload, upload"},
+		{Operation: "GetObject", NeededFor: "restore from S3, diff"},
+		{Operation: "ListObjectsV2", NeededFor: "list --s3, prune --s3, restore --latest --s3"},
+		{Operation: "DeleteObject", NeededFor: "prune --s3"},
+	}
+	head, put, get, list, del := checks[0], checks[1], checks[2], checks[3], checks[4]
+
+	_, head.Err = client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: bucket})
+
+	_, put.Err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        bucket,
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(payload),
+		ContentLength: aws.Int64(int64(len(payload))),
+	})
+
+	if put.Err != nil {
+		get.Skipped = "PutObject failed"
+	} else if output, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: bucket, Key: aws.String(key)}); err != nil {
+		get.Err = err
+	} else {
+		body, err := io.ReadAll(output.Body)
+		output.Body.Close()
+		if err == nil && !bytes.Equal(body, payload) {
+			err = fmt.Errorf("read back %d bytes that differ from the %d bytes written", len(body), len(payload))
+		}
+		get.Err = err
+	}
+
+	_, list.Err = client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  bucket,
+		Prefix:  aws.String(iops.config.S3Prefix),
+		MaxKeys: aws.Int32(1),
+	})
+
+	if put.Err != nil {
+		del.Skipped = "PutObject failed"
+	} else if del.Err = iops.deleteS3Backup(ctx, client, key); del.Err != nil {
+		logrus.Warnf("The probe object s3://%s/%s could not be deleted; remove it manually", iops.config.S3Bucket, key)
+	}
+
+	failed := []string{}
+	authFailure := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tNEEDED FOR\tRESULT")
+	for _, check := range checks {
+		result := "ok"
+		switch {
+		case check.Skipped != "":
+			result = "skipped: " + check.Skipped
+			failed = append(failed, check.Operation)
+		case check.Err != nil:
+			message, _, _ := strings.Cut(check.Err.Error(), "\n")
+			result = "FAILED: " + message
+			failed = append(failed, check.Operation)
+			authFailure = authFailure || isAuthFailure(check.Err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.Operation, check.NeededFor, result)
+	}
+	w.Flush()
+
+	if len(failed) == 0 {
+		fmt.Printf("S3 bucket %s is reachable and all required operations are allowed\n", iops.config.S3Bucket)
+		return nil
+	}
+	err = fmt.Errorf("S3 probe failed for %s", strings.Join(failed, ", "))
+	if authFailure {
+		return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProbeS3(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = body
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte("<ListBucketResult><Name>backups</Name><KeyCount>1</KeyCount></ListBucketResult>"))
+		case r.Method == http.MethodGet:
+			w.Write(objects[r.URL.Path])
+		case r.Method == http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	iops := newRegionTestOps(t, server.URL)
+
+	output := captureStdout(t, iops.ProbeS3)
+	_, deleteLine, _ := strings.Cut(output, "\nDeleteObject")
+	deleteLine, _, _ = strings.Cut(deleteLine, "\n")
+	if !strings.Contains(deleteLine, "prune --s3") || strings.Contains(deleteLine, "schedule") {
+		t.Errorf("DeleteObject line = %q, want it needed for prune --s3 only", deleteLine)
+	}
+	if !strings.Contains(output, "all required operations are allowed") {
+		t.Errorf("probe output = %q, want every operation allowed", output)
+	}
+	if len(objects) != 0 {
+		t.Errorf("objects left in the bucket: %d, want the probe object deleted", len(objects))
+	}
+}
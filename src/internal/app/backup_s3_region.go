@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithy "github.com/aws/smithy-go"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+	"github.com/sirupsen/logrus"
+)
+
+// s3BucketRegionOption resolves endpoints, and so the signing region, for the bucket region
+// S3 reported once a request failed, instead of S3_REGION.
+func (iops *InfrahubOps) s3BucketRegionOption() func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.EndpointResolverV2 = s3BucketRegionResolver{base: o.EndpointResolverV2, iops: iops}
+	}
+}
+
+type s3BucketRegionResolver struct {
+	base s3.EndpointResolverV2
+	iops *InfrahubOps
+}
+
+func (r s3BucketRegionResolver) ResolveEndpoint(ctx context.Context, params s3.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	if region := r.iops.s3BucketRegion.Load(); region != nil {
+		params.Region = aws.String(*region)
+	}
+	return r.base.ResolveEndpoint(ctx, params)
+}
+
+// s3BucketRegionRetryable retries a request that failed because S3_REGION is wrong. S3 answers
+// a bucket in another region with a redirect or a signature error, and sends the real region in
+// the x-amz-bucket-region header, even when access is denied. The region is kept for the rest of
+// the run, so the retry and every later request go to it.
+func (iops *InfrahubOps) s3BucketRegionRetryable() retry.IsErrorRetryable {
+	return retry.IsErrorRetryableFunc(func(err error) aws.Ternary {
+		region := s3ErrorBucketRegion(err)
+		current := iops.config.S3Region
+		if detected := iops.s3BucketRegion.Load(); detected != nil {
+			current = *detected
+		}
+		if region == "" || region == current {
+			return aws.UnknownTernary
+		}
+		if previous := iops.s3BucketRegion.Swap(&region); previous == nil || *previous != region {
+			logrus.Warnf("S3 bucket %s is in region %s, not %s; using %s; set S3_REGION=%s", iops.config.S3Bucket, region, current, region, region)
+		}
+		return aws.TrueTernary
+	})
+}
+
+// s3ErrorBucketRegion returns the bucket region S3 reported with a redirect or a signature
+// error, or "" for any other error.
+func s3ErrorBucketRegion(err error) string {
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.Response == nil {
+		return ""
+	}
+	region := respErr.Response.Header.Get("X-Amz-Bucket-Region")
+	if region == "" {
+		return ""
+	}
+	switch respErr.Response.StatusCode {
+	// HEAD responses have no body, so a wrong region is a bare 400 Bad Request there
+	case http.StatusMovedPermanently, http.StatusTemporaryRedirect, http.StatusBadRequest:
+		return region
+	case http.StatusForbidden:
+		// Access denied also names the region; only a signature error is about it
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "SignatureDoesNotMatch" {
+			return region
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+// regionTestServer answers requests not signed for region with the signature error S3 returns
+// for a bucket in another region, and records the signing region of each request.
+func regionTestServer(t *testing.T, region string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var signed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, scope, _ := strings.Cut(r.Header.Get("Authorization"), "Credential=")
+		parts := strings.Split(scope, "/")
+		requestRegion := ""
+		if len(parts) > 2 {
+			requestRegion = parts[2]
+		}
+		mu.Lock()
+		signed = append(signed, requestRegion)
+		mu.Unlock()
+
+		if requestRegion != region {
+			w.Header().Set("X-Amz-Bucket-Region", region)
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("<Error><Code>AuthorizationHeaderMalformed</Code><Message>wrong region</Message></Error>"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), signed...)
+	}
+}
+
+func newRegionTestOps(t *testing.T, endpoint string) *InfrahubOps {
+	t.Helper()
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		t.Setenv(name, "")
+	}
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	iops.config.S3Endpoint = endpoint
+	iops.config.S3Bucket = "backups"
+	iops.config.S3Region = "us-east-1"
+	iops.config.S3AccessKeyID = "minio"
+	iops.config.S3SecretKey = "minio-secret"
+	return iops
+}
+
+func TestS3BucketRegionRetry(t *testing.T) {
+	server, signed := regionTestServer(t, "eu-west-1")
+	iops := newRegionTestOps(t, server.URL)
+
+	ctx := context.Background()
+	client, err := iops.createS3Client(ctx)
+	if err != nil {
+		t.Fatalf("createS3Client: %v", err)
+	}
+	if _, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("backups"), Key: aws.String("a")}); err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if got := strings.Join(signed(), ","); got != "us-east-1,eu-west-1" {
+		t.Errorf("signing regions = %s, want one failed request then the bucket region", got)
+	}
+
+	// A later client of the same run starts with the reported region
+	client, err = iops.createS3Client(ctx)
+	if err != nil {
+		t.Fatalf("createS3Client: %v", err)
+	}
+	if _, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("backups"), Key: aws.String("b")}); err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if got := signed(); len(got) != 3 || got[2] != "eu-west-1" {
+		t.Errorf("signing regions = %v, want the bucket region without a failed request", got)
+	}
+}
+
+func TestS3SigningRegion(t *testing.T) {
+	server, signed := regionTestServer(t, "eu-west-1")
+	iops := newRegionTestOps(t, server.URL)
+	iops.config.S3SigningRegion = "eu-west-1"
+
+	ctx := context.Background()
+	client, err := iops.createS3Client(ctx)
+	if err != nil {
+		t.Fatalf("createS3Client: %v", err)
+	}
+	if _, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("backups"), Key: aws.String("a")}); err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if got := strings.Join(signed(), ","); got != "eu-west-1" {
+		t.Errorf("signing regions = %s, want only the signing region", got)
+	}
+	if client.Options().Region != "us-east-1" {
+		t.Errorf("client region = %s, want S3_REGION", client.Options().Region)
+	}
+}
@@ -0,0 +1,260 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	s3StateSuffix          = ".s3state"
+	defaultS3ResumeMaxAge  = 24 * time.Hour
+	minS3PartSize          = 64 * 1024 * 1024
+	maxS3Parts             = 10000
+	s3StateMetadataVersion = 1
+)
+
+// s3UploadState is the checkpoint of a multipart upload, stored next to the archive.
+type s3UploadState struct {
+	Version   int           `json:"version"`
+	Bucket    string        `json:"bucket"`
+	Key       string        `json:"key"`
+	UploadID  string        `json:"upload_id"`
+	PartSize  int64         `json:"part_size"`
+	FileSize  int64         `json:"file_size"`
+	FileMtime time.Time     `json:"file_mtime"`
+	StartedAt time.Time     `json:"started_at"`
+	Parts     []s3StatePart `json:"parts"`
+}
+
+type s3StatePart struct {
+	Number int32  `json:"number"`
+	ETag   string `json:"etag"`
+}
+
+// UploadBackup uploads an existing archive to S3, e.g. to finish an upload that failed
+// after the backup itself was created.
+func (iops *InfrahubOps) UploadBackup(backupPath string) error {
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("backup file not found: %w", err)
+	}
+	iops.config.S3Upload = true
+	if err := iops.validateS3DeleteLocal(); err != nil {
+		return err
+	}
+	if err := iops.uploadBackupToS3(backupPath); err != nil {
+		return err
+	}
+	if iops.config.S3DeleteLocal {
+		return iops.deleteLocalAfterUpload(backupPath, iops.s3Key(filepath.Base(backupPath)))
+	}
+	return nil
+}
+
+// uploadResumable uploads the archive in parts, checkpointing each completed part to
+// <archive>.s3state so a later run resumes the same multipart upload.
+func (iops *InfrahubOps) uploadResumable(ctx context.Context, client *s3.Client, file *os.File, stat os.FileInfo, key, contentType string) error {
+	statePath := file.Name() + s3StateSuffix
+	state, err := iops.loadResumableState(ctx, client, statePath, stat, key)
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		output, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:             aws.String(iops.config.S3Bucket),
+			Key:                aws.String(key),
+			ContentType:        aws.String(contentType),
+			ContentDisposition: aws.String(contentDisposition(filepath.Base(file.Name()))),
+			Metadata:           s3ObjectMetadata(file.Name()),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start multipart upload: %w", err)
+		}
+		state = &s3UploadState{
+			Version:   s3StateMetadataVersion,
+			Bucket:    iops.config.S3Bucket,
+			Key:       key,
+			UploadID:  aws.ToString(output.UploadId),
+			PartSize:  s3PartSize(stat.Size()),
+			FileSize:  stat.Size(),
+			FileMtime: stat.ModTime().UTC(),
+			StartedAt: time.Now().UTC(),
+		}
+		if err := saveS3UploadState(statePath, state); err != nil {
+			return err
+		}
+	}
+
+	totalParts := int32((state.FileSize + state.PartSize - 1) / state.PartSize)
+	if totalParts == 0 {
+		totalParts = 1
+	}
+	done := map[int32]bool{}
+	for _, part := range state.Parts {
+		done[part.Number] = true
+	}
+
+	for number := int32(1); number <= totalParts; number++ {
+		if done[number] {
+			continue
+		}
+		offset := int64(number-1) * state.PartSize
+		size := min(state.PartSize, state.FileSize-offset)
+		output, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(state.Bucket),
+			Key:           aws.String(state.Key),
+			UploadId:      aws.String(state.UploadID),
+			PartNumber:    aws.Int32(number),
+			Body:          io.NewSectionReader(file, offset, size),
+			ContentLength: aws.Int64(size),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d of %d (re-run with --resume-upload to continue): %w", number, totalParts, err)
+		}
+		state.Parts = append(state.Parts, s3StatePart{Number: number, ETag: aws.ToString(output.ETag)})
+		if err := saveS3UploadState(statePath, state); err != nil {
+			return err
+		}
+		logrus.Infof("Uploaded part %d/%d", number, totalParts)
+	}
+
+	completed := make([]types.CompletedPart, 0, len(state.Parts))
+	for number := int32(1); number <= totalParts; number++ {
+		for _, part := range state.Parts {
+			if part.Number == number {
+				completed = append(completed, types.CompletedPart{PartNumber: aws.Int32(number), ETag: aws.String(part.ETag)})
+				break
+			}
+		}
+	}
+	if _, err := client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(state.Bucket),
+		Key:             aws.String(state.Key),
+		UploadId:        aws.String(state.UploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	if err := os.Remove(statePath); err != nil {
+		logrus.Warnf("Failed to remove upload state %s: %v", statePath, err)
+	}
+	return nil
+}
+
+// loadResumableState returns the checkpoint to resume, or nil to start a new upload. A
+// checkpoint for a different target or archive, or one older than --resume-max-age, is
+// discarded and its multipart upload aborted.
+func (iops *InfrahubOps) loadResumableState(ctx context.Context, client *s3.Client, statePath string, stat os.FileInfo, key string) (*s3UploadState, error) {
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload state: %w", err)
+	}
+
+	var state s3UploadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		logrus.Warnf("Ignoring unreadable upload state %s: %v", statePath, err)
+		return nil, nil
+	}
+
+	reason := ""
+	switch {
+	case state.Version != s3StateMetadataVersion:
+		reason = "unsupported state version"
+	case state.Bucket != iops.config.S3Bucket || state.Key != key:
+		reason = "state is for a different bucket or key"
+	case state.FileSize != stat.Size() || !state.FileMtime.Equal(stat.ModTime().UTC()):
+		reason = "archive changed since the upload started"
+	case iops.config.S3ResumeMaxAge > 0 && time.Since(state.StartedAt) > iops.config.S3ResumeMaxAge:
+		reason = fmt.Sprintf("upload started more than %s ago", iops.config.S3ResumeMaxAge)
+	}
+	if reason == "" {
+		parts, err := iops.listUploadedParts(ctx, client, &state)
+		if err != nil {
+			var apiErr smithy.APIError
+			if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "NoSuchUpload" {
+				return nil, fmt.Errorf("failed to list uploaded parts: %w", err)
+			}
+			logrus.Warn("Previous multipart upload no longer exists; starting over")
+			return nil, nil
+		}
+		state.Parts = parts
+		logrus.WithFields(logrus.Fields{
+			"upload_id":      state.UploadID,
+			"parts_uploaded": len(state.Parts),
+		}).Info("Resuming multipart upload")
+		return &state, nil
+	}
+
+	logrus.Warnf("Discarding previous upload state: %s", reason)
+	if state.UploadID != "" && state.Bucket != "" {
+		if _, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(state.Bucket),
+			Key:      aws.String(state.Key),
+			UploadId: aws.String(state.UploadID),
+		}); err != nil {
+			logrus.Warnf("Failed to abort previous multipart upload %s: %v", state.UploadID, err)
+		}
+	}
+	return nil, nil
+}
+
+// listUploadedParts returns the parts S3 holds for the upload, which is authoritative over
+// the checkpoint in case the last save was lost.
+func (iops *InfrahubOps) listUploadedParts(ctx context.Context, client *s3.Client, state *s3UploadState) ([]s3StatePart, error) {
+	var parts []s3StatePart
+	paginator := s3.NewListPartsPaginator(client, &s3.ListPartsInput{
+		Bucket:   aws.String(state.Bucket),
+		Key:      aws.String(state.Key),
+		UploadId: aws.String(state.UploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range page.Parts {
+			parts = append(parts, s3StatePart{Number: aws.ToInt32(part.PartNumber), ETag: aws.ToString(part.ETag)})
+		}
+	}
+	return parts, nil
+}
+
+// s3PartSize keeps the part count within the S3 limit of 10000 parts.
+func s3PartSize(fileSize int64) int64 {
+	size := int64(minS3PartSize)
+	if needed := (fileSize + maxS3Parts - 1) / maxS3Parts; needed > size {
+		size = needed
+	}
+	return size
+}
+
+func saveS3UploadState(path string, state *s3UploadState) error {
+	data, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	return nil
+}
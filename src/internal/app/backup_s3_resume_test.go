@@ -0,0 +1,242 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// multipartTestServer is an S3 stub for the multipart upload calls of --resume-upload.
+type multipartTestServer struct {
+	mu       sync.Mutex
+	uploads  map[string]map[int]int64 // upload ID -> part number -> size
+	next     int
+	failPart int // UploadPart of this part number fails once
+	ops      []string
+	complete string // part list of the last CompleteMultipartUpload
+}
+
+func newMultipartTestServer(t *testing.T) (*multipartTestServer, *httptest.Server) {
+	t.Helper()
+	stub := &multipartTestServer{uploads: map[string]map[int]int64{}}
+	server := httptest.NewServer(http.HandlerFunc(stub.serve))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func (m *multipartTestServer) serve(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+	writeError := func(status int, code string) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+	}
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		m.next++
+		id := fmt.Sprintf("upload-%d", m.next)
+		m.uploads[id] = map[int]int64{}
+		m.ops = append(m.ops, "create")
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Bucket>backups</Bucket><Key>k</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && uploadID != "":
+		var number int
+		fmt.Sscan(query.Get("partNumber"), &number)
+		size, _ := io.Copy(io.Discard, r.Body)
+		m.ops = append(m.ops, fmt.Sprintf("part%d", number))
+		if number == m.failPart {
+			m.failPart = 0
+			writeError(http.StatusBadRequest, "InvalidRequest")
+			return
+		}
+		m.uploads[uploadID][number] = size
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodGet && uploadID != "":
+		m.ops = append(m.ops, "list")
+		parts, ok := m.uploads[uploadID]
+		if !ok {
+			writeError(http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var b strings.Builder
+		b.WriteString("<ListPartsResult><IsTruncated>false</IsTruncated>")
+		for number, size := range parts {
+			fmt.Fprintf(&b, `<Part><PartNumber>%d</PartNumber><ETag>"etag-%d"</ETag><Size>%d</Size></Part>`, number, number, size)
+		}
+		b.WriteString("</ListPartsResult>")
+		w.Write([]byte(b.String()))
+	case r.Method == http.MethodPost && uploadID != "":
+		body, _ := io.ReadAll(r.Body)
+		m.ops = append(m.ops, "complete")
+		m.complete = string(body)
+		delete(m.uploads, uploadID)
+		fmt.Fprint(w, "<CompleteMultipartUploadResult><Bucket>backups</Bucket><Key>k</Key><ETag>\"done\"</ETag></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && uploadID != "":
+		m.ops = append(m.ops, "abort")
+		delete(m.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (m *multipartTestServer) takeOps() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ops := strings.Join(m.ops, ",")
+	m.ops = nil
+	return ops
+}
+
+// newResumeTestArchive creates a sparse archive of two parts: one full part and 1 KiB.
+func newResumeTestArchive(t *testing.T, iops *InfrahubOps) string {
+	t.Helper()
+	path := filepath.Join(iops.config.BackupDir, "infrahub_backup_20250601T020000Z.tar.gz")
+	if err := os.WriteFile(path, []byte{0x1f, 0x8b}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, minS3PartSize+1024); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newResumeTestOps(t *testing.T, endpoint string) *InfrahubOps {
+	t.Helper()
+	iops := newRegionTestOps(t, endpoint)
+	iops.config.S3Upload = true
+	iops.config.S3ResumeUpload = true
+	iops.config.S3ResumeMaxAge = defaultS3ResumeMaxAge
+	return iops
+}
+
+func TestUploadResumableResumesAfterFailedPart(t *testing.T) {
+	stub, server := newMultipartTestServer(t)
+	iops := newResumeTestOps(t, server.URL)
+	archive := newResumeTestArchive(t, iops)
+	statePath := archive + s3StateSuffix
+
+	stub.failPart = 2
+	if err := iops.uploadBackupToS3(archive); err == nil || !strings.Contains(err.Error(), "part 2 of 2") {
+		t.Fatalf("upload with a failing part = %v, want the part 2 failure", err)
+	}
+	if got := stub.takeOps(); got != "create,part1,part2" {
+		t.Errorf("first run = %s, want create,part1,part2", got)
+	}
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("upload state not saved: %v", err)
+	}
+	var state s3UploadState
+	if err := json.Unmarshal(data, &state); err != nil || len(state.Parts) != 1 || state.Parts[0].Number != 1 {
+		t.Fatalf("saved state = %s (%v), want part 1 recorded", data, err)
+	}
+
+	if err := iops.uploadBackupToS3(archive); err != nil {
+		t.Fatalf("resumed upload: %v", err)
+	}
+	if got := stub.takeOps(); got != "list,part2,complete" {
+		t.Errorf("resumed run = %s, want only part 2 uploaded", got)
+	}
+	if !strings.Contains(stub.complete, "<PartNumber>1</PartNumber>") || !strings.Contains(stub.complete, "<PartNumber>2</PartNumber>") {
+		t.Errorf("completed part list = %s, want parts 1 and 2", stub.complete)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("upload state left after completion: %v", err)
+	}
+}
+
+func TestUploadResumableDiscardsStaleState(t *testing.T) {
+	stub, server := newMultipartTestServer(t)
+	iops := newResumeTestOps(t, server.URL)
+	archive := newResumeTestArchive(t, iops)
+	stat, err := os.Stat(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := s3UploadState{
+		Version: s3StateMetadataVersion, Bucket: "backups", Key: iops.s3Key(filepath.Base(archive)),
+		PartSize: s3PartSize(stat.Size()), FileSize: stat.Size(), FileMtime: stat.ModTime().UTC(), StartedAt: time.Now().UTC(),
+	}
+
+	tests := []struct {
+		name   string
+		change func(*s3UploadState)
+		want   string
+	}{
+		{name: "other key", change: func(s *s3UploadState) { s.Key = "other" }, want: "abort,create,part1,part2,complete"},
+		{name: "archive changed", change: func(s *s3UploadState) { s.FileSize++ }, want: "abort,create,part1,part2,complete"},
+		{name: "too old", change: func(s *s3UploadState) { s.StartedAt = time.Now().Add(-48 * time.Hour) }, want: "abort,create,part1,part2,complete"},
+		{name: "upload gone", change: func(s *s3UploadState) { s.UploadID = "missing" }, want: "list,create,part1,part2,complete"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := valid
+			state.UploadID = "stale"
+			stub.mu.Lock()
+			stub.uploads["stale"] = map[int]int64{1: minS3PartSize}
+			stub.mu.Unlock()
+			tt.change(&state)
+			if err := saveS3UploadState(archive+s3StateSuffix, &state); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := iops.uploadBackupToS3(archive); err != nil {
+				t.Fatalf("upload: %v", err)
+			}
+			if got := stub.takeOps(); got != tt.want {
+				t.Errorf("operations = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestS3PartSize(t *testing.T) {
+	tests := []struct {
+		fileSize int64
+		want     int64
+	}{
+		{fileSize: 0, want: minS3PartSize},
+		{fileSize: minS3PartSize * maxS3Parts, want: minS3PartSize},
+		{fileSize: minS3PartSize*maxS3Parts + 1, want: minS3PartSize + 1},
+	}
+	for _, tt := range tests {
+		got := s3PartSize(tt.fileSize)
+		if got != tt.want {
+			t.Errorf("s3PartSize(%d) = %d, want %d", tt.fileSize, got, tt.want)
+		}
+		if parts := (tt.fileSize + got - 1) / got; parts > maxS3Parts {
+			t.Errorf("s3PartSize(%d) gives %d parts, more than %d", tt.fileSize, parts, maxS3Parts)
+		}
+	}
+}
+
+func TestSaveS3UploadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.tar.gz"+s3StateSuffix)
+	state := &s3UploadState{Version: s3StateMetadataVersion, UploadID: "u", Parts: []s3StatePart{{Number: 1, ETag: `"a"`}}}
+	if err := saveS3UploadState(path, state); err != nil {
+		t.Fatalf("saveS3UploadState: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got s3UploadState
+	if err := json.Unmarshal(data, &got); err != nil || got.UploadID != "u" || len(got.Parts) != 1 {
+		t.Errorf("saved state = %s (%v), want the upload and its part", data, err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary state file left behind: %v", err)
+	}
+}
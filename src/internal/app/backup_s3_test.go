@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestCreateS3ClientForCompatibleEndpoint(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	iops.config.S3Endpoint = "http://minio.example.test:9000"
+	iops.config.S3Region = "us-east-1"
+	iops.config.S3AccessKeyID = "minio"
+	iops.config.S3SecretKey = "minio-secret"
+
+	environ := os.Environ()
+	client, err := iops.createS3Client(context.Background())
+	if err != nil {
+		t.Fatalf("createS3Client: %v", err)
+	}
+	if after := os.Environ(); !slices.Equal(after, environ) {
+		t.Errorf("createS3Client changed the process environment")
+	}
+
+	options := client.Options()
+	if got := aws.ToString(options.BaseEndpoint); got != iops.config.S3Endpoint {
+		t.Errorf("BaseEndpoint = %q, want %q", got, iops.config.S3Endpoint)
+	}
+	if !options.UsePathStyle || !options.UseARNRegion || !options.DisableMultiRegionAccessPoints {
+		t.Errorf("UsePathStyle %t, UseARNRegion %t, DisableMultiRegionAccessPoints %t, want all true",
+			options.UsePathStyle, options.UseARNRegion, options.DisableMultiRegionAccessPoints)
+	}
+	if options.RequestChecksumCalculation != aws.RequestChecksumCalculationWhenRequired ||
+		options.ResponseChecksumValidation != aws.ResponseChecksumValidationWhenRequired {
+		t.Errorf("checksums = %v/%v, want only when required", options.RequestChecksumCalculation, options.ResponseChecksumValidation)
+	}
+}
+
+func TestNegativeS3MaxRetriesIsRejected(t *testing.T) {
+	fake := apptest.NewFakeBackend("database", "task-manager-db")
+	iops := newTestOps(t, fake)
+	iops.config.S3MaxRetries = -1
+	iops.config.S3Bucket = "backups"
+	iops.config.S3AccessKeyID = "minio"
+	iops.config.S3SecretKey = "minio-secret"
+
+	if err := iops.validateS3Config(); !errors.Is(err, ErrPrerequisites) {
+		t.Errorf("validateS3Config with --s3-max-retries -1 = %v, want ErrPrerequisites", err)
+	}
+
+	iops.config.S3Upload = true
+	if err := iops.CreateBackup(true, "none", false); !errors.Is(err, ErrPrerequisites) {
+		t.Errorf("CreateBackup with --s3-max-retries -1 = %v, want ErrPrerequisites", err)
+	}
+	if stops := fake.CallsTo("Stop"); len(stops) != 0 {
+		t.Errorf("CreateBackup stopped services before rejecting --s3-max-retries: %v", stops)
+	}
+
+	iops.config.S3MaxRetries = 0
+	if err := iops.validateS3Config(); err != nil {
+		t.Errorf("validateS3Config with --s3-max-retries 0 = %v, want nil", err)
+	}
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sirupsen/logrus"
+)
+
+// validateS3DeleteLocal checks --s3-delete-local before anything is backed up: the archive is
+// only deleted after --verify-s3-before-delete-local confirmed the uploaded copy, unless
+// --force-delete-local explicitly accepts deleting it unverified.
+func (iops *InfrahubOps) validateS3DeleteLocal() error {
+	if !iops.config.S3DeleteLocal {
+		return nil
+	}
+	if !iops.config.S3Upload {
+		return fmt.Errorf("%w: --s3-delete-local requires --s3-upload", ErrPrerequisites)
+	}
+	if !iops.config.VerifyS3BeforeDeleteLocal && !iops.config.ForceDeleteLocal {
+		return fmt.Errorf("%w: refusing --s3-delete-local with --verify-s3-before-delete-local=false; pass --force-delete-local to delete the local archive without verifying the upload", ErrPrerequisites)
+	}
+	return nil
+}
+
+// s3ChecksumSHA256 returns the base64 SHA-256 that S3 checks the uploaded bytes against.
+func s3ChecksumSHA256(path string) (string, error) {
+	sum, err := calculateSHA256(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum %s: %w", filepath.Base(path), err)
+	}
+	raw, err := hex.DecodeString(sum)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// verifyS3Upload checks that the object at key has the size and SHA-256 of the local archive.
+// The checksum S3 stored for a single-part upload is compared directly; a multipart upload
+// only has a checksum of its parts, so the object is downloaded and hashed instead.
+func (iops *InfrahubOps) verifyS3Upload(backupPath, key string) error {
+	stat, err := os.Stat(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat backup file: %w", err)
+	}
+	localSum, err := s3ChecksumSHA256(backupPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	client, err := iops.createS3Client(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(iops.config.S3Bucket),
+		Key:          aws.String(key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read uploaded object s3://%s/%s: %w", iops.config.S3Bucket, key, err)
+	}
+	if size := aws.ToInt64(head.ContentLength); size != stat.Size() {
+		return fmt.Errorf("%w: s3://%s/%s is %s, the local archive is %s", ErrChecksumMismatch, iops.config.S3Bucket, key, formatBytes(size), formatBytes(stat.Size()))
+	}
+
+	method := "S3 checksum"
+	remoteSum := aws.ToString(head.ChecksumSHA256)
+	if remoteSum == "" || strings.Contains(remoteSum, "-") {
+		method = "download"
+		if remoteSum, err = iops.downloadS3ChecksumSHA256(ctx, client, key); err != nil {
+			return err
+		}
+	}
+	if remoteSum != localSum {
+		return fmt.Errorf("%w: SHA-256 of s3://%s/%s does not match the local archive", ErrChecksumMismatch, iops.config.S3Bucket, key)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"key":    key,
+		"size":   formatBytes(stat.Size()),
+		"method": method,
+	}).Info("Verified the uploaded backup: size and SHA-256 match the local archive")
+	return nil
+}
+
+// downloadS3ChecksumSHA256 streams the object through SHA-256 without writing it to disk.
+func (iops *InfrahubOps) downloadS3ChecksumSHA256(ctx context.Context, client *s3.Client, key string) (string, error) {
+	logrus.WithField("key", key).Info("S3 has no full-object SHA-256 for this upload; downloading it to verify")
+	output, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(iops.config.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download uploaded object for verification: %w", err)
+	}
+	defer output.Body.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, output.Body); err != nil {
+		return "", fmt.Errorf("failed to read uploaded object for verification: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// deleteLocalAfterUpload removes the local archive once it is in S3 (--s3-delete-local). A
+// failed verification keeps the archive and fails the command.
+func (iops *InfrahubOps) deleteLocalAfterUpload(backupPath, key string) error {
+	if iops.config.VerifyS3BeforeDeleteLocal {
+		if err := iops.verifyS3Upload(backupPath, key); err != nil {
+			return fmt.Errorf("local archive %s kept: %w", backupPath, err)
+		}
+	} else {
+		logrus.Warnf("Deleting %s without verifying the uploaded copy (--force-delete-local)", backupPath)
+	}
+
+	if iops.config.DryRun {
+		stat, err := os.Stat(backupPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat backup file: %w", err)
+		}
+		printDryRunDeletion(backupPath, stat.Size())
+		fmt.Println("[dry-run] 1 local archive would be deleted; nothing was deleted")
+		return nil
+	}
+	if err := os.Remove(backupPath); err != nil {
+		return fmt.Errorf("failed to delete local archive: %w", err)
+	}
+	logrus.WithField("path", backupPath).Info("Deleted the local archive after the S3 upload")
+	return nil
+}
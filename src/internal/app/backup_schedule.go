@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const scheduleStateFilename = ".infrahub_backup_schedule.json"
+
+// ScheduleOptions controls the built-in backup scheduler.
+type ScheduleOptions struct {
+	Interval           time.Duration
+	Jitter             time.Duration
+	StateFile          string
+	Force              bool
+	Neo4jMetadata      string
+	ExcludeTaskManager bool
+}
+
+// scheduleState is persisted between runs so restarts neither skip nor duplicate backups.
+type scheduleState struct {
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// RunSchedule creates a backup every interval until interrupted. On startup it runs a
+// catch-up backup if the last successful one is older than the interval.
+func (iops *InfrahubOps) RunSchedule(opts ScheduleOptions) error {
+	if opts.Interval <= 0 {
+		return fmt.Errorf("%w: --interval must be greater than zero", ErrPrerequisites)
+	}
+	if opts.Jitter < 0 {
+		return fmt.Errorf("%w: --schedule-jitter must not be negative", ErrPrerequisites)
+	}
+	if opts.StateFile == "" {
+		opts.StateFile = filepath.Join(iops.config.BackupDir, scheduleStateFilename)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	state, err := loadScheduleState(opts.StateFile)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"interval":     opts.Interval,
+		"jitter":       opts.Jitter,
+		"state_file":   opts.StateFile,
+		"last_success": formatScheduleTime(state.LastSuccess),
+	}).Info("Backup scheduler started")
+
+	// retryAt holds a failed attempt of this run back for one interval instead of retrying at once
+	var retryAt time.Time
+	for {
+		next := nextScheduledRun(state, opts.Interval, time.Now())
+		if retryAt.After(next) {
+			next = retryAt
+		}
+		if next.IsZero() {
+			logrus.Info("Last successful backup is older than the interval; running catch-up backup")
+			next = time.Now()
+		}
+		next = next.Add(scheduleJitter(opts.Jitter))
+
+		logrus.WithField("next_run", next.Format(time.RFC3339)).Info("Waiting for next scheduled backup")
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logrus.Info("Backup scheduler stopped")
+			return nil
+		case <-timer.C:
+		}
+
+		state.LastAttempt = time.Now()
+		if runErr := iops.createBackupWithRetries(ctx, opts.Force, opts.Neo4jMetadata, opts.ExcludeTaskManager); runErr != nil {
+			logrus.Errorf("Scheduled backup failed: %v", runErr)
+			state.LastError = runErr.Error()
+			retryAt = state.LastAttempt.Add(opts.Interval)
+		} else {
+			state.LastSuccess = state.LastAttempt
+			state.LastError = ""
+		}
+
+		if err := saveScheduleState(opts.StateFile, state); err != nil {
+			return err
+		}
+	}
+}
+
+// nextScheduledRun returns when the next backup is due, or the zero time if one is due now.
+// It only counts successful backups, so that a scheduler restarted after failed attempts
+// catches up at once instead of waiting another interval without a backup.
+func nextScheduledRun(state scheduleState, interval time.Duration, now time.Time) time.Time {
+	if state.LastSuccess.IsZero() {
+		return time.Time{}
+	}
+	next := state.LastSuccess.Add(interval)
+	if !next.After(now) {
+		return time.Time{}
+	}
+	return next
+}
+
+// scheduleJitter returns a random delay in [0, limit).
+func scheduleJitter(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit)
+}
+
+func formatScheduleTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func loadScheduleState(path string) (scheduleState, error) {
+	var state scheduleState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, fmt.Errorf("failed to read schedule state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse schedule state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// saveScheduleState writes the state atomically so a crash never leaves a truncated file.
+func saveScheduleState(path string, state scheduleState) error {
+	data, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create schedule state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schedule state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write schedule state: %w", err)
+	}
+	return nil
+}
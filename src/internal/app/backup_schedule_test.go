@@ -0,0 +1,42 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestNextScheduledRun(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	interval := 6 * time.Hour
+	tests := []struct {
+		name  string
+		state scheduleState
+		want  time.Time
+	}{
+		{name: "never ran", state: scheduleState{}},
+		{name: "recent success", state: scheduleState{LastSuccess: now.Add(-time.Hour), LastAttempt: now.Add(-time.Hour)}, want: now.Add(5 * time.Hour)},
+		{name: "old success", state: scheduleState{LastSuccess: now.Add(-7 * time.Hour), LastAttempt: now.Add(-7 * time.Hour)}},
+		{name: "old success, recent failure", state: scheduleState{LastSuccess: now.Add(-7 * time.Hour), LastAttempt: now.Add(-time.Minute)}},
+		{name: "only failures", state: scheduleState{LastAttempt: now.Add(-time.Minute)}},
+		{name: "due exactly now", state: scheduleState{LastSuccess: now.Add(-interval)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextScheduledRun(tt.state, interval, now); !got.Equal(tt.want) {
+				t.Errorf("nextScheduledRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunScheduleRejectsInvalidFlags(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	for _, opts := range []ScheduleOptions{{}, {Interval: time.Hour, Jitter: -time.Minute}} {
+		if err := iops.RunSchedule(opts); !errors.Is(err, ErrPrerequisites) {
+			t.Errorf("RunSchedule(%+v) = %v, want ErrPrerequisites", opts, err)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package app
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// smallArchiveWarnSize is the size below which a finished archive most likely holds an empty or failed dump.
+	smallArchiveWarnSize = 64 * 1024
+	defaultMinDumpSize   = "4KB"
+	// dumpShrinkWarnRatio warns when a dump is smaller than this fraction of the previous backup's.
+	dumpShrinkWarnRatio = 0.5
+)
+
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"T":   1 << 40,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// parseByteSize parses sizes such as 512MB, 5GiB or 1048576. Units are binary (1 KB = 1024 bytes),
+// matching formatBytes. An empty value returns 0.
+func parseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	split := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	number, unit := value, ""
+	if split >= 0 {
+		number, unit = value[:split], strings.ToUpper(strings.TrimSpace(value[split:]))
+	}
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q in %q", unit, value)
+	}
+	parsed, err := strconv.ParseFloat(number, 64)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(parsed * float64(multiplier)), nil
+}
+
+// checkArchiveSize fails when the archive exceeds maxSize (0 disables the limit) and
+// warns when it is suspiciously small. An oversized archive is removed so it is never
+// mistaken for a usable backup.
+func checkArchiveSize(path string, maxSize int64) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat archive: %w", err)
+	}
+	size := stat.Size()
+
+	if maxSize > 0 && size > maxSize {
+		if err := os.Remove(path); err != nil {
+			logrus.Warnf("Failed to remove oversized archive %s: %v", path, err)
+		}
+		return fmt.Errorf("backup archive is %s, above --max-archive-size %s; check that no unexpected data was included, exclude the task manager database, or raise the limit", formatBytes(size), formatBytes(maxSize))
+	}
+
+	if size < smallArchiveWarnSize {
+		logrus.Warnf("Backup archive is only %s; the database dump may be empty or incomplete", formatBytes(size))
+	}
+	return nil
+}
+
+// checkDumpSizes verifies that each dump (a file or directory under backupDir) is non-empty and
+// at least minSize bytes, and returns the sizes so they can be recorded in the metadata and
+// compared on the next backup.
+func checkDumpSizes(backupDir string, dumps []string, minSize int64) (map[string]int64, error) {
+	sizes := make(map[string]int64, len(dumps))
+	for _, name := range dumps {
+		size, err := pathSize(filepath.Join(backupDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s: %w", name, err)
+		}
+		if size == 0 {
+			return nil, fmt.Errorf("%s dump is empty; refusing to create a backup that cannot be restored", name)
+		}
+		if size < minSize {
+			return nil, fmt.Errorf("%s dump is only %s, below --min-dump-size %s; check the database configuration", name, formatBytes(size), formatBytes(minSize))
+		}
+		sizes[name] = size
+	}
+	return sizes, nil
+}
+
+// pathSize returns the size of a file, or the total size of the files in a directory.
+func pathSize(path string) (int64, error) {
+	var total int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// warnOnDumpShrink compares dump sizes with the newest local backup and warns on a large drop.
+func (iops *InfrahubOps) warnOnDumpShrink(sizes map[string]int64) {
+	entries, err := iops.listLocalBackups()
+	if err != nil {
+		logrus.Debugf("Skipping dump size comparison: %v", err)
+		return
+	}
+	previous, ok := latestBackup(entries)
+	if !ok {
+		return
+	}
+	metadata, err := readArchiveMetadata(filepath.Join(iops.config.BackupDir, previous.Name))
+	if err != nil {
+		logrus.Debugf("Skipping dump size comparison with %s: %v", previous.Name, err)
+		return
+	}
+
+	for name, size := range sizes {
+		previousSize, ok := metadata.DumpSizes[name]
+		if !ok || previousSize == 0 {
+			continue
+		}
+		if float64(size) < float64(previousSize)*dumpShrinkWarnRatio {
+			logrus.Warnf("%s dump is %s, down from %s in %s; verify that no data was lost", name, formatBytes(size), formatBytes(previousSize), previous.Name)
+		}
+	}
+}
+
+// readArchiveMetadata reads backup_information.json from an archive without extracting it.
+func readArchiveMetadata(archivePath string) (*BackupMetadata, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readArchiveMetadataStream(file)
+}
+
+// readArchiveMetadataStream reads the metadata from a tar stream, gzip-compressed or not, and
+// stops there, without reading the rest of the archive.
+func readArchiveMetadataStream(r io.Reader) (*BackupMetadata, error) {
+	stream, err := openArchiveStream(r)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	tr := tar.NewReader(stream)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in archive", backupMetadataFilename)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(header.Name) != backupMetadataFilename {
+			continue
+		}
+		var metadata BackupMetadata
+		if err := json.NewDecoder(tr).Decode(&metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata: %w", err)
+		}
+		return &metadata, nil
+	}
+}
+
+// componentPath returns the file or directory under the backup directory that holds a
+// component, or "" for components without their own path.
+func (iops *InfrahubOps) componentPath(component string) string {
+	switch component {
+	case "database":
+		return neo4jBackupDirName
+	case "task-manager-db":
+		return prefectDumpFilename
+	case neo4jAuthComponent:
+		return neo4jAuthDirName
+	case neo4jSchemaComponent:
+		return neo4jSchemaFilename
+	case deploymentConfigComponent:
+		return deploymentConfigDirName
+	}
+	if database, ok := strings.CutPrefix(component, taskManagerExtraComponentPrefix); ok {
+		return iops.taskManagerDumpFilename(database)
+	}
+	return ""
+}
+
+// componentSizes returns the on-disk size of each component of the backup, before archiving.
+func (iops *InfrahubOps) componentSizes(backupDir string, components []string) map[string]int64 {
+	sizes := make(map[string]int64, len(components))
+	for _, component := range components {
+		path := iops.componentPath(component)
+		if path == "" {
+			continue
+		}
+		size, err := pathSize(filepath.Join(backupDir, path))
+		if err != nil {
+			logrus.Debugf("Could not measure component %s: %v", component, err)
+			continue
+		}
+		sizes[component] = size
+	}
+	return sizes
+}
+
+// logComponentSizes logs and reports the size of each component and of the archive, so that
+// operators can see what takes up backup space.
+func (iops *InfrahubOps) logComponentSizes(sizes map[string]int64, uncompressed, archive int64) {
+	fields := logrus.Fields{}
+	parts := make([]string, 0, len(sizes))
+	for _, component := range slices.Sorted(maps.Keys(sizes)) {
+		fields[component] = sizes[component]
+		parts = append(parts, fmt.Sprintf("%s %s", component, formatBytes(sizes[component])))
+	}
+	logrus.WithFields(fields).Info("Backup component sizes (bytes)")
+
+	ratio := compressionRatio(uncompressed, archive)
+	logrus.WithFields(logrus.Fields{
+		"uncompressed_bytes": uncompressed,
+		"archive_bytes":      archive,
+		"compression_ratio":  ratio,
+	}).Infof("Archive is %s for %s of backup data (compression ratio %.2f)", formatBytes(archive), formatBytes(uncompressed), ratio)
+
+	iops.report.Load().set("Component sizes", strings.Join(parts, ", "))
+	iops.report.Load().set("Compression", fmt.Sprintf("%s -> %s (ratio %.2f)", formatBytes(uncompressed), formatBytes(archive), ratio))
+}
+
+// compressionRatio is the uncompressed size divided by the archive size, rounded to two decimals.
+func compressionRatio(uncompressed, archive int64) float64 {
+	if archive <= 0 {
+		return 0
+	}
+	return math.Round(float64(uncompressed)/float64(archive)*100) / 100
+}
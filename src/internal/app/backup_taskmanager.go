@@ -2,37 +2,245 @@ package app
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
-func (iops *InfrahubOps) backupTaskManagerDB(backupDir string) error {
-	logrus.Info("Backing up PostgreSQL database...")
+const (
+	taskManagerDBService = "task-manager-db"
+	defaultPostgresHost  = "localhost"
+	defaultPostgresPort  = 5432
+	// postgresClientLocal runs pg_dump/pg_restore on the machine running this tool.
+	postgresClientLocal = "local"
+	// taskManagerExtraComponentPrefix marks additional task manager databases in metadata components.
+	taskManagerExtraComponentPrefix = "task-manager-db:"
+)
+
+// postgresDatabaseNamePattern restricts database names to ones that are safe in a dump filename.
+var postgresDatabaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// isExternalPostgres reports whether the task manager database is reached over the network
+// rather than from inside the task-manager-db container.
+func (iops *InfrahubOps) isExternalPostgres() bool {
+	switch iops.config.PostgresHost {
+	case "", "localhost", "127.0.0.1", "::1":
+		return false
+	}
+	return true
+}
+
+// taskManagerAbsence returns why the task manager database is left out of a backup on its
+// own, or "" to dump it. Lean deployments have no task-manager-db service at all; only that
+// service is checked, so an external database or --postgres-client-service is always dumped.
+// A task-manager-db that exists but is stopped is dumped too, so that checkBackupServices
+// fails on it instead of the backup silently leaving it out.
+func (iops *InfrahubOps) taskManagerAbsence() string {
+	service, err := iops.postgresClient(iops.config.tool(pgDumpTool))
+	if err != nil || service != taskManagerDBService {
+		return ""
+	}
+	exists, err := iops.HasService(service)
+	if err != nil || exists {
+		return ""
+	}
+	return fmt.Sprintf("service %s does not exist in this deployment", service)
+}
+
+// postgresClient returns the service in which the PostgreSQL client tool runs, or ""
+// when it runs locally. External databases default to a local client when one is installed.
+func (iops *InfrahubOps) postgresClient(tool string) (string, error) {
+	switch service := iops.config.PostgresClientService; {
+	case service == postgresClientLocal:
+		if _, err := exec.LookPath(tool); err != nil {
+			return "", fmt.Errorf("%w: %s is not installed locally: %w", ErrPrerequisites, tool, err)
+		}
+		return "", nil
+	case service != "":
+		return service, nil
+	case !iops.isExternalPostgres():
+		return taskManagerDBService, nil
+	}
+
+	if _, err := exec.LookPath(tool); err == nil {
+		return "", nil
+	}
+	return "", fmt.Errorf("%w: task manager database host %s is external and %s is not installed locally; set --postgres-client-service to a service with the PostgreSQL client", ErrPrerequisites, iops.config.PostgresHost, tool)
+}
+
+// pgConnectionArgs returns the host and port arguments shared by pg_dump and pg_restore.
+func (iops *InfrahubOps) pgConnectionArgs() []string {
+	host := iops.config.PostgresHost
+	if host == "" {
+		host = defaultPostgresHost
+	}
+	port := iops.config.PostgresPort
+	if port == 0 {
+		port = defaultPostgresPort
+	}
+	return []string{"-h", host, "-p", strconv.Itoa(port)}
+}
+
+// taskManagerDatabases returns the databases to dump. The Prefect database always comes first.
+func (iops *InfrahubOps) taskManagerDatabases() []string {
+	databases := []string{iops.config.PostgresDatabase}
+	for _, database := range iops.config.PostgresDatabases {
+		if database != "" && !slices.Contains(databases, database) {
+			databases = append(databases, database)
+		}
+	}
+	return databases
+}
+
+// taskManagerDumpFilename keeps prefect.dump for the Prefect database so older archives
+// and tools remain compatible; additional databases use prefect_<db>.dump.
+func (iops *InfrahubOps) taskManagerDumpFilename(database string) string {
+	if database == iops.config.PostgresDatabase {
+		return prefectDumpFilename
+	}
+	return "prefect_" + database + ".dump"
+}
+
+// extraTaskManagerDatabases lists the additional databases recorded in backup metadata.
+func extraTaskManagerDatabases(metadata *BackupMetadata) []string {
+	var databases []string
+	for _, component := range metadata.Components {
+		if database, ok := strings.CutPrefix(component, taskManagerExtraComponentPrefix); ok {
+			databases = append(databases, database)
+		}
+	}
+	return databases
+}
+
+// checkTaskManagerDatabaseName rejects a database name that is not safe in a dump filename or
+// a pg_restore argument.
+func checkTaskManagerDatabaseName(database string) error {
+	if !postgresDatabaseNamePattern.MatchString(database) {
+		return fmt.Errorf("unsupported task manager database name %q", database)
+	}
+	return nil
+}
+
+// validateExtraTaskManagerDatabases checks the additional database names read from the
+// metadata of an archive the same way a backup checks --taskmanager-databases.
+func validateExtraTaskManagerDatabases(metadata *BackupMetadata) error {
+	for _, database := range extraTaskManagerDatabases(metadata) {
+		if err := checkTaskManagerDatabaseName(database); err != nil {
+			return fmt.Errorf("invalid backup file: %w", err)
+		}
+	}
+	return nil
+}
+
+// isTaskManagerDump reports whether a file in the archive is a task manager database dump.
+func isTaskManagerDump(name string) bool {
+	return name == prefectDumpFilename || (strings.HasPrefix(name, "prefect_") && strings.HasSuffix(name, ".dump"))
+}
+
+// backupTaskManagerDB dumps each task manager database and returns the dump filenames.
+func (iops *InfrahubOps) backupTaskManagerDB(backupDir string) ([]string, error) {
+	var dumps []string
+	var connArgs []string
+	for _, database := range iops.taskManagerDatabases() {
+		if err := checkTaskManagerDatabaseName(database); err != nil {
+			return nil, err
+		}
+		if connArgs == nil {
+			connArgs = iops.pgDumpConnectionArgs()
+		}
+		filename := iops.taskManagerDumpFilename(database)
+		if err := iops.dumpPostgresDatabase(backupDir, database, filename, connArgs); err != nil {
+			return nil, err
+		}
+		dumps = append(dumps, filename)
+	}
+	return dumps, nil
+}
+
+// pgDumpExcludeArgs returns the pg_dump options of --pg-exclude-table and --pg-exclude-table-data.
+func (iops *InfrahubOps) pgDumpExcludeArgs() []string {
+	var args []string
+	for _, pattern := range iops.config.PgExcludeTables {
+		args = append(args, "--exclude-table="+pattern)
+	}
+	for _, pattern := range iops.config.PgExcludeTableData {
+		args = append(args, "--exclude-table-data="+pattern)
+	}
+	return args
+}
+
+// validatePgExcludeTables rejects empty --pg-exclude-table and --pg-exclude-table-data patterns.
+func validatePgExcludeTables(cfg *Configuration) error {
+	if slices.ContainsFunc(cfg.PgExcludeTables, isBlank) {
+		return fmt.Errorf("invalid --pg-exclude-table: the pattern is empty")
+	}
+	if slices.ContainsFunc(cfg.PgExcludeTableData, isBlank) {
+		return fmt.Errorf("invalid --pg-exclude-table-data: the pattern is empty")
+	}
+	return nil
+}
+
+func isBlank(value string) bool {
+	return strings.TrimSpace(value) == ""
+}
+
+// pgDataParentDir returns the parent of $PGDATA in service, which on hardened images is often
+// the only writable location besides the data directory itself. It is empty when unknown.
+func (iops *InfrahubOps) pgDataParentDir(service string) string {
+	output, err := iops.Exec(service, []string{"sh", "-c", `echo "$PGDATA"`}, nil)
+	pgData := strings.TrimSuffix(strings.TrimSpace(output), "/")
+	if err != nil || pgData == "" {
+		return ""
+	}
+	return path.Dir(pgData)
+}
+
+func (iops *InfrahubOps) dumpPostgresDatabase(backupDir, database, filename string, connArgs []string) error {
+	logrus.WithField("database", database).Info("Backing up PostgreSQL database...")
+
+	client, err := iops.postgresClient(iops.config.tool(pgDumpTool))
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{iops.config.tool(pgDumpTool), "-Fc"}, connArgs...)
+	args = append(args, "-U", iops.config.PostgresUsername, "-d", database)
+	args = append(args, iops.pgDumpExcludeArgs()...)
+	env := map[string]string{"PGPASSWORD": iops.config.PostgresPassword}
+	localDump := filepath.Join(backupDir, filename)
+
+	if client == "" {
+		args = append(args, "-f", localDump)
+		if output, err := iops.executor.runCommandEnv(env, args[0], args[1:]...); err != nil {
+			return fmt.Errorf("failed to create postgresql dump of %s: %w\nOutput: %v", database, err, output)
+		}
+		logrus.Info("PostgreSQL backup completed")
+		return nil
+	}
 
 	// Determine writable temp directory
-	tempDir := iops.getWritableTempDir("task-manager-db")
-	dumpFile := tempDir + "/infrahubops_prefect.dump"
+	tempDir, err := iops.getWritableTempDir(client, iops.config.PgTempDir, "/tmp", "/var/tmp", iops.pgDataParentDir(client), "/run")
+	if err != nil {
+		return fmt.Errorf("%w; mount a writable volume in %s and pass it with --pg-temp-dir", err, client)
+	}
+	dumpFile := tempDir + "/" + pgDumpStagingPrefix + filename
 
 	// Create dump
-	opts := &ExecOptions{Env: map[string]string{
-		"PGPASSWORD": iops.config.PostgresPassword,
-	}}
-	if output, err := iops.Exec(
-		"task-manager-db",
-		[]string{"pg_dump", "-Fc", "-h", "localhost", "-U", iops.config.PostgresUsername, "-d", iops.config.PostgresDatabase, "-f", dumpFile},
-		opts,
-	); err != nil {
-		return fmt.Errorf("failed to create postgresql dump: %w\nOutput: %v", err, output)
-	}
-	defer func() {
-		if _, err := iops.Exec("task-manager-db", []string{"rm", dumpFile}, nil); err != nil {
-			logrus.Warnf("Failed to remove temporary postgres dump: %v", err)
-		}
-	}()
+	args = append(args, "-f", dumpFile)
+	if output, err := iops.Exec(client, args, &ExecOptions{Env: env}); err != nil {
+		return fmt.Errorf("failed to create postgresql dump of %s: %w\nOutput: %v", database, err, output)
+	}
+	defer iops.removeRemotePaths(client, dumpFile)
 
 	// Copy dump
-	if err := iops.CopyFrom("task-manager-db", dumpFile, filepath.Join(backupDir, "prefect.dump")); err != nil {
+	if err := iops.CopyFrom(client, dumpFile, localDump); err != nil {
 		return fmt.Errorf("failed to copy postgresql dump: %w", err)
 	}
 
@@ -40,41 +248,151 @@ func (iops *InfrahubOps) backupTaskManagerDB(backupDir string) error {
 	return nil
 }
 
-func (iops *InfrahubOps) restorePostgreSQL(workDir string) error {
-	logrus.Info("Restoring PostgreSQL database...")
+// restorePostgreSQL restores the Prefect database followed by any additional databases.
+func (iops *InfrahubOps) restorePostgreSQL(workDir string, extraDatabases []string) error {
+	client, err := iops.postgresClient(iops.config.tool(pgRestoreTool))
+	if err != nil {
+		return err
+	}
 
-	// Start task-manager-db
-	if err := iops.StartServices("task-manager-db"); err != nil {
-		return fmt.Errorf("failed to start task-manager-db: %w", err)
+	// Start task-manager-db (an external database is managed outside of the deployment)
+	if !iops.isExternalPostgres() {
+		if err := iops.StartServices(taskManagerDBService); err != nil {
+			return fmt.Errorf("failed to start task-manager-db: %w", err)
+		}
 	}
 
-	// Determine writable temp directory
-	tempDir := iops.getWritableTempDir("task-manager-db")
-	dumpFile := tempDir + "/infrahubops_prefect.dump"
+	databases := append([]string{iops.config.PostgresDatabase}, extraDatabases...)
+	for _, database := range databases {
+		if err := iops.restorePostgresDatabase(workDir, client, database); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Copy dump to container
-	dumpPath := filepath.Join(workDir, "backup", "prefect.dump")
-	if err := iops.CopyTo("task-manager-db", dumpPath, dumpFile); err != nil {
-		return fmt.Errorf("failed to copy dump to container: %w", err)
+func (iops *InfrahubOps) restorePostgresDatabase(workDir, client, database string) error {
+	if err := checkTaskManagerDatabaseName(database); err != nil {
+		return err
+	}
+	logrus.WithField("database", database).Info("Restoring PostgreSQL database...")
+
+	// A local client reads the dump directly; a container client reads it from stdin,
+	// which avoids copying the dump into the container first.
+	dumpPath := filepath.Join(workDir, "backup", iops.taskManagerDumpFilename(database))
+	dumpArg := dumpPath
+	if client != "" {
+		dumpArg = ""
 	}
-	defer func() {
-		if _, err := iops.Exec("task-manager-db", []string{"rm", dumpFile}, nil); err != nil {
-			logrus.Warnf("Failed to remove temporary postgres dump: %v", err)
-		}
-	}()
 
 	// Restore database
-	opts := &ExecOptions{Env: map[string]string{
-		"PGPASSWORD": iops.config.PostgresPassword,
-	}}
-	if output, err := iops.Exec(
-		"task-manager-db",
-		// "-x", "--no-owner" for role does not exist
-		[]string{"pg_restore", "-h", "localhost", "-d", "postgres", "-U", iops.config.PostgresUsername, "--clean", "--create", dumpFile},
-		opts,
-	); err != nil {
-		return fmt.Errorf("failed to restore postgresql: %w\nOutput: %v", err, output)
+	args, err := iops.pgRestoreArgs(dumpArg, database)
+	if err != nil {
+		return err
+	}
+	clientName := client
+	if clientName == "" {
+		clientName = postgresClientLocal
+	}
+	logrus.WithFields(logrus.Fields{
+		"command": "PGPASSWORD=<redacted> " + strings.Join(args, " "),
+		"client":  clientName,
+	}).Info("Running pg_restore")
+
+	env := map[string]string{"PGPASSWORD": iops.config.PostgresPassword}
+	var output string
+	if client == "" {
+		output, err = iops.executor.runCommandEnv(env, args[0], args[1:]...)
+	} else {
+		dump, openErr := os.Open(dumpPath)
+		if openErr != nil {
+			return fmt.Errorf("failed to open postgresql dump: %w", openErr)
+		}
+		defer dump.Close()
+		output, err = iops.Exec(client, args, &ExecOptions{Env: env, Stdin: dump})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore postgresql database %s: %w\nOutput: %v", database, err, output)
 	}
 
 	return nil
 }
+
+// pgRestoreManagedFlags are set by the tool itself and cannot be passed through --pg-restore-opts.
+var pgRestoreManagedFlags = []string{"-d", "--dbname", "-h", "--host", "-p", "--port", "-U", "--username", "-c", "--clean", "-C", "--create", "-f", "--file"}
+
+// pgRestoreShortArgFlags are the short pg_restore options that take an argument.
+const pgRestoreShortArgFlags = "dfFhIjLnNpPStTU"
+
+// pgRestoreOptionNames returns the options that one --pg-restore-opts word sets, the way
+// pg_restore's getopt reads it: -cv is -c and -v, the argument of -dfoo is foo, and a long
+// option may be abbreviated, so --cre is --create. Abbreviations are resolved against the
+// managed flags and --single-transaction only, since those are the ones checked.
+func pgRestoreOptionNames(word string) []string {
+	if long, ok := strings.CutPrefix(word, "--"); ok {
+		name, _, _ := strings.Cut(long, "=")
+		if name == "" {
+			return nil
+		}
+		for _, known := range append(slices.Clone(pgRestoreManagedFlags), "--single-transaction") {
+			if strings.HasPrefix(known, "--"+name) {
+				return []string{known}
+			}
+		}
+		return []string{"--" + name}
+	}
+	letters, ok := strings.CutPrefix(word, "-")
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, letter := range letters {
+		names = append(names, "-"+string(letter))
+		if strings.ContainsRune(pgRestoreShortArgFlags, letter) {
+			break // the rest of the word is the argument
+		}
+	}
+	return names
+}
+
+// pgRestoreArgs builds the pg_restore command line for the dump of database; an empty dumpFile
+// reads the dump from stdin. By default the dump is restored with --clean --create through the
+// "postgres" maintenance database. Without --create, -d names the existing database to restore
+// into, which is what managed Postgres services require; --pg-restore-db then only renames the
+// Prefect database target.
+func (iops *InfrahubOps) pgRestoreArgs(dumpFile, database string) ([]string, error) {
+	extra := strings.Fields(iops.config.PgRestoreOpts)
+	for _, opt := range extra {
+		for _, name := range pgRestoreOptionNames(opt) {
+			if slices.Contains(pgRestoreManagedFlags, name) {
+				return nil, fmt.Errorf("--pg-restore-opts must not contain %s (in %q); use the dedicated --pg-* options instead", name, opt)
+			}
+			if !iops.config.PgRestoreNoCreate && (name == "-1" || name == "--single-transaction") {
+				return nil, fmt.Errorf("%s cannot be combined with --create; add --pg-no-create", opt)
+			}
+		}
+	}
+
+	target := "postgres"
+	if iops.config.PgRestoreNoCreate {
+		target = database
+	}
+	if iops.config.PgRestoreDB != "" && (!iops.config.PgRestoreNoCreate || database == iops.config.PostgresDatabase) {
+		target = iops.config.PgRestoreDB
+	}
+
+	// "-x", "--no-owner" for role does not exist
+	args := append([]string{iops.config.tool(pgRestoreTool)}, iops.pgConnectionArgs()...)
+	args = append(args, "-d", target, "-U", iops.config.PostgresUsername)
+	if !iops.config.PgRestoreNoClean {
+		args = append(args, "--clean")
+	}
+	if !iops.config.PgRestoreNoCreate {
+		args = append(args, "--create")
+	}
+	args = append(args, extra...)
+	if dumpFile != "" {
+		args = append(args, dumpFile)
+	}
+	return args, nil
+}
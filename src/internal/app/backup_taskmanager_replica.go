@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultPgReplicaMaxLag = time.Minute
+
+// replicaStatusQuery returns whether the server is a standby and how many seconds of WAL it
+// has received but not replayed yet. A standby that has replayed everything it received
+// reports no lag, even when the primary has been idle for a while.
+const replicaStatusQuery = "SELECT pg_is_in_recovery(), CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 " +
+	"ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END"
+
+// pgDumpConnectionArgs returns the host and port arguments for pg_dump: those of the replica
+// with --pg-prefer-replica when it is a standby that is caught up, those of the primary
+// otherwise. Restores always use pgConnectionArgs.
+func (iops *InfrahubOps) pgDumpConnectionArgs() []string {
+	if !iops.config.PgPreferReplica {
+		return iops.pgConnectionArgs()
+	}
+	replica := []string{"-h", iops.config.PostgresReplicaHost, "-p", strconv.Itoa(iops.pgReplicaPort())}
+	lag, err := iops.pgReplicaLag(replica)
+	if err == nil && lag > iops.config.PgReplicaMaxLag {
+		err = fmt.Errorf("replica is %s behind, more than --pg-replica-max-lag %s", lag, iops.config.PgReplicaMaxLag)
+	}
+	if err != nil {
+		logrus.Warnf("Dumping the task manager database from the primary instead of replica %s: %v", iops.config.PostgresReplicaHost, err)
+		iops.report.Load().set("Task manager dump source", "primary (replica unusable: "+err.Error()+")")
+		return iops.pgConnectionArgs()
+	}
+	logrus.WithFields(logrus.Fields{"replica": iops.config.PostgresReplicaHost, "lag": lag.String()}).Info("Dumping the task manager database from the replica")
+	iops.report.Load().set("Task manager dump source", fmt.Sprintf("replica %s (lag %s)", iops.config.PostgresReplicaHost, lag))
+	return replica
+}
+
+// pgReplicaPort returns the replica port, which defaults to the port of the primary.
+func (iops *InfrahubOps) pgReplicaPort() int {
+	if iops.config.PostgresReplicaPort != 0 {
+		return iops.config.PostgresReplicaPort
+	}
+	if iops.config.PostgresPort != 0 {
+		return iops.config.PostgresPort
+	}
+	return defaultPostgresPort
+}
+
+// pgReplicaLag checks with psql, next to pg_dump, that the server behind connArgs is a standby
+// and returns how far its replay lags behind.
+func (iops *InfrahubOps) pgReplicaLag(connArgs []string) (time.Duration, error) {
+	pgDump := iops.config.tool(pgDumpTool)
+	client, err := iops.postgresClient(pgDump)
+	if err != nil {
+		return 0, err
+	}
+	psql := "psql"
+	if iops.config.isToolOverridden(pgDumpTool) {
+		psql = path.Join(path.Dir(pgDump), "psql")
+	}
+
+	args := append([]string{psql, "-At", "-F", " "}, connArgs...)
+	args = append(args, "-U", iops.config.PostgresUsername, "-d", iops.config.PostgresDatabase, "-c", replicaStatusQuery)
+	env := map[string]string{"PGPASSWORD": iops.config.PostgresPassword}
+	var output string
+	if client == "" {
+		output, err = iops.executor.runCommandEnv(env, args[0], args[1:]...)
+	} else {
+		output, err = iops.Exec(client, args, &ExecOptions{Env: env})
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query the replica status: %w\nOutput: %v", err, output)
+	}
+
+	fields := strings.Fields(strings.TrimSpace(output))
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("unexpected replica status %q", strings.TrimSpace(output))
+	}
+	if fields[0] != "t" {
+		return 0, fmt.Errorf("%s is not a standby (pg_is_in_recovery is false)", iops.config.PostgresReplicaHost)
+	}
+	seconds, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected replica lag %q: %w", fields[1], err)
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second), nil
+}
+
+// checkPgReplicaConfig rejects --pg-prefer-replica without a replica to prefer.
+func (iops *InfrahubOps) checkPgReplicaConfig() error {
+	if iops.config.PgPreferReplica && iops.config.PostgresReplicaHost == "" {
+		return fmt.Errorf("%w: --pg-prefer-replica requires --postgres-replica-host", ErrPrerequisites)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestTaskManagerAbsence(t *testing.T) {
+	tests := []struct {
+		name       string
+		fake       *apptest.FakeBackend
+		clientSvc  string
+		wantAbsent bool
+	}{
+		{name: "running", fake: apptest.NewFakeBackend("database", taskManagerDBService)},
+		{name: "stopped", fake: apptest.NewFakeBackend("database").AddStopped(taskManagerDBService)},
+		{name: "no such service", fake: apptest.NewFakeBackend("database"), wantAbsent: true},
+		{name: "client service", fake: apptest.NewFakeBackend("database"), clientSvc: "task-manager"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iops := newTestOps(t, tt.fake)
+			iops.config.PostgresClientService = tt.clientSvc
+
+			absence := iops.taskManagerAbsence()
+			if (absence != "") != tt.wantAbsent {
+				t.Errorf("taskManagerAbsence() = %q, want absent %t", absence, tt.wantAbsent)
+			}
+			if tt.wantAbsent && !strings.Contains(absence, "does not exist") {
+				t.Errorf("taskManagerAbsence() = %q, want it to say the service does not exist", absence)
+			}
+		})
+	}
+}
+
+// A stopped task-manager-db is not skipped: the preflight names it instead.
+func TestCheckBackupServicesStoppedTaskManager(t *testing.T) {
+	fake := apptest.NewFakeBackend("database").AddStopped(taskManagerDBService)
+	iops := newTestOps(t, fake)
+
+	err := iops.checkBackupServices(true, false)
+	if err == nil || !strings.Contains(err.Error(), taskManagerDBService) {
+		t.Errorf("checkBackupServices() = %v, want an error naming %s", err, taskManagerDBService)
+	}
+}
+
+func TestPgRestoreArgsRejectsManagedFlags(t *testing.T) {
+	tests := []struct {
+		opts     string
+		noCreate bool
+		wantErr  bool
+	}{
+		{opts: "--no-owner -x"},
+		{opts: "-j 4 -v"},
+		{opts: "-Ox"},
+		{opts: "-Ofoo", wantErr: true}, // -O takes no argument, so f is the -f option
+		{opts: "-d prefect", wantErr: true},
+		{opts: "-dprefect", wantErr: true},
+		{opts: "-hdb.internal", wantErr: true},
+		{opts: "-Uadmin", wantErr: true},
+		{opts: "-cv", wantErr: true},
+		{opts: "-vC", wantErr: true},
+		{opts: "-xc", wantErr: true},
+		{opts: "-jd"}, // d is the argument of -j
+		{opts: "--dbname=prefect", wantErr: true},
+		{opts: "--cre", wantErr: true},
+		{opts: "--use=admin", wantErr: true},
+		{opts: "-1v", wantErr: true},
+		{opts: "--single", wantErr: true},
+		{opts: "-1v", noCreate: true},
+		{opts: "--single-transaction", noCreate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.opts, func(t *testing.T) {
+			iops := newTestOps(t, apptest.NewFakeBackend())
+			iops.config.PgRestoreOpts = tt.opts
+			iops.config.PgRestoreNoCreate = tt.noCreate
+			_, err := iops.pgRestoreArgs("", iops.config.PostgresDatabase)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("pgRestoreArgs with --pg-restore-opts %q = %v, want error %t", tt.opts, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRestoreRejectsUnsafeTaskManagerDatabase(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "backup"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	metadata := BackupMetadata{Components: []string{"task-manager-db", taskManagerExtraComponentPrefix + "x --clean"}}
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "backup", backupMetadataFilename), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := apptest.NewFakeBackend("database", "task-manager-db")
+	iops := newTestOps(t, fake)
+	iops.config.ConfirmDestructive = true
+	err = iops.RestoreFromDirectory(dir, true, false)
+	if err == nil || !strings.Contains(err.Error(), `unsupported task manager database name "x --clean"`) {
+		t.Fatalf("RestoreFromDirectory = %v, want the database name rejected", err)
+	}
+	for _, call := range fake.Calls() {
+		if call.Method == "Stop" || call.Service == "task-manager-db" {
+			t.Errorf("restore went on to %s %s before the name was checked", call.Method, call.Service)
+		}
+	}
+
+	if err := iops.restorePostgresDatabase(dir, "", "x --clean"); err == nil {
+		t.Error("restorePostgresDatabase accepted an unsafe database name")
+	}
+}
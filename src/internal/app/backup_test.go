@@ -0,0 +1,32 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestRestoreConfirmationPrompt(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	const archive = "/tmp/infrahub_download_1/infrahub_backup_20250601T020000Z.tar.gz"
+
+	local := iops.restoreConfirmationPrompt(archive, "", "docker", "infrahub")
+	if strings.Contains(local, "downloaded") {
+		t.Errorf("prompt for a local archive mentions a download:\n%s", local)
+	}
+	if !strings.Contains(local, "WIPE") || !strings.HasSuffix(local, `Type "infrahub" to proceed: `) {
+		t.Errorf("prompt for a local archive = %q, want the wipe warning and the confirmation request", local)
+	}
+
+	const origin = "s3://backups/prod/infrahub_backup_20250601T020000Z.tar.gz"
+	remote := iops.restoreConfirmationPrompt(archive, origin, "docker", "infrahub")
+	if !strings.Contains(remote, origin+" has been downloaded") || !strings.Contains(remote, "downloaded copy is deleted") {
+		t.Errorf("prompt for an S3 archive = %q, want it to say the archive was already downloaded", remote)
+	}
+
+	iops.config.KeepTemp = true
+	if kept := iops.restoreConfirmationPrompt(archive, origin, "docker", "infrahub"); strings.Contains(kept, "is deleted") {
+		t.Errorf("prompt with --keep-temp = %q, want no deletion notice", kept)
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWarmupTimeout = 10 * time.Minute
+
+	// neo4jIndexStatusQuery counts the indexes of the current database by state.
+	neo4jIndexStatusQuery = "SHOW INDEXES YIELD state RETURN state, count(*)"
+	// neo4jWarmupQuery reads a bounded sample of nodes and their relationships so the first
+	// user queries do not pay for loading the store from disk.
+	neo4jWarmupQuery = "MATCH (n) WITH n LIMIT 100000 OPTIONAL MATCH (n)-[r]-() RETURN count(DISTINCT n), count(r)"
+)
+
+// warmupNeo4j waits for the restored database to accept queries and for its indexes to come
+// online, then runs a warmup query (--warmup). It returns a one-line index status for the
+// report. Failures are returned but leave the restored data untouched.
+func (iops *InfrahubOps) warmupNeo4j(timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		timeout = defaultWarmupTimeout
+	}
+	logrus.WithField("timeout", timeout).Info("Warming up Neo4j...")
+	deadline := time.Now().Add(timeout)
+
+	if err := iops.waitForNeo4jQueries(timeout); err != nil {
+		return "", err
+	}
+
+	remaining := max(int(time.Until(deadline).Seconds()), 1)
+	if output, err := iops.cypherQuery(fmt.Sprintf("CALL db.awaitIndexes(%d)", remaining)); err != nil {
+		logrus.Warnf("Not all Neo4j indexes came online within %s: %v %s", timeout, err, strings.TrimSpace(output))
+	}
+
+	output, err := iops.cypherQuery(neo4jIndexStatusQuery)
+	if err != nil {
+		return "", fmt.Errorf("failed to query neo4j index status: %w\nOutput: %v", err, output)
+	}
+	states := parseIndexStates(output)
+	status := formatIndexStates(states)
+	if states["FAILED"] > 0 {
+		logrus.WithField("indexes", status).Warn("Some Neo4j indexes failed to populate; check them with SHOW INDEXES")
+	} else {
+		logrus.WithField("indexes", status).Info("Neo4j index status")
+	}
+
+	start := time.Now()
+	if output, err := iops.cypherQuery(neo4jWarmupQuery); err != nil {
+		return status, fmt.Errorf("neo4j warmup query failed: %w\nOutput: %v", err, output)
+	}
+	logrus.WithField("duration", time.Since(start).Round(time.Millisecond)).Info("Neo4j warmup completed")
+	return status, nil
+}
+
+// waitForNeo4jQueries polls the Infrahub database until it accepts queries or timeout expires.
+func (iops *InfrahubOps) waitForNeo4jQueries(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for attempts := 1; ; attempts++ {
+		output, err := iops.cypherQuery("RETURN 1")
+		if err == nil {
+			return nil
+		}
+		logrus.Debugf("Neo4j database not ready yet (attempt %d): %v %s", attempts, err, strings.TrimSpace(output))
+		if time.Now().Add(healthPollInterval).After(deadline) {
+			return fmt.Errorf("neo4j database %s did not accept queries within %s: %w", iops.config.Neo4jDatabase, timeout, ErrTimeout)
+		}
+		time.Sleep(healthPollInterval)
+	}
+}
+
+// cypherQuery runs a statement against the Infrahub database with cypher-shell.
+func (iops *InfrahubOps) cypherQuery(statement string) (string, error) {
+	return iops.Exec("database", []string{
+		iops.config.tool(cypherShellTool),
+		"-u", iops.config.Neo4jUsername,
+		"-p" + iops.config.Neo4jPassword,
+		"-d", iops.config.Neo4jDatabase,
+		"--format", "plain",
+		statement,
+	}, nil)
+}
+
+// parseIndexStates reads the plain cypher-shell output of neo4jIndexStatusQuery.
+func parseIndexStates(output string) map[string]int {
+	states := map[string]int{}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for _, line := range lines[min(1, len(lines)):] {
+		state, count, ok := strings.Cut(line, ",")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			continue
+		}
+		states[strings.ToUpper(strings.Trim(strings.TrimSpace(state), `"`))] += n
+	}
+	return states
+}
+
+func formatIndexStates(states map[string]int) string {
+	return fmt.Sprintf("%d online, %d populating, %d failed", states["ONLINE"], states["POPULATING"], states["FAILED"])
+}
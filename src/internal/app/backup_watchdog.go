@@ -3,31 +3,225 @@ package app
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	neo4jPIDFile              = "/var/lib/neo4j/run/neo4j.pid"
-	neo4jRemoteWorkDir        = "/tmp/infrahubops"
-	neo4jRemoteWatchdogBinary = neo4jRemoteWorkDir + "/neo4j_watchdog"
-	neo4jRemoteWatchdogReady  = neo4jRemoteWorkDir + "/neo4j_watchdog.ready"
-	neo4jRemoteWatchdogLog    = neo4jRemoteWorkDir + "/neo4j_watchdog.log"
+	neo4jPIDFile                 = "/var/lib/neo4j/run/neo4j.pid"
+	neo4jRemoteWorkDir           = "/tmp/infrahubops"
+	neo4jRemoteWatchdogBinary    = neo4jRemoteWorkDir + "/neo4j_watchdog"
+	neo4jRemoteWatchdogReady     = neo4jRemoteWorkDir + "/neo4j_watchdog.ready"
+	neo4jRemoteWatchdogLog       = neo4jRemoteWorkDir + "/neo4j_watchdog.log"
+	neo4jRemoteWatchdogHeartbeat = neo4jRemoteWorkDir + "/neo4j_watchdog.heartbeat"
+	neo4jStopProgressInterval    = 10 * time.Second
+	neo4jPortCloseTimeout        = 15 * time.Second
+	neo4jWatchdogLogFilename     = "neo4j_watchdog.log"
+
+	// defaultWatchdogHeartbeatInterval is how often the heartbeat file is touched; the
+	// watchdog resumes Neo4j once it has not been touched for watchdogHeartbeatMisses intervals.
+	defaultWatchdogHeartbeatInterval = 10 * time.Second
+	watchdogHeartbeatMisses          = 6
 )
 
-func selectWatchdogBinary(arch string) ([]byte, error) {
-	switch strings.ToLower(arch) {
-	case "x86_64", "amd64":
-		return neo4jWatchdogLinuxAMD64, nil
-	case "aarch64", "arm64":
-		return neo4jWatchdogLinuxARM64, nil
+// Watchdog modes control how Neo4j is frozen once it releases its store during a Community stop.
+const (
+	watchdogModeBinary = "binary"
+	watchdogModeShell  = "shell"
+	watchdogModeNone   = "none"
+)
+
+// shellWatchdogScript mirrors the watchdog binary using a polling loop: once Neo4j removes
+// its pid file the process is sent SIGSTOP so the container stays up during the dump.
+const shellWatchdogScript = `echo ready > %[1]s; while [ -f %[2]s ]; do sleep 0.2; done; kill -STOP %[3]s && echo "sent SIGSTOP to %[3]s"`
+
+// shellWatchdogHeartbeatScript is appended to shellWatchdogScript when a heartbeat is used:
+// Neo4j is resumed if the heartbeat file goes stale, and the loop exits once it is removed.
+const shellWatchdogHeartbeatScript = `; while [ -f %[1]s ]; do age=$(( $(date +%%s) - $(stat -c %%Y %[1]s 2>/dev/null || date +%%s) )); if [ "$age" -gt %[2]d ]; then kill -CONT %[3]s && echo "heartbeat stale for ${age}s; sent SIGCONT to %[3]s"; exit 0; fi; sleep 1; done`
+
+func resolveWatchdogMode(mode string) (string, error) {
+	switch normalized := strings.ToLower(strings.TrimSpace(mode)); normalized {
+	case "":
+		return watchdogModeBinary, nil
+	case watchdogModeBinary, watchdogModeShell, watchdogModeNone:
+		return normalized, nil
 	default:
-		return nil, fmt.Errorf("unsupported architecture for watchdog: %s", arch)
+		return "", fmt.Errorf("invalid watchdog mode %q (expected binary, shell, or none)", mode)
+	}
+}
+
+// normalizeArch maps `uname -m` style architecture names onto GOARCH names.
+func normalizeArch(arch string) string {
+	normalized := strings.ToLower(strings.TrimSpace(arch))
+	switch normalized {
+	case "x86_64", "x86-64", "x64", "amd64":
+		return "amd64"
+	case "aarch64", "arm64", "armv8", "armv8l":
+		return "arm64"
+	case "ppc64le", "ppc64el", "powerpc64le":
+		return "ppc64le"
+	case "s390x":
+		return "s390x"
+	default:
+		return normalized
+	}
+}
+
+func selectWatchdogBinary(arch string) ([]byte, error) {
+	normalized := normalizeArch(arch)
+	if content, ok := embeddedWatchdogBinaries[normalized]; ok && len(content) > 0 {
+		return content, nil
+	}
+
+	supported := make([]string, 0, len(embeddedWatchdogBinaries))
+	for name, content := range embeddedWatchdogBinaries {
+		if len(content) > 0 {
+			supported = append(supported, name)
+		}
+	}
+	sort.Strings(supported)
+	return nil, fmt.Errorf("unsupported architecture for watchdog: %s (normalized: %s; embedded: %s)", arch, normalized, strings.Join(supported, ", "))
+}
+
+// watchdogHeartbeatTimeout returns how stale the heartbeat may get before the watchdog
+// resumes Neo4j. A zero result disables the heartbeat.
+func watchdogHeartbeatTimeout(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	return interval * watchdogHeartbeatMisses
+}
+
+// startWatchdogHeartbeat touches the heartbeat file periodically until the returned
+// function is called.
+func (iops *InfrahubOps) startWatchdogHeartbeat(interval time.Duration) (func(), error) {
+	if _, err := iops.Exec("database", []string{"touch", neo4jRemoteWatchdogHeartbeat}, nil); err != nil {
+		return nil, fmt.Errorf("failed to create watchdog heartbeat: %w", err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if _, err := iops.Exec("database", []string{"touch", neo4jRemoteWatchdogHeartbeat}, nil); err != nil {
+					logrus.Warnf("Failed to refresh watchdog heartbeat: %v", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+	}, nil
+}
+
+// startBinaryWatchdog deploys the embedded watchdog binary and waits for it to initialize.
+func (iops *InfrahubOps) startBinaryWatchdog(heartbeatTimeout time.Duration) error {
+	arch, err := iops.detectNeo4jArchitecture()
+	if err != nil {
+		return err
+	}
+
+	watchdogBytes, err := selectWatchdogBinary(arch)
+	if err != nil {
+		return err
+	}
+
+	localWatchdog, cleanup, err := writeEmbeddedWatchdog(watchdogBytes)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := iops.CopyTo("database", localWatchdog, neo4jRemoteWatchdogBinary); err != nil {
+		return fmt.Errorf("failed to deploy watchdog binary: %w", err)
+	}
+
+	if _, err := iops.Exec("database", []string{"chmod", "+x", neo4jRemoteWatchdogBinary}, nil); err != nil {
+		return fmt.Errorf("failed to mark watchdog executable: %w", err)
+	}
+
+	iops.clearWatchdogMarkers()
+
+	watchdogArgs := fmt.Sprintf("--ready-file %s", neo4jRemoteWatchdogReady)
+	if heartbeatTimeout > 0 {
+		watchdogArgs += fmt.Sprintf(" --heartbeat-file %s --heartbeat-timeout %s", neo4jRemoteWatchdogHeartbeat, heartbeatTimeout)
+	}
+	watchdogCmd := fmt.Sprintf("nohup %s %s >%s 2>&1 &", neo4jRemoteWatchdogBinary, watchdogArgs, neo4jRemoteWatchdogLog)
+	if _, err := iops.Exec("database", []string{"sh", "-c", watchdogCmd}, nil); err != nil {
+		return fmt.Errorf("failed to start watchdog: %w", err)
+	}
+
+	if err := iops.waitForRemoteFile(neo4jRemoteWatchdogReady, neo4jWatchdogInitTimeout); err != nil {
+		return fmt.Errorf("watchdog failed to initialize: %w", err)
+	}
+
+	return nil
+}
+
+// startShellWatchdog starts a shell-based watchdog for containers that cannot run the binary.
+func (iops *InfrahubOps) startShellWatchdog(pidStr string, heartbeatTimeout time.Duration) error {
+	logrus.Info("Starting shell watchdog for Neo4j")
+	iops.clearWatchdogMarkers()
+
+	script := fmt.Sprintf(shellWatchdogScript, neo4jRemoteWatchdogReady, neo4jPIDFile, pidStr)
+	if heartbeatTimeout > 0 {
+		script += fmt.Sprintf(shellWatchdogHeartbeatScript, neo4jRemoteWatchdogHeartbeat, int(heartbeatTimeout.Seconds()), pidStr)
+	}
+	watchdogCmd := fmt.Sprintf("nohup sh -c %s >%s 2>&1 &", shellQuote(script), neo4jRemoteWatchdogLog)
+	if _, err := iops.Exec("database", []string{"sh", "-c", watchdogCmd}, nil); err != nil {
+		return fmt.Errorf("failed to start shell watchdog: %w", err)
+	}
+
+	if err := iops.waitForRemoteFile(neo4jRemoteWatchdogReady, neo4jWatchdogInitTimeout); err != nil {
+		return fmt.Errorf("shell watchdog failed to initialize: %w", err)
+	}
+
+	return nil
+}
+
+func (iops *InfrahubOps) clearWatchdogMarkers() {
+	if _, err := iops.Exec("database", []string{"rm", "-f", neo4jRemoteWatchdogReady, neo4jRemoteWatchdogLog}, nil); err != nil {
+		logrus.Debugf("Could not clear watchdog markers: %v", err)
 	}
 }
 
+// suspendNeo4jAfterShutdown freezes Neo4j from the tool itself when no watchdog runs in the
+// container. It is best effort: the pid file is polled over exec, so the window is wider.
+func (iops *InfrahubOps) suspendNeo4jAfterShutdown(pid string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := iops.Exec("database", []string{"test", "-f", neo4jPIDFile}, nil); err != nil {
+			if _, err := iops.Exec("database", []string{"kill", "-STOP", pid}, nil); err != nil {
+				return fmt.Errorf("failed to suspend neo4j (pid %s): %w", pid, err)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for neo4j to release %s", neo4jPIDFile)
+}
+
 func writeEmbeddedWatchdog(content []byte) (string, func(), error) {
 	file, err := os.CreateTemp("", "neo4j_watchdog_*")
 	if err != nil {
@@ -66,50 +260,169 @@ func (iops *InfrahubOps) waitForRemoteFile(path string, timeout time.Duration) e
 		}
 		time.Sleep(200 * time.Millisecond)
 	}
-	return fmt.Errorf("timeout waiting for remote file %s", path)
+	return fmt.Errorf("%w waiting for remote file %s", ErrTimeout, path)
 }
 
 func (iops *InfrahubOps) waitForProcessStopped(pid string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
+	start := time.Now()
+	deadline := start.Add(timeout)
+	lastProgress := start
+	lastState := ""
 	for {
 		stateCmd := fmt.Sprintf("sed -n 's/^State:\t//p' /proc/%s/status", pid)
 		state, err := iops.Exec("database", []string{"sh", "-c", stateCmd}, nil)
 		if err == nil {
 			trimmed := strings.TrimSpace(state)
 			if strings.HasPrefix(trimmed, "T") {
+				logrus.Infof("Neo4j process %s stopped after %s", pid, time.Since(start).Round(time.Second))
 				return nil
 			}
+			lastState = trimmed
 		}
 		if time.Now().After(deadline) {
 			break
 		}
+		if time.Since(lastProgress) >= neo4jStopProgressInterval {
+			lastProgress = time.Now()
+			elapsed := time.Since(start).Round(time.Second)
+			remaining := time.Until(deadline).Round(time.Second)
+			fields := logrus.Fields{
+				"pid":       pid,
+				"elapsed":   elapsed.String(),
+				"remaining": remaining.String(),
+			}
+			if lastState != "" {
+				fields["state"] = lastState
+			}
+			logrus.WithFields(fields).Info("Still waiting for Neo4j process to stop...")
+		}
 		time.Sleep(1 * time.Second)
 	}
-	return fmt.Errorf("timed out waiting for neo4j process %s to stop", pid)
+
+	if lastState != "" {
+		return fmt.Errorf("%w after %s waiting for neo4j process %s to stop (last state: %s)", ErrTimeout, timeout, pid, lastState)
+	}
+	return fmt.Errorf("%w after %s waiting for neo4j process %s to stop", ErrTimeout, timeout, pid)
 }
 
-// getWritableTempDir checks if /tmp is writable in the given container/pod.
-// If /tmp is not writable, it falls back to /run.
-func (iops *InfrahubOps) getWritableTempDir(service string) string {
-	// Try to create a test file in /tmp
-	testFile := "/tmp/.infrahubops_write_test"
-	if _, err := iops.Exec(service, []string{"touch", testFile}, nil); err == nil {
-		// Clean up test file
-		_, _ = iops.Exec(service, []string{"rm", "-f", testFile}, nil)
-		logrus.Debugf("Using /tmp as temp directory for %s", service)
-		return "/tmp"
+// neo4jPorts are the Bolt and HTTP ports a running Neo4j listens on.
+var neo4jPorts = []string{"7687", "7474"}
+
+// neo4jListenersScript lists the listening TCP sockets of the database container, or prints
+// noNetToolsMarker when neither ss nor netstat is installed.
+const (
+	noNetToolsMarker     = "no-net-tools"
+	neo4jListenersScript = "if command -v ss >/dev/null 2>&1; then ss -Hltn; elif command -v netstat >/dev/null 2>&1; then netstat -ltn; else echo " + noNetToolsMarker + "; fi"
+)
+
+// waitForNeo4jPortsClosed complements waitForProcessStopped: a stopped process can leave a child
+// or a socket behind, so the dump only starts once nothing listens on the Neo4j ports anymore.
+// The check is skipped when the container has neither ss nor netstat.
+func (iops *InfrahubOps) waitForNeo4jPortsClosed(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		output, err := iops.Exec("database", []string{"sh", "-c", neo4jListenersScript}, nil)
+		if err != nil {
+			logrus.Warnf("Could not list listening ports in the database container; skipping the Neo4j port check: %v", err)
+			return nil
+		}
+		if strings.TrimSpace(output) == noNetToolsMarker {
+			logrus.Info("Neither ss nor netstat is available in the database container; skipping the Neo4j port check")
+			return nil
+		}
+		listening := listeningPorts(output, neo4jPorts)
+		if len(listening) == 0 {
+			logrus.Debug("Neo4j ports are closed")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w after %s waiting for Neo4j to stop listening on port %s", ErrTimeout, timeout, strings.Join(listening, ", "))
+		}
+		logrus.WithField("ports", listening).Debug("Neo4j ports still listening")
+		time.Sleep(1 * time.Second)
 	}
+}
 
-	// /tmp is not writable, try /run
-	testFile = "/run/.infrahubops_write_test"
-	if _, err := iops.Exec(service, []string{"touch", testFile}, nil); err == nil {
-		// Clean up test file
-		_, _ = iops.Exec(service, []string{"rm", "-f", testFile}, nil)
-		logrus.Infof("/tmp is not writable in %s, using /run as temp directory", service)
-		return "/run"
+// listeningPorts returns the ports among ports that ss -ltn or netstat -ltn output shows
+// listening. The local address is the first column holding host:port.
+func listeningPorts(output string, ports []string) []string {
+	var listening []string
+	for _, line := range nonEmptyLines(output) {
+		for _, field := range strings.Fields(line) {
+			idx := strings.LastIndex(field, ":")
+			if idx < 0 {
+				continue
+			}
+			if port := field[idx+1:]; slices.Contains(ports, port) && !slices.Contains(listening, port) {
+				listening = append(listening, port)
+			}
+			break
+		}
+	}
+	return listening
+}
+
+// collectWatchdogLog logs the remote watchdog log and copies it into localDir so
+// a failed Community backup or restore can be diagnosed after the fact.
+func (iops *InfrahubOps) collectWatchdogLog(localDir string) {
+	output, err := iops.Exec("database", []string{"cat", neo4jRemoteWatchdogLog}, nil)
+	if err != nil {
+		logrus.Warnf("Could not read watchdog log %s: %v", neo4jRemoteWatchdogLog, err)
+		return
+	}
+	iops.report.Load().attach(neo4jWatchdogLogFilename, output)
+	if trimmed := strings.TrimSpace(output); trimmed != "" {
+		logrus.Warnf("Watchdog log (%s):\n%s", neo4jRemoteWatchdogLog, trimmed)
+	} else {
+		logrus.Warnf("Watchdog log %s is empty", neo4jRemoteWatchdogLog)
 	}
 
-	// Fall back to /tmp even if both failed (let the actual operation fail with a meaningful error)
-	logrus.Warnf("Neither /tmp nor /run appear writable in %s, defaulting to /tmp", service)
-	return "/tmp"
+	if localDir == "" {
+		return
+	}
+	localPath := filepath.Join(localDir, neo4jWatchdogLogFilename)
+	if err := iops.CopyFrom("database", neo4jRemoteWatchdogLog, localPath); err != nil {
+		logrus.Warnf("Could not copy watchdog log to %s: %v", localPath, err)
+		return
+	}
+	if iops.config.KeepTemp {
+		logrus.Infof("Watchdog log saved to %s", localPath)
+		return
+	}
+	logrus.Infof("Watchdog log content is logged above; pass --keep-temp to keep a copy in %s", localPath)
+}
+
+// defaultTempDirs are probed by getWritableTempDir when the caller passes no candidates.
+var defaultTempDirs = []string{"/tmp", "/var/tmp", "/run"}
+
+// getWritableTempDir returns the first of the candidate directories (defaultTempDirs when
+// none are given) that accepts a test file in the given container/pod. Read-only root
+// filesystems often leave only a mounted volume writable. Empty candidates are skipped.
+func (iops *InfrahubOps) getWritableTempDir(service string, dirs ...string) (string, error) {
+	if len(dirs) == 0 {
+		dirs = defaultTempDirs
+	}
+	candidates := []string{}
+	for _, dir := range dirs {
+		dir = strings.TrimSuffix(dir, "/")
+		if dir != "" && !slices.Contains(candidates, dir) {
+			candidates = append(candidates, dir)
+		}
+	}
+
+	for _, dir := range candidates {
+		testFile := dir + "/.infrahubops_write_test"
+		if _, err := iops.Exec(service, []string{"touch", testFile}, nil); err != nil {
+			logrus.Debugf("%s is not writable in %s: %v", dir, service, err)
+			continue
+		}
+		_, _ = iops.Exec(service, []string{"rm", "-f", testFile}, nil)
+		if dir == candidates[0] {
+			logrus.Debugf("Using %s as temp directory for %s", dir, service)
+		} else {
+			logrus.Infof("Using %s as temp directory for %s", dir, service)
+		}
+		return dir, nil
+	}
+	return "", fmt.Errorf("no writable temp directory in %s (tried %s)", service, strings.Join(candidates, ", "))
 }
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestCollectWatchdogLogMessage(t *testing.T) {
+	for _, keepTemp := range []bool{false, true} {
+		fake := apptest.NewFakeBackend("database")
+		fake.On("database", []string{"cat", neo4jRemoteWatchdogLog}, apptest.Response{Output: "resumed neo4j\n"})
+		fake.SetFile("database", neo4jRemoteWatchdogLog, []byte("resumed neo4j\n"))
+		iops := newTestOps(t, fake)
+		iops.config.KeepTemp = keepTemp
+		workDir := t.TempDir()
+
+		var logs bytes.Buffer
+		out := logrus.StandardLogger().Out
+		logrus.SetOutput(&logs)
+		iops.collectWatchdogLog(workDir)
+		logrus.SetOutput(out)
+
+		saved := strings.Contains(logs.String(), "Watchdog log saved to "+filepath.Join(workDir, neo4jWatchdogLogFilename))
+		if saved != keepTemp {
+			t.Errorf("keep-temp=%v: logs claim the file was saved = %v:\n%s", keepTemp, saved, logs.String())
+		}
+		if !keepTemp && !strings.Contains(logs.String(), "--keep-temp") {
+			t.Errorf("logs without --keep-temp don't mention it:\n%s", logs.String())
+		}
+	}
+}
@@ -3,7 +3,9 @@ package app
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -17,8 +19,68 @@ func ConfigureRootCommand(cmd *cobra.Command, app *InfrahubOps) {
 	cmd.PersistentFlags().StringVar(&cfg.DockerComposeProject, "project", cfg.DockerComposeProject, "Target specific Docker Compose project")
 	cmd.PersistentFlags().StringVar(&cfg.BackupDir, "backup-dir", cfg.BackupDir, "Backup directory")
 	cmd.PersistentFlags().StringVar(&cfg.K8sNamespace, "k8s-namespace", cfg.K8sNamespace, "Target Kubernetes namespace")
+	var k8sSelectors []string
+	cmd.PersistentFlags().StringArrayVar(&k8sSelectors, "k8s-selector", nil, "Label selector for a service's pods as service=selector, e.g. database=app=neo4j (repeatable)")
 	cmd.PersistentFlags().String("log-format", "text", "Log output format: text or json (can also set INFRAHUB_LOG_FORMAT)")
+	cmd.PersistentFlags().Bool("no-color", false, "Disable colors in text logs; also disabled by a non-empty NO_COLOR or when stderr is not a terminal (can also set INFRAHUB_NO_COLOR)")
+	cmd.PersistentFlags().String("log-timestamp-format", time.RFC3339, "Go time layout of log timestamps, e.g. 2006-01-02 15:04:05.000 (can also set INFRAHUB_LOG_TIMESTAMP_FORMAT)")
+	cmd.PersistentFlags().BoolVarP(&cfg.Quiet, "quiet", "q", false, "Only log warnings and errors, and print the final result (can also set INFRAHUB_QUIET)")
+	cmd.PersistentFlags().BoolVar(&cfg.DryRun, "dry-run", false, "Print what prune, s3 cleanup-multipart and --s3-delete-local would delete without deleting anything; restore only validates (can also set INFRAHUB_DRY_RUN)")
+	cmd.PersistentFlags().StringVar(&cfg.AuditLog, "audit-log", "", "Append a JSON line for every container command, copy and start/stop to this file (can also set INFRAHUB_AUDIT_LOG)")
+	cmd.PersistentFlags().StringVar(&cfg.SummaryFile, "summary-file", "", "Write a human-readable report of the backup or restore to this file (can also set INFRAHUB_SUMMARY_FILE)")
+	cmd.PersistentFlags().BoolVar(&cfg.SummaryOnFailure, "summary-on-failure", false, "When a backup or restore fails, write a redacted diagnostic bundle diag-<timestamp>.tar.gz to the backup directory (can also set INFRAHUB_SUMMARY_ON_FAILURE)")
+	cmd.PersistentFlags().StringVar(&cfg.RecordBackend, "record-backend", "", "Record all backend interactions (redacted, without file contents) to this capture file for offline debugging")
+	cmd.PersistentFlags().StringVar(&cfg.ReplayBackend, "replay-backend", "", "Replay a capture written by --record-backend instead of contacting a deployment")
 	cmd.PersistentFlags().BoolVar(&cfg.S3Upload, "s3-upload", false, "Upload backup to S3 (requires S3_* env vars)")
+	// Not bound to viper: INFRAHUB_DB_* and PREFECT_API_DATABASE_CONNECTION_URL remain the
+	// environment variables for the database credentials
+	cmd.PersistentFlags().StringVar(&cfg.Neo4jDatabase, "neo4j-database", "", "Neo4j database name (default INFRAHUB_DB_DATABASE, or read from infrahub-server)")
+	cmd.PersistentFlags().StringVar(&cfg.Neo4jUsername, "neo4j-username", "", "Neo4j username (default INFRAHUB_DB_USERNAME, or read from infrahub-server)")
+	cmd.PersistentFlags().StringVar(&cfg.Neo4jPassword, "neo4j-password", "", "Neo4j password; prefer --neo4j-password-file, since flags are visible in process listings")
+	cmd.PersistentFlags().StringVar(&cfg.PostgresDatabase, "postgres-dbname", "", "Task manager PostgreSQL database (default from PREFECT_API_DATABASE_CONNECTION_URL, or read from task-manager)")
+	cmd.PersistentFlags().StringVar(&cfg.PostgresUsername, "postgres-username", "", "Task manager PostgreSQL username (default from PREFECT_API_DATABASE_CONNECTION_URL, or read from task-manager)")
+	cmd.PersistentFlags().StringVar(&cfg.PostgresPassword, "postgres-password", "", "Task manager PostgreSQL password; prefer --postgres-password-file, since flags are visible in process listings")
+	cmd.PersistentFlags().StringVar(&cfg.Neo4jPasswordFile, "neo4j-password-file", "", "Read the Neo4j password from a file (can also set INFRAHUB_DB_PASSWORD_FILE)")
+	cmd.PersistentFlags().StringVar(&cfg.PostgresPasswordFile, "postgres-password-file", "", "Read the task manager PostgreSQL password from a file (can also set INFRAHUB_POSTGRES_PASSWORD_FILE)")
+	cmd.PersistentFlags().StringVar(&cfg.IntegrityKey, "integrity-key", "", "HMAC key used to sign backup metadata and verify it on restore (can also set INFRAHUB_INTEGRITY_KEY)")
+	cmd.PersistentFlags().StringVar(&cfg.IntegrityKeyFile, "integrity-key-file", "", "Read the metadata signing key from a file (can also set INFRAHUB_INTEGRITY_KEY_FILE)")
+	cmd.PersistentFlags().StringVar(&cfg.IntegrityKeyring, "integrity-keyring", "", "Directory of previous integrity keys, one per file; restore picks the key recorded in the backup metadata (can also set INFRAHUB_INTEGRITY_KEYRING)")
+	cmd.PersistentFlags().StringVar(&cfg.Neo4jEdition, "neo4j-edition", "", "Skip Neo4j edition detection and use community or enterprise, for custom images where detection fails (can also set INFRAHUB_NEO4J_EDITION)")
+	cmd.PersistentFlags().StringVar(&cfg.Neo4jMode, "neo4j-mode", "", "Neo4j access mode: container (exec into the database service) or external (default external when --neo4j-host is set)")
+	cmd.PersistentFlags().StringVar(&cfg.Neo4jHost, "neo4j-host", "", "Bolt URI of an external Neo4j, e.g. neo4j+s://xxxx.databases.neo4j.io (can also set INFRAHUB_NEO4J_HOST)")
+	cmd.PersistentFlags().StringVar(&cfg.PostgresHost, "postgres-host", cfg.PostgresHost, "Task manager PostgreSQL host; a non-local host is treated as an external database (can also set INFRAHUB_POSTGRES_HOST)")
+	cmd.PersistentFlags().IntVar(&cfg.PostgresPort, "postgres-port", cfg.PostgresPort, "Task manager PostgreSQL port (can also set INFRAHUB_POSTGRES_PORT)")
+	cmd.PersistentFlags().StringVar(&cfg.PostgresReplicaHost, "postgres-replica-host", "", "Read replica of the task manager PostgreSQL that --pg-prefer-replica dumps from (can also set INFRAHUB_POSTGRES_REPLICA_HOST)")
+	cmd.PersistentFlags().IntVar(&cfg.PostgresReplicaPort, "postgres-replica-port", 0, "Port of --postgres-replica-host (default --postgres-port; can also set INFRAHUB_POSTGRES_REPLICA_PORT)")
+	cmd.PersistentFlags().StringVar(&cfg.PostgresClientService, "postgres-client-service", "", "Service in which pg_dump/pg_restore run, or \"local\" (default task-manager-db, or local for an external host)")
+	cmd.PersistentFlags().IntVar(&cfg.CopyRetries, "copy-retries", cfg.CopyRetries, "Retry a failed copy to or from a container this many times (can also set INFRAHUB_COPY_RETRIES)")
+	cmd.PersistentFlags().IntVar(&cfg.MaxOpenFiles, "max-open-files", cfg.MaxOpenFiles, "Maximum backup files held open at once while computing and validating checksums (can also set INFRAHUB_MAX_OPEN_FILES)")
+	cmd.PersistentFlags().StringVar(&cfg.Neo4jAdminPath, "neo4j-admin-path", cfg.Neo4jAdminPath, "neo4j-admin command in the database container (can also set INFRAHUB_NEO4J_ADMIN_PATH)")
+	cmd.PersistentFlags().StringVar(&cfg.CypherShellPath, "cypher-shell-path", cfg.CypherShellPath, "cypher-shell command in the database container (can also set INFRAHUB_CYPHER_SHELL_PATH)")
+	cmd.PersistentFlags().StringVar(&cfg.PgDumpPath, "pg-dump-path", cfg.PgDumpPath, "pg_dump command in the PostgreSQL client service or locally (can also set INFRAHUB_PG_DUMP_PATH)")
+	cmd.PersistentFlags().StringVar(&cfg.PgRestorePath, "pg-restore-path", cfg.PgRestorePath, "pg_restore command in the PostgreSQL client service or locally (can also set INFRAHUB_PG_RESTORE_PATH)")
+	cmd.PersistentFlags().StringVar(&cfg.KubectlPath, "kubectl-path", cfg.KubectlPath, "kubectl command run by this tool (can also set INFRAHUB_KUBECTL_PATH)")
+	cmd.PersistentFlags().StringVar(&cfg.DockerPath, "docker-path", cfg.DockerPath, "docker command run by this tool (can also set INFRAHUB_DOCKER_PATH)")
+	cmd.PersistentFlags().StringVar(&cfg.S3Prefix, "s3-prefix", "", "Key prefix (folder) for backups in the S3 bucket, e.g. prod/infrahub (can also set S3_PREFIX)")
+	cmd.PersistentFlags().BoolVar(&cfg.S3DatePartition, "s3-date-partition", false, "Upload backups under year=YYYY/month=MM/day=DD/ below the S3 prefix (can also set S3_DATE_PARTITION)")
+	cmd.PersistentFlags().StringVar(&cfg.S3ContentType, "s3-content-type", "", "Content-Type of uploaded archives (default detected from the file, e.g. application/gzip; can also set S3_CONTENT_TYPE)")
+	cmd.PersistentFlags().StringVar(&cfg.S3SigningRegion, "s3-signing-region", "", "Region used to sign S3 requests when the provider expects one other than S3_REGION (can also set S3_SIGNING_REGION)")
+	cmd.PersistentFlags().StringVar(&cfg.S3Proxy, "s3-proxy", "", "HTTP(S) proxy URL for S3 requests, overriding HTTPS_PROXY/NO_PROXY (can also set S3_PROXY)")
+	cmd.PersistentFlags().IntVar(&cfg.S3MaxRetries, "s3-max-retries", cfg.S3MaxRetries, "Maximum retries per S3 request (can also set S3_MAX_RETRIES)")
+	cmd.PersistentFlags().DurationVar(&cfg.S3HTTPTimeout, "s3-http-timeout", cfg.S3HTTPTimeout, "Connect/response-header timeout per S3 request, 0 disables (can also set S3_HTTP_TIMEOUT)")
+	cmd.PersistentFlags().StringVar(&cfg.S3CABundle, "s3-ca-bundle", "", "PEM file of CA certificates to trust for the S3 endpoint, in addition to the system roots (can also set S3_CA_BUNDLE)")
+	cmd.PersistentFlags().BoolVar(&cfg.S3InsecureSkipVerify, "s3-insecure-skip-verify", false, "Do not verify the S3 endpoint's TLS certificate; for development and testing only (can also set S3_INSECURE_SKIP_VERIFY)")
+	// Kept so existing scripts don't fail: no AWS_* variable is set any more
+	cmd.PersistentFlags().Bool("no-s3-compatibility-env", false, "No effect; S3-compatible endpoints are configured on the client without AWS_* variables")
+	cmd.PersistentFlags().MarkDeprecated("no-s3-compatibility-env", "it has no effect: S3-compatible endpoints no longer set AWS_* variables")
+	cmd.PersistentFlags().BoolVar(&cfg.S3ResumeUpload, "resume-upload", false, "Upload to S3 in checkpointed parts and resume an interrupted upload of the same archive")
+	cmd.PersistentFlags().DurationVar(&cfg.S3ResumeMaxAge, "resume-max-age", cfg.S3ResumeMaxAge, "Abort and restart an interrupted upload older than this (0 never expires)")
+	cmd.PersistentFlags().BoolVar(&cfg.S3DeleteLocal, "s3-delete-local", false, "Delete the local archive after it was uploaded to S3 (can also set INFRAHUB_S3_DELETE_LOCAL)")
+	cmd.PersistentFlags().BoolVar(&cfg.VerifyS3BeforeDeleteLocal, "verify-s3-before-delete-local", cfg.VerifyS3BeforeDeleteLocal, "Before --s3-delete-local deletes the archive, check the size and SHA-256 of the uploaded object; =false also requires --force-delete-local")
+	cmd.PersistentFlags().BoolVar(&cfg.ForceDeleteLocal, "force-delete-local", false, "Let --s3-delete-local delete the archive with --verify-s3-before-delete-local=false")
+	cmd.PersistentFlags().StringVar(&cfg.S3SecretFile, "s3-secret-file", "", "Read the S3 secret access key from a file (can also set S3_SECRET_ACCESS_KEY_FILE)")
+	cmd.PersistentFlags().StringVar(&cfg.S3SharedCredentialsFile, "aws-shared-credentials-file", "", "AWS shared credentials file to read S3 credentials from when S3_ACCESS_KEY_ID is not set (can also set INFRAHUB_AWS_SHARED_CREDENTIALS_FILE)")
+	cmd.PersistentFlags().StringVar(&cfg.S3SharedConfigFile, "aws-config-file", "", "AWS config file to read the S3 profile settings from when S3_ACCESS_KEY_ID is not set (can also set INFRAHUB_AWS_CONFIG_FILE)")
 
 	bind := func(name string) {
 		if err := viper.BindPFlag(name, cmd.PersistentFlags().Lookup(name)); err != nil {
@@ -30,7 +92,51 @@ func ConfigureRootCommand(cmd *cobra.Command, app *InfrahubOps) {
 	bind("backup-dir")
 	bind("k8s-namespace")
 	bind("log-format")
+	bind("no-color")
+	bind("log-timestamp-format")
+	bind("quiet")
+	bind("dry-run")
+	bind("audit-log")
+	bind("summary-file")
+	bind("summary-on-failure")
 	bind("s3-upload")
+	bind("neo4j-password-file")
+	bind("postgres-password-file")
+	bind("s3-secret-file")
+	bind("aws-shared-credentials-file")
+	bind("aws-config-file")
+	bind("integrity-key")
+	bind("integrity-key-file")
+	bind("integrity-keyring")
+	bind("neo4j-mode")
+	bind("neo4j-edition")
+	bind("neo4j-host")
+	bind("postgres-host")
+	bind("postgres-port")
+	bind("postgres-replica-host")
+	bind("postgres-replica-port")
+	bind("postgres-client-service")
+	bind("copy-retries")
+	bind("max-open-files")
+	bind("neo4j-admin-path")
+	bind("cypher-shell-path")
+	bind("pg-dump-path")
+	bind("pg-restore-path")
+	bind("kubectl-path")
+	bind("docker-path")
+	bind("s3-proxy")
+	bind("s3-signing-region")
+	bind("s3-prefix")
+	bind("s3-content-type")
+	bind("s3-date-partition")
+	bind("s3-max-retries")
+	bind("s3-http-timeout")
+	bind("s3-ca-bundle")
+	bind("s3-insecure-skip-verify")
+	bind("resume-upload")
+	bind("resume-max-age")
+	bind("s3-delete-local")
+	bind("verify-s3-before-delete-local")
 
 	cobra.OnInitialize(func() {
 		viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
@@ -49,19 +155,153 @@ func ConfigureRootCommand(cmd *cobra.Command, app *InfrahubOps) {
 		if viper.IsSet("s3-upload") {
 			cfg.S3Upload = viper.GetBool("s3-upload")
 		}
+		if viper.IsSet("neo4j-password-file") {
+			cfg.Neo4jPasswordFile = viper.GetString("neo4j-password-file")
+		} else if path := os.Getenv("INFRAHUB_DB_PASSWORD_FILE"); path != "" {
+			cfg.Neo4jPasswordFile = path
+		}
+		if viper.IsSet("postgres-password-file") {
+			cfg.PostgresPasswordFile = viper.GetString("postgres-password-file")
+		} else if path := os.Getenv("INFRAHUB_POSTGRES_PASSWORD_FILE"); path != "" {
+			cfg.PostgresPasswordFile = path
+		}
+		if viper.IsSet("integrity-key") {
+			cfg.IntegrityKey = viper.GetString("integrity-key")
+		}
+		if viper.IsSet("integrity-key-file") {
+			cfg.IntegrityKeyFile = viper.GetString("integrity-key-file")
+		}
+		if viper.IsSet("integrity-keyring") {
+			cfg.IntegrityKeyring = viper.GetString("integrity-keyring")
+		}
+		if viper.IsSet("neo4j-mode") {
+			cfg.Neo4jMode = viper.GetString("neo4j-mode")
+		}
+		if viper.IsSet("neo4j-edition") {
+			cfg.Neo4jEdition = viper.GetString("neo4j-edition")
+		}
+		if viper.IsSet("neo4j-host") {
+			cfg.Neo4jHost = viper.GetString("neo4j-host")
+		}
+		if viper.IsSet("postgres-host") {
+			cfg.PostgresHost = viper.GetString("postgres-host")
+		}
+		if viper.IsSet("postgres-port") {
+			cfg.PostgresPort = viper.GetInt("postgres-port")
+		}
+		if viper.IsSet("postgres-replica-host") {
+			cfg.PostgresReplicaHost = viper.GetString("postgres-replica-host")
+		}
+		if viper.IsSet("postgres-replica-port") {
+			cfg.PostgresReplicaPort = viper.GetInt("postgres-replica-port")
+		}
+		if viper.IsSet("postgres-client-service") {
+			cfg.PostgresClientService = viper.GetString("postgres-client-service")
+		}
+		if viper.IsSet("copy-retries") {
+			cfg.CopyRetries = viper.GetInt("copy-retries")
+		}
+		if viper.IsSet("max-open-files") {
+			cfg.MaxOpenFiles = viper.GetInt("max-open-files")
+		}
+		app.files = newFileLimiter(cfg.MaxOpenFiles)
+		if viper.IsSet("neo4j-admin-path") {
+			cfg.Neo4jAdminPath = viper.GetString("neo4j-admin-path")
+		}
+		if viper.IsSet("cypher-shell-path") {
+			cfg.CypherShellPath = viper.GetString("cypher-shell-path")
+		}
+		if viper.IsSet("pg-dump-path") {
+			cfg.PgDumpPath = viper.GetString("pg-dump-path")
+		}
+		if viper.IsSet("pg-restore-path") {
+			cfg.PgRestorePath = viper.GetString("pg-restore-path")
+		}
+		if viper.IsSet("kubectl-path") {
+			cfg.KubectlPath = viper.GetString("kubectl-path")
+		}
+		if viper.IsSet("docker-path") {
+			cfg.DockerPath = viper.GetString("docker-path")
+		}
+
+		if viper.IsSet("resume-upload") {
+			cfg.S3ResumeUpload = viper.GetBool("resume-upload")
+		}
+		if viper.IsSet("resume-max-age") {
+			cfg.S3ResumeMaxAge = viper.GetDuration("resume-max-age")
+		}
+		if viper.IsSet("s3-delete-local") {
+			cfg.S3DeleteLocal = viper.GetBool("s3-delete-local")
+		}
+		if viper.IsSet("verify-s3-before-delete-local") {
+			cfg.VerifyS3BeforeDeleteLocal = viper.GetBool("verify-s3-before-delete-local")
+		}
+
+		cfg.K8sSelectors = parseK8sSelectors(k8sSelectors)
 
 		// Load S3 configuration from environment variables
 		loadS3Config(cfg)
 
+		if viper.IsSet("audit-log") {
+			cfg.AuditLog = viper.GetString("audit-log")
+		}
+		if viper.IsSet("summary-file") {
+			cfg.SummaryFile = viper.GetString("summary-file")
+		}
+		if viper.IsSet("summary-on-failure") {
+			cfg.SummaryOnFailure = viper.GetBool("summary-on-failure")
+		}
+		if viper.IsSet("quiet") {
+			cfg.Quiet = viper.GetBool("quiet")
+		}
+		if viper.IsSet("dry-run") {
+			cfg.DryRun = viper.GetBool("dry-run")
+		}
+		if cfg.Quiet {
+			logrus.SetLevel(logrus.WarnLevel)
+		}
+
+		timestampFormat := viper.GetString("log-timestamp-format")
 		switch viper.GetString("log-format") {
 		case "json":
-			logrus.SetFormatter(&logrus.JSONFormatter{})
+			logrus.SetFormatter(&logrus.JSONFormatter{TimestampFormat: timestampFormat})
 		default:
-			logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+			logrus.SetFormatter(&logrus.TextFormatter{
+				FullTimestamp:   true,
+				TimestampFormat: timestampFormat,
+				DisableColors:   !textLogColors(viper.GetBool("no-color")),
+			})
 		}
+
+		applyEnvironmentContext(cfg)
 	})
 }
 
+// textLogColors reports whether text logs may use ANSI colors: not with --no-color, with a
+// non-empty NO_COLOR (https://no-color.org), or when stderr is not a terminal.
+func textLogColors(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isInteractiveTerminal(os.Stderr)
+}
+
+// parseK8sSelectors parses service=selector pairs. Only the first "=" separates the service,
+// so the selector itself may contain "=" and "," (e.g. database=app=neo4j,tier=db).
+func parseK8sSelectors(values []string) map[string]string {
+	selectors := map[string]string{}
+	for _, value := range values {
+		service, selector, ok := strings.Cut(value, "=")
+		service, selector = strings.TrimSpace(service), strings.TrimSpace(selector)
+		if !ok || service == "" || selector == "" {
+			logrus.Warnf("Ignoring invalid --k8s-selector %q (expected service=selector)", value)
+			continue
+		}
+		selectors[service] = selector
+	}
+	return selectors
+}
+
 // loadS3Config loads S3 configuration from environment variables
 func loadS3Config(cfg *Configuration) {
 	if bucket := os.Getenv("S3_BUCKET"); bucket != "" {
@@ -76,11 +316,84 @@ func loadS3Config(cfg *Configuration) {
 	if secretKey := os.Getenv("S3_SECRET_ACCESS_KEY"); secretKey != "" {
 		cfg.S3SecretKey = secretKey
 	}
+	if secretFile := viper.GetString("s3-secret-file"); secretFile != "" {
+		cfg.S3SecretFile = secretFile
+	} else if secretFile := os.Getenv("S3_SECRET_ACCESS_KEY_FILE"); secretFile != "" {
+		cfg.S3SecretFile = secretFile
+	}
+	if file := viper.GetString("aws-shared-credentials-file"); file != "" {
+		cfg.S3SharedCredentialsFile = file
+	}
+	if file := viper.GetString("aws-config-file"); file != "" {
+		cfg.S3SharedConfigFile = file
+	}
+	if prefix := viper.GetString("s3-prefix"); prefix != "" {
+		cfg.S3Prefix = prefix
+	} else if prefix := os.Getenv("S3_PREFIX"); prefix != "" {
+		cfg.S3Prefix = prefix
+	}
+	cfg.S3Prefix = normalizeS3Prefix(cfg.S3Prefix)
+	if contentType := viper.GetString("s3-content-type"); contentType != "" {
+		cfg.S3ContentType = contentType
+	} else if contentType := os.Getenv("S3_CONTENT_TYPE"); contentType != "" {
+		cfg.S3ContentType = contentType
+	}
+	if proxy := viper.GetString("s3-proxy"); proxy != "" {
+		cfg.S3Proxy = proxy
+	} else if proxy := os.Getenv("S3_PROXY"); proxy != "" {
+		cfg.S3Proxy = proxy
+	}
+	if viper.IsSet("s3-max-retries") {
+		cfg.S3MaxRetries = viper.GetInt("s3-max-retries")
+	} else if retries := os.Getenv("S3_MAX_RETRIES"); retries != "" {
+		if parsed, err := strconv.Atoi(retries); err == nil {
+			cfg.S3MaxRetries = parsed
+		} else {
+			logrus.Warnf("Ignoring invalid S3_MAX_RETRIES %q: %v", retries, err)
+		}
+	}
+	if viper.IsSet("s3-http-timeout") {
+		cfg.S3HTTPTimeout = viper.GetDuration("s3-http-timeout")
+	} else if timeout := os.Getenv("S3_HTTP_TIMEOUT"); timeout != "" {
+		if parsed, err := time.ParseDuration(timeout); err == nil {
+			cfg.S3HTTPTimeout = parsed
+		} else {
+			logrus.Warnf("Ignoring invalid S3_HTTP_TIMEOUT %q: %v", timeout, err)
+		}
+	}
+	if caBundle := viper.GetString("s3-ca-bundle"); caBundle != "" {
+		cfg.S3CABundle = caBundle
+	} else if caBundle := os.Getenv("S3_CA_BUNDLE"); caBundle != "" {
+		cfg.S3CABundle = caBundle
+	}
+	if viper.IsSet("s3-insecure-skip-verify") {
+		cfg.S3InsecureSkipVerify = viper.GetBool("s3-insecure-skip-verify")
+	} else if insecure := os.Getenv("S3_INSECURE_SKIP_VERIFY"); insecure != "" {
+		if parsed, err := strconv.ParseBool(insecure); err == nil {
+			cfg.S3InsecureSkipVerify = parsed
+		} else {
+			logrus.Warnf("Ignoring invalid S3_INSECURE_SKIP_VERIFY %q: %v", insecure, err)
+		}
+	}
+	if viper.IsSet("s3-date-partition") {
+		cfg.S3DatePartition = viper.GetBool("s3-date-partition")
+	} else if partition := os.Getenv("S3_DATE_PARTITION"); partition != "" {
+		if parsed, err := strconv.ParseBool(partition); err == nil {
+			cfg.S3DatePartition = parsed
+		} else {
+			logrus.Warnf("Ignoring invalid S3_DATE_PARTITION %q: %v", partition, err)
+		}
+	}
 	if region := os.Getenv("S3_REGION"); region != "" {
 		cfg.S3Region = region
 	} else {
 		cfg.S3Region = "us-east-1" // Default region
 	}
+	if region := viper.GetString("s3-signing-region"); region != "" {
+		cfg.S3SigningRegion = region
+	} else if region := os.Getenv("S3_SIGNING_REGION"); region != "" {
+		cfg.S3SigningRegion = region
+	}
 }
 
 // AttachEnvironmentCommands wires the environment detection subcommands onto a root command.
@@ -107,8 +420,8 @@ func AttachEnvironmentCommands(rootCmd *cobra.Command, app *InfrahubOps) {
 		Short: "List available Infrahub deployment targets",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			executor := NewCommandExecutor()
-			dockerProjects, _ := ListDockerProjects(executor)
-			k8sNamespaces, _ := ListKubernetesNamespaces(executor)
+			dockerProjects, _ := ListDockerProjects(executor, app.Config().tool(dockerTool))
+			k8sNamespaces, _ := ListKubernetesNamespaces(executor, app.Config().tool(kubectlTool))
 
 			if len(dockerProjects) == 0 && len(k8sNamespaces) == 0 {
 				logrus.Info("No Infrahub deployments detected")
@@ -133,7 +446,41 @@ func AttachEnvironmentCommands(rootCmd *cobra.Command, app *InfrahubOps) {
 		},
 	}
 
+	var useKind string
+	var useClear bool
+	useCmd := &cobra.Command{
+		Use:   "use [project-or-namespace]",
+		Short: "Persist a default Docker Compose project or Kubernetes namespace",
+		Long: "Save a default target so later commands no longer need --project or --k8s-namespace. " +
+			"The flags and their environment variables still take precedence.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if useClear {
+				if len(args) > 0 {
+					return fmt.Errorf("%w: --clear does not take a target", ErrPrerequisites)
+				}
+				return app.ClearEnvironment()
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("%w: a target project or namespace is required (or use --clear)", ErrPrerequisites)
+			}
+			return app.UseEnvironment(useKind, args[0])
+		},
+	}
+	useCmd.Flags().StringVar(&useKind, "kind", "", "Target kind (docker or kubernetes); required when the target is ambiguous or not running")
+	useCmd.Flags().BoolVar(&useClear, "clear", false, "Remove the saved context and go back to auto-detection")
+
+	currentCmd := &cobra.Command{
+		Use:   "current",
+		Short: "Show the active target and where it comes from",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.CurrentEnvironment()
+		},
+	}
+
 	envCmd.AddCommand(detectCmd)
 	envCmd.AddCommand(listCmd)
+	envCmd.AddCommand(useCmd)
+	envCmd.AddCommand(currentCmd)
 	rootCmd.AddCommand(envCmd)
 }
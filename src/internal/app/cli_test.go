@@ -0,0 +1,29 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestPasswordFileEnvironment(t *testing.T) {
+	t.Setenv("INFRAHUB_DB_PASSWORD_FILE", "/run/secrets/neo4j-password")
+	t.Setenv("INFRAHUB_POSTGRES_PASSWORD_FILE", "/run/secrets/prefect-password")
+	t.Cleanup(viper.Reset)
+
+	iops := NewInfrahubOps()
+	cmd := &cobra.Command{Use: "infrahub-backup", RunE: func(*cobra.Command, []string) error { return nil }}
+	ConfigureRootCommand(cmd, iops)
+	cmd.SetArgs([]string{})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := iops.config.Neo4jPasswordFile; got != "/run/secrets/neo4j-password" {
+		t.Errorf("Neo4jPasswordFile = %q, want the INFRAHUB_DB_PASSWORD_FILE path", got)
+	}
+	if got := iops.config.PostgresPasswordFile; got != "/run/secrets/prefect-password" {
+		t.Errorf("PostgresPasswordFile = %q, want the INFRAHUB_POSTGRES_PASSWORD_FILE path", got)
+	}
+}
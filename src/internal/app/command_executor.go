@@ -3,6 +3,7 @@ package app
 import (
 	"bytes"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -10,7 +11,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// CommandExecutor handles command execution
+// CommandExecutor handles command execution. Commands inherit the process environment,
+// so docker and kubectl pick up HTTPS_PROXY/NO_PROXY (and KUBECONFIG) as usual.
 type CommandExecutor struct{}
 
 func NewCommandExecutor() *CommandExecutor {
@@ -59,6 +61,25 @@ func (ce *CommandExecutor) runCommand(name string, args ...string) (string, erro
 	return strings.TrimSpace(string(output)), err
 }
 
+// runCommandInput runs a command with stdin connected to the given reader.
+func (ce *CommandExecutor) runCommandInput(stdin io.Reader, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// runCommandEnv runs a command with extra environment variables added to the process environment.
+func (ce *CommandExecutor) runCommandEnv(env map[string]string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
 func (ce *CommandExecutor) runCommandQuiet(name string, args ...string) error {
 	cmd := exec.Command(name, args...)
 	return cmd.Run()
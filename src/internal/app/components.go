@@ -0,0 +1,109 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Component names accepted by --exclude-components and --include-components.
+const (
+	neo4jSelection       = "neo4j"        // the Infrahub database
+	taskManagerSelection = "task-manager" // the task manager databases
+	authSelection        = "auth"         // the Neo4j Community users
+	configSelection      = "config"       // the deployment configuration snapshot
+)
+
+var (
+	backupComponentSelections  = []string{neo4jSelection, taskManagerSelection, authSelection, configSelection}
+	restoreComponentSelections = []string{neo4jSelection, taskManagerSelection, authSelection}
+)
+
+// parseComponentSelection validates the component names given to --<flag> against known.
+func parseComponentSelection(flag string, names, known []string) ([]string, error) {
+	var selected []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("invalid --%s %q: must be one of %s", flag, name, strings.Join(known, ", "))
+		}
+		selected = append(selected, name)
+	}
+	return unique(selected), nil
+}
+
+// applyBackupComponentSelection folds --exclude-components into the individual exclude
+// settings, which it keeps as aliases, and returns whether the task manager is excluded.
+func (iops *InfrahubOps) applyBackupComponentSelection(excludeTaskManager bool) (bool, error) {
+	excluded, err := parseComponentSelection("exclude-components", iops.config.ExcludeComponents, backupComponentSelections)
+	if err != nil {
+		return excludeTaskManager, err
+	}
+	excludeTaskManager = excludeTaskManager || contains(excluded, taskManagerSelection)
+	iops.config.ExcludeNeo4j = contains(excluded, neo4jSelection)
+	if contains(excluded, authSelection) || iops.config.ExcludeNeo4j {
+		iops.config.ExcludeNeo4jAuth = true
+	}
+	if contains(excluded, configSelection) {
+		if iops.config.IncludeConfig {
+			logrus.Warn("--include-config is ignored because config is in --exclude-components")
+		}
+		iops.config.IncludeConfig = false
+	}
+	if len(iops.config.ExcludeFiles) > 0 && !iops.config.IncludeConfig {
+		if contains(excluded, configSelection) {
+			return excludeTaskManager, fmt.Errorf("%w: --exclude-file only filters the config component, which --exclude-components leaves out", ErrPrerequisites)
+		}
+		return excludeTaskManager, fmt.Errorf("%w: --exclude-file only filters the config component; add --include-config", ErrPrerequisites)
+	}
+
+	if iops.config.ExcludeNeo4j && excludeTaskManager {
+		return excludeTaskManager, fmt.Errorf("nothing to back up: neo4j and task-manager are both excluded")
+	}
+	if iops.config.ExcludeNeo4j && iops.config.DumpOnly {
+		return excludeTaskManager, fmt.Errorf("--dump-only writes the Neo4j database and cannot exclude neo4j")
+	}
+	return excludeTaskManager, nil
+}
+
+// applyRestoreComponentSelection folds --exclude-components or --include-components into the
+// individual exclude settings and returns whether the task manager is excluded. auth is
+// restored with the Neo4j database, so excluding neo4j excludes it too.
+func (iops *InfrahubOps) applyRestoreComponentSelection(excludeTaskManager bool) (bool, error) {
+	if len(iops.config.ExcludeComponents) > 0 && len(iops.config.IncludeComponents) > 0 {
+		return excludeTaskManager, fmt.Errorf("--exclude-components and --include-components cannot be used together")
+	}
+	excluded, err := parseComponentSelection("exclude-components", iops.config.ExcludeComponents, restoreComponentSelections)
+	if err != nil {
+		return excludeTaskManager, err
+	}
+	if len(iops.config.IncludeComponents) > 0 {
+		included, err := parseComponentSelection("include-components", iops.config.IncludeComponents, restoreComponentSelections)
+		if err != nil {
+			return excludeTaskManager, err
+		}
+		if contains(included, authSelection) && !contains(included, neo4jSelection) {
+			return excludeTaskManager, fmt.Errorf("--include-components auth requires neo4j: the users are restored with the database")
+		}
+		excluded = slices.DeleteFunc(slices.Clone(restoreComponentSelections), func(name string) bool { return contains(included, name) })
+	}
+
+	excludeTaskManager = excludeTaskManager || contains(excluded, taskManagerSelection)
+	iops.config.ExcludeNeo4j = contains(excluded, neo4jSelection)
+	if contains(excluded, authSelection) || iops.config.ExcludeNeo4j {
+		iops.config.ExcludeNeo4jAuth = true
+	}
+
+	if iops.config.ExcludeNeo4j && excludeTaskManager {
+		return excludeTaskManager, fmt.Errorf("nothing to restore: neo4j and task-manager are both excluded")
+	}
+	if iops.config.ExcludeNeo4j && iops.config.SchemaOnly {
+		return excludeTaskManager, fmt.Errorf("--schema-only applies to the Neo4j database and cannot exclude neo4j")
+	}
+	return excludeTaskManager, nil
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestExcludeFileRequiresConfigComponent(t *testing.T) {
+	tests := []struct {
+		name              string
+		includeConfig     bool
+		excludeComponents []string
+		wantErr           bool
+	}{
+		{name: "with include-config", includeConfig: true},
+		{name: "without include-config", wantErr: true},
+		{name: "config excluded", includeConfig: true, excludeComponents: []string{"config"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iops := newTestOps(t, apptest.NewFakeBackend())
+			iops.config.ExcludeFiles = []string{"**/*.pem"}
+			iops.config.IncludeConfig = tt.includeConfig
+			iops.config.ExcludeComponents = tt.excludeComponents
+			_, err := iops.applyBackupComponentSelection(false)
+			if tt.wantErr && !errors.Is(err, ErrPrerequisites) {
+				t.Errorf("applyBackupComponentSelection = %v, want ErrPrerequisites", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("applyBackupComponentSelection = %v, want nil", err)
+			}
+		})
+	}
+}
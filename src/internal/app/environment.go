@@ -2,28 +2,36 @@ package app
 
 import (
 	"errors"
+	"regexp"
 	"sort"
 	"strings"
+
+	"infrahub-ops/src/pkg/backend"
 )
 
 var ErrEnvironmentNotFound = errors.New("environment not found")
 
-type ExecOptions struct {
-	User string
-	Env  map[string]string
-}
+// ExecOptions, Backend and ServiceChecker are defined in pkg/backend so that other programs
+// can implement a backend; the aliases keep the names used throughout this package.
+type (
+	ExecOptions    = backend.ExecOptions
+	Backend        = backend.Backend
+	ServiceChecker = backend.ServiceChecker
+)
 
-type EnvironmentBackend interface {
-	Name() string
-	Detect() error
-	Info() string
-	Exec(service string, command []string, opts *ExecOptions) (string, error)
-	ExecStream(service string, command []string, opts *ExecOptions) (string, error)
-	CopyTo(service, src, dest string) error
-	CopyFrom(service, src, dest string) error
-	Start(services ...string) error
-	Stop(services ...string) error
-	IsRunning(service string) (bool, error)
+var (
+	_ Backend        = (*DockerBackend)(nil)
+	_ Backend        = (*KubernetesBackend)(nil)
+	_ ServiceChecker = (*DockerBackend)(nil)
+	_ ServiceChecker = (*KubernetesBackend)(nil)
+)
+
+// hasService asks b whether the deployment has service, assuming it does when b can't tell.
+func hasService(b Backend, service string) (bool, error) {
+	if checker, ok := b.(ServiceChecker); ok {
+		return checker.HasService(service)
+	}
+	return true, nil
 }
 
 // Shared utility functions
@@ -64,3 +72,21 @@ func unique(values []string) []string {
 	sort.Strings(result)
 	return result
 }
+
+var (
+	secretKeyPattern      = regexp.MustCompile(`(?i)^(\s*-?\s*"?[A-Za-z0-9_.-]*(PASSWORD|PASSWD|SECRET|TOKEN|API_KEY|ACCESS_KEY|PRIVATE_KEY|AUTH)[A-Za-z0-9_.-]*"?\s*[:=]\s*)(.+)$`)
+	urlCredentialsPattern = regexp.MustCompile(`://([^:/@\s]+):([^@\s]+)@`)
+)
+
+// redactConfig masks values of secret-looking keys and credentials embedded in URLs so
+// configuration snapshots can be stored alongside backups.
+func redactConfig(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if secretKeyPattern.MatchString(line) {
+			line = secretKeyPattern.ReplaceAllString(line, "${1}<redacted>")
+		}
+		lines[i] = urlCredentialsPattern.ReplaceAllString(line, "://$1:<redacted>@")
+	}
+	return strings.Join(lines, "\n")
+}
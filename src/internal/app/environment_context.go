@@ -0,0 +1,191 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	environmentContextDir      = ".infrahubops"
+	environmentContextFilename = "context"
+	environmentContextEnvVar   = "INFRAHUB_CONTEXT_FILE"
+
+	environmentKindDocker     = "docker"
+	environmentKindKubernetes = "kubernetes"
+)
+
+// environmentContext is the default target persisted by "environment use".
+type environmentContext struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+}
+
+// environmentContextPath returns the context file, INFRAHUB_CONTEXT_FILE or ~/.infrahubops/context.
+func environmentContextPath() (string, error) {
+	if path := os.Getenv(environmentContextEnvVar); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the environment context file: %w", err)
+	}
+	return filepath.Join(home, environmentContextDir, environmentContextFilename), nil
+}
+
+// loadEnvironmentContext returns nil when no context has been set.
+func loadEnvironmentContext(path string) (*environmentContext, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read environment context: %w", err)
+	}
+	var ctx environmentContext
+	if err := json.Unmarshal(data, &ctx); err != nil {
+		return nil, fmt.Errorf("failed to parse environment context %s: %w", path, err)
+	}
+	if err := validateEnvironmentKind(ctx.Kind); err != nil || ctx.Target == "" {
+		return nil, fmt.Errorf("invalid environment context %s: run 'environment use' again or 'environment use --clear'", path)
+	}
+	return &ctx, nil
+}
+
+// saveEnvironmentContext writes the context atomically, like the schedule state.
+func saveEnvironmentContext(path string, ctx environmentContext) error {
+	data, err := json.MarshalIndent(ctx, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal environment context: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create environment context directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write environment context: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write environment context: %w", err)
+	}
+	return nil
+}
+
+func validateEnvironmentKind(kind string) error {
+	switch kind {
+	case environmentKindDocker, environmentKindKubernetes:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown environment kind %q (expected docker or kubernetes)", ErrPrerequisites, kind)
+	}
+}
+
+// applyEnvironmentContext fills in the target from the context file when neither --project nor
+// --k8s-namespace (or their environment variables) chose one. A replayed backend never uses it.
+func applyEnvironmentContext(cfg *Configuration) {
+	if cfg.DockerComposeProject != "" || cfg.K8sNamespace != "" || cfg.ReplayBackend != "" {
+		return
+	}
+	path, err := environmentContextPath()
+	if err != nil {
+		logrus.Debugf("Skipping environment context: %v", err)
+		return
+	}
+	ctx, err := loadEnvironmentContext(path)
+	if err != nil {
+		logrus.Warnf("Ignoring environment context: %v", err)
+		return
+	}
+	if ctx == nil {
+		return
+	}
+	if ctx.Kind == environmentKindDocker {
+		cfg.DockerComposeProject = ctx.Target
+	} else {
+		cfg.K8sNamespace = ctx.Target
+	}
+	cfg.environmentContextFile = path
+	logrus.Infof("Using %s target %s from environment context %s", ctx.Kind, ctx.Target, path)
+}
+
+// UseEnvironment persists target as the default for later commands. With an empty kind the
+// target must match exactly one running Docker Compose project or Kubernetes namespace.
+func (iops *InfrahubOps) UseEnvironment(kind, target string) error {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return fmt.Errorf("%w: a target project or namespace is required", ErrPrerequisites)
+	}
+
+	if kind != "" {
+		if err := validateEnvironmentKind(kind); err != nil {
+			return err
+		}
+	} else {
+		var matches []string
+		if projects, err := ListDockerProjects(iops.executor, iops.config.tool(dockerTool)); err == nil && contains(projects, target) {
+			matches = append(matches, environmentKindDocker)
+		}
+		if namespaces, err := ListKubernetesNamespaces(iops.executor, iops.config.tool(kubectlTool)); err == nil && contains(namespaces, target) {
+			matches = append(matches, environmentKindKubernetes)
+		}
+		switch len(matches) {
+		case 0:
+			return fmt.Errorf("%w: %s is neither a running Docker Compose project nor a Kubernetes namespace with Infrahub pods (set --kind to save it anyway)", ErrEnvironmentNotFound, target)
+		case 1:
+			kind = matches[0]
+		default:
+			return fmt.Errorf("%w: %s is both a Docker Compose project and a Kubernetes namespace; set --kind", ErrPrerequisites, target)
+		}
+	}
+
+	path, err := environmentContextPath()
+	if err != nil {
+		return err
+	}
+	if err := saveEnvironmentContext(path, environmentContext{Kind: kind, Target: target}); err != nil {
+		return err
+	}
+	logrus.Infof("Default target set to %s %s (saved in %s)", kind, target, path)
+	return nil
+}
+
+// ClearEnvironment removes the persisted context so auto-detection applies again.
+func (iops *InfrahubOps) ClearEnvironment() error {
+	path, err := environmentContextPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove environment context: %w", err)
+	}
+	logrus.Info("Environment context cleared; the target is auto-detected again")
+	return nil
+}
+
+// CurrentEnvironment prints the active target and where it comes from.
+func (iops *InfrahubOps) CurrentEnvironment() error {
+	cfg := iops.config
+	var kind, target, source string
+	switch {
+	case cfg.ReplayBackend != "":
+		fmt.Printf("replay %s (--replay-backend)\n", cfg.ReplayBackend)
+		return nil
+	case cfg.environmentContextFile != "" && cfg.DockerComposeProject != "":
+		kind, target, source = environmentKindDocker, cfg.DockerComposeProject, "context "+cfg.environmentContextFile
+	case cfg.environmentContextFile != "":
+		kind, target, source = environmentKindKubernetes, cfg.K8sNamespace, "context "+cfg.environmentContextFile
+	case cfg.DockerComposeProject != "":
+		kind, target, source = environmentKindDocker, cfg.DockerComposeProject, "--project or INFRAHUB_PROJECT"
+	case cfg.K8sNamespace != "":
+		kind, target, source = environmentKindKubernetes, cfg.K8sNamespace, "--k8s-namespace or INFRAHUB_K8S_NAMESPACE"
+	default:
+		fmt.Println("none (auto-detect)")
+		return nil
+	}
+	fmt.Printf("%s %s (%s)\n", kind, target, source)
+	return nil
+}
@@ -2,6 +2,9 @@ package app
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -25,11 +28,11 @@ func (d *DockerBackend) Info() string {
 }
 
 func (d *DockerBackend) Detect() error {
-	if err := d.executor.runCommandQuiet("docker", "--version"); err != nil {
+	if err := d.executor.runCommandQuiet(d.config.tool(dockerTool), "--version"); err != nil {
 		return fmt.Errorf("docker CLI not available: %w", err)
 	}
 
-	projects, err := ListDockerProjects(d.executor)
+	projects, err := ListDockerProjects(d.executor, d.config.tool(dockerTool))
 	if err != nil {
 		return err
 	}
@@ -37,7 +40,7 @@ func (d *DockerBackend) Detect() error {
 	if d.config.DockerComposeProject != "" {
 		project := d.config.DockerComposeProject
 		if !contains(projects, project) {
-			if _, err := d.executor.runCommand("docker", "compose", "-p", project, "ps"); err != nil {
+			if _, err := d.executor.runCommand(d.config.tool(dockerTool), "compose", "-p", project, "ps"); err != nil {
 				return fmt.Errorf("docker compose project %s not found: %w", project, err)
 			}
 		}
@@ -86,7 +89,11 @@ func (d *DockerBackend) Exec(service string, command []string, opts *ExecOptions
 	args = append(args, service)
 	args = append(args, command...)
 	full := d.composeArgs(args...)
-	return d.executor.runCommand("docker", full...)
+	if opts != nil && opts.Stdin != nil {
+		// compose exec keeps stdin attached by default; -T only disables the TTY
+		return d.executor.runCommandInput(opts.Stdin, d.config.tool(dockerTool), full...)
+	}
+	return d.executor.runCommand(d.config.tool(dockerTool), full...)
 }
 
 func (d *DockerBackend) ExecStream(service string, command []string, opts *ExecOptions) (string, error) {
@@ -109,13 +116,13 @@ func (d *DockerBackend) ExecStream(service string, command []string, opts *ExecO
 	args = append(args, service)
 	args = append(args, command...)
 	full := d.composeArgs(args...)
-	return d.executor.runCommandWithStream("docker", full...)
+	return d.executor.runCommandWithStream(d.config.tool(dockerTool), full...)
 }
 
 func (d *DockerBackend) CopyTo(service, src, dest string) error {
 	target := fmt.Sprintf("%s:%s", service, dest)
 	cmd := d.composeArgs("cp", "-a", src, target)
-	if _, err := d.executor.runCommand("docker", cmd...); err != nil {
+	if _, err := d.executor.runCommand(d.config.tool(dockerTool), cmd...); err != nil {
 		return err
 	}
 	return nil
@@ -124,7 +131,7 @@ func (d *DockerBackend) CopyTo(service, src, dest string) error {
 func (d *DockerBackend) CopyFrom(service, src, dest string) error {
 	source := fmt.Sprintf("%s:%s", service, src)
 	cmd := d.composeArgs("cp", source, dest)
-	if _, err := d.executor.runCommand("docker", cmd...); err != nil {
+	if _, err := d.executor.runCommand(d.config.tool(dockerTool), cmd...); err != nil {
 		return err
 	}
 	return nil
@@ -136,7 +143,7 @@ func (d *DockerBackend) Start(services ...string) error {
 	}
 	args := append([]string{"start"}, services...)
 	cmd := d.composeArgs(args...)
-	_, err := d.executor.runCommand("docker", cmd...)
+	_, err := d.executor.runCommand(d.config.tool(dockerTool), cmd...)
 	return err
 }
 
@@ -146,21 +153,55 @@ func (d *DockerBackend) Stop(services ...string) error {
 	}
 	args := append([]string{"stop"}, services...)
 	cmd := d.composeArgs(args...)
-	_, err := d.executor.runCommand("docker", cmd...)
+	_, err := d.executor.runCommand(d.config.tool(dockerTool), cmd...)
 	return err
 }
 
 func (d *DockerBackend) IsRunning(service string) (bool, error) {
 	cmd := d.composeArgs("ps", service)
-	output, err := d.executor.runCommand("docker", cmd...)
+	output, err := d.executor.runCommand(d.config.tool(dockerTool), cmd...)
 	if err != nil {
 		return false, err
 	}
 	return strings.Contains(output, "Up"), nil
 }
 
-func ListDockerProjects(executor *CommandExecutor) ([]string, error) {
-	output, err := executor.runCommand("docker", "compose", "ls")
+// HasService reports whether the project has a container for the service, in any state.
+func (d *DockerBackend) HasService(service string) (bool, error) {
+	output, err := d.executor.runCommand(d.config.tool(dockerTool), d.composeArgs("ps", "--all", "--services")...)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(nonEmptyLines(output), service), nil
+}
+
+// IsReady reports whether a container of the service is running and, when it defines a
+// healthcheck, healthy.
+func (d *DockerBackend) IsReady(service string) (bool, error) {
+	cmd := d.composeArgs("ps", "--format", "{{.State}} {{.Health}}", service)
+	output, err := d.executor.runCommand(d.config.tool(dockerTool), cmd...)
+	if err != nil {
+		return false, err
+	}
+	for _, line := range nonEmptyLines(output) {
+		fields := strings.Fields(line)
+		if fields[0] == "running" && (len(fields) == 1 || fields[1] == "healthy") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *DockerBackend) CaptureConfig(destDir string) error {
+	output, err := d.executor.runCommand(d.config.tool(dockerTool), d.composeArgs("config")...)
+	if err != nil {
+		return fmt.Errorf("failed to read docker compose config: %w", err)
+	}
+	return os.WriteFile(filepath.Join(destDir, "docker-compose.yaml"), []byte(redactConfig(output)+"\n"), 0600)
+}
+
+func ListDockerProjects(executor *CommandExecutor, docker string) ([]string, error) {
+	output, err := executor.runCommand(docker, "compose", "ls")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list docker compose projects: %w", err)
 	}
@@ -179,7 +220,7 @@ func ListDockerProjects(executor *CommandExecutor) ([]string, error) {
 		if project == "" {
 			continue
 		}
-		psOutput, err := executor.runCommand("docker", "compose", "-p", project, "ps", "-a")
+		psOutput, err := executor.runCommand(docker, "compose", "-p", project, "ps", "-a")
 		if err != nil {
 			continue
 		}
@@ -2,7 +2,12 @@ package app
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
 )
 
 type KubernetesBackend struct {
@@ -10,6 +15,7 @@ type KubernetesBackend struct {
 	executor  *CommandExecutor
 	namespace string
 	podCache  map[string]string
+	podMu     sync.Mutex // guards podCache; exec may run concurrently (e.g. watchdog heartbeat)
 }
 
 func NewKubernetesBackend(config *Configuration, executor *CommandExecutor) *KubernetesBackend {
@@ -25,18 +31,18 @@ func (k *KubernetesBackend) Info() string {
 }
 
 func (k *KubernetesBackend) Detect() error {
-	if err := k.executor.runCommandQuiet("kubectl", "version", "--client"); err != nil {
+	if err := k.executor.runCommandQuiet(k.config.tool(kubectlTool), "version", "--client"); err != nil {
 		return fmt.Errorf("kubectl CLI not available: %w", err)
 	}
 
-	namespaces, err := ListKubernetesNamespaces(k.executor)
+	namespaces, err := ListKubernetesNamespaces(k.executor, k.config.tool(kubectlTool))
 	if err != nil {
 		return err
 	}
 
 	if k.config.K8sNamespace != "" {
 		k.namespace = k.config.K8sNamespace
-		if _, err := k.executor.runCommand("kubectl", "get", "pods", "-n", k.namespace, "-l", "app.kubernetes.io/name=infrahub"); err != nil {
+		if _, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", "pods", "-n", k.namespace, "-l", "app.kubernetes.io/name=infrahub"); err != nil {
 			return fmt.Errorf("failed to verify namespace %s: %w", k.namespace, err)
 		}
 		return nil
@@ -60,9 +66,14 @@ func (k *KubernetesBackend) Exec(service string, command []string, opts *ExecOpt
 		return "", err
 	}
 	finalCmd := k.prepareCommand(command, opts)
+	if opts != nil && opts.Stdin != nil {
+		args := []string{"exec", "-i", "-n", k.namespace, pod, "--"}
+		args = append(args, finalCmd...)
+		return k.executor.runCommandInput(opts.Stdin, k.config.tool(kubectlTool), args...)
+	}
 	args := []string{"exec", "-n", k.namespace, pod, "--"}
 	args = append(args, finalCmd...)
-	return k.executor.runCommand("kubectl", args...)
+	return k.executor.runCommand(k.config.tool(kubectlTool), args...)
 }
 
 func (k *KubernetesBackend) ExecStream(service string, command []string, opts *ExecOptions) (string, error) {
@@ -73,7 +84,7 @@ func (k *KubernetesBackend) ExecStream(service string, command []string, opts *E
 	finalCmd := k.prepareCommand(command, opts)
 	args := []string{"exec", "-n", k.namespace, pod, "--"}
 	args = append(args, finalCmd...)
-	return k.executor.runCommandWithStream("kubectl", args...)
+	return k.executor.runCommandWithStream(k.config.tool(kubectlTool), args...)
 }
 
 func (k *KubernetesBackend) CopyTo(service, src, dest string) error {
@@ -82,7 +93,7 @@ func (k *KubernetesBackend) CopyTo(service, src, dest string) error {
 		return err
 	}
 	target := fmt.Sprintf("%s/%s:%s", k.namespace, pod, dest)
-	if _, err := k.executor.runCommand("kubectl", "cp", src, target); err != nil {
+	if _, err := k.executor.runCommand(k.config.tool(kubectlTool), "cp", src, target); err != nil {
 		return err
 	}
 	return nil
@@ -94,7 +105,7 @@ func (k *KubernetesBackend) CopyFrom(service, src, dest string) error {
 		return err
 	}
 	source := fmt.Sprintf("%s/%s:%s", k.namespace, pod, src)
-	if _, err := k.executor.runCommand("kubectl", "cp", source, dest); err != nil {
+	if _, err := k.executor.runCommand(k.config.tool(kubectlTool), "cp", source, dest); err != nil {
 		return err
 	}
 	return nil
@@ -114,74 +125,172 @@ func (k *KubernetesBackend) IsRunning(service string) (bool, error) {
 		return false, err
 	}
 	for _, status := range statuses {
-		if strings.EqualFold(status, "Running") {
+		if strings.EqualFold(status.Phase, "Running") {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
-func (k *KubernetesBackend) getPodStatuses(service string) ([]string, error) {
+// HasService reports whether the namespace has a pod or a workload for the service; a
+// workload scaled to zero still counts.
+func (k *KubernetesBackend) HasService(service string) (bool, error) {
+	if _, err := k.getPodForService(service); err == nil {
+		return true, nil
+	}
+	if _, _, err := k.findWorkloadResource(service); err == nil {
+		return true, nil
+	}
+	return false, nil
+}
+
+// IsReady reports whether a pod of the service is running and passes its readiness probe.
+func (k *KubernetesBackend) IsReady(service string) (bool, error) {
+	statuses, err := k.getPodStatuses(service)
+	if err != nil {
+		return false, err
+	}
+	for _, status := range statuses {
+		if strings.EqualFold(status.Phase, "Running") && status.Ready {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CaptureConfig stores the namespace ConfigMaps and the names (not contents) of its Secrets.
+func (k *KubernetesBackend) CaptureConfig(destDir string) error {
+	configMaps, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", "configmaps", "-n", k.namespace, "-o", "yaml")
+	if err != nil {
+		return fmt.Errorf("failed to read configmaps: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(destDir, "configmaps.yaml"), []byte(redactConfig(configMaps)+"\n"), 0600); err != nil {
+		return err
+	}
+
+	secrets, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", "secrets", "-n", k.namespace, "-o", "name")
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	return os.WriteFile(filepath.Join(destDir, "secret-names.txt"), []byte(secrets+"\n"), 0600)
+}
+
+// podStatus is the phase and Ready condition of one pod.
+type podStatus struct {
+	Phase string
+	Ready bool
+}
+
+// podStatusJSONPath prints "<phase>;<Ready condition status>" for each pod, prefixed by
+// "<name>;" when withName is set.
+func podStatusJSONPath(withName bool) string {
+	name := ""
+	if withName {
+		name = `{.metadata.name}{";"}`
+	}
+	return `jsonpath={range .items[*]}` + name + `{.status.phase}{";"}{.status.conditions[?(@.type=="Ready")].status}{"\n"}{end}`
+}
+
+func parsePodStatus(phase, ready string) podStatus {
+	return podStatus{Phase: phase, Ready: strings.EqualFold(ready, "True")}
+}
+
+func (k *KubernetesBackend) getPodStatuses(service string) ([]podStatus, error) {
 	selectors := k.podSelectors(service)
 	for _, selector := range selectors {
-		output, err := k.executor.runCommand("kubectl", "get", "pods", "-n", k.namespace, "-l", selector, "-o", "jsonpath={range .items[*]}{.status.phase}{\"\\n\"}{end}")
+		output, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", "pods", "-n", k.namespace, "-l", selector, "-o", podStatusJSONPath(false))
 		if err != nil {
 			continue
 		}
-		statuses := nonEmptyLines(output)
+		statuses := []podStatus{}
+		for _, line := range nonEmptyLines(output) {
+			phase, ready, _ := strings.Cut(line, ";")
+			statuses = append(statuses, parsePodStatus(phase, ready))
+		}
 		if len(statuses) > 0 {
 			return statuses, nil
 		}
 	}
+	if selector, ok := k.selectorOverride(service); ok {
+		return nil, fmt.Errorf("no pods match selector %q for service %s in namespace %s", selector, service, k.namespace)
+	}
+
 	// Fallback to all pods search
-	output, err := k.executor.runCommand("kubectl", "get", "pods", "-n", k.namespace, "-o", "jsonpath={range .items[*]}{.metadata.name}{\";\"}{.status.phase}{\"\\n\"}{end}")
+	output, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", "pods", "-n", k.namespace, "-o", podStatusJSONPath(true))
 	if err != nil {
 		return nil, err
 	}
-	statuses := []string{}
+	statuses := []podStatus{}
 	for _, line := range strings.Split(output, "\n") {
 		if line == "" {
 			continue
 		}
 		parts := strings.Split(line, ";")
-		if len(parts) != 2 {
+		if len(parts) != 3 {
 			continue
 		}
-		if strings.Contains(parts[0], service) {
-			statuses = append(statuses, parts[1])
+		if nameMatchesService(parts[0], service) {
+			statuses = append(statuses, parsePodStatus(parts[1], parts[2]))
 		}
 	}
+	if len(statuses) > 0 {
+		logrus.Warnf("No pods matched the label selectors for %s; matched %d pod(s) by name instead (set --k8s-selector to avoid this)", service, len(statuses))
+	}
 	return statuses, nil
 }
 
 func (k *KubernetesBackend) getPodForService(service string) (string, error) {
-	if pod, ok := k.podCache[service]; ok && pod != "" {
+	if pod, ok := k.cachedPod(service); ok {
 		return pod, nil
 	}
 
 	selectors := k.podSelectors(service)
 	for _, selector := range selectors {
-		output, err := k.executor.runCommand("kubectl", "get", "pods", "-n", k.namespace, "-l", selector, "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}")
+		output, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", "pods", "-n", k.namespace, "-l", selector, "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}")
 		if err != nil {
 			continue
 		}
 		pods := nonEmptyLines(output)
 		if len(pods) > 0 {
-			k.podCache[service] = pods[0]
+			k.cachePod(service, pods[0])
 			return pods[0], nil
 		}
 	}
 
-	output, err := k.executor.runCommand("kubectl", "get", "pods", "-n", k.namespace, "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}")
+	if selector, ok := k.selectorOverride(service); ok {
+		return "", fmt.Errorf("no pods match selector %q for service %s in namespace %s", selector, service, k.namespace)
+	}
+
+	output, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", "pods", "-n", k.namespace, "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}")
 	if err != nil {
 		return "", err
 	}
 	for _, name := range nonEmptyLines(output) {
-		if strings.Contains(name, service) {
-			k.podCache[service] = name
+		if nameMatchesService(name, service) {
+			logrus.Warnf("No pods matched the label selectors for %s; using pod %s matched by name (set --k8s-selector to avoid this)", service, name)
+			k.cachePod(service, name)
 			return name, nil
 		}
 	}
 
 	return "", fmt.Errorf("no pods found for service %s in namespace %s", service, k.namespace)
 }
+
+func (k *KubernetesBackend) cachedPod(service string) (string, bool) {
+	k.podMu.Lock()
+	defer k.podMu.Unlock()
+	pod, ok := k.podCache[service]
+	return pod, ok && pod != ""
+}
+
+func (k *KubernetesBackend) cachePod(service, pod string) {
+	k.podMu.Lock()
+	defer k.podMu.Unlock()
+	k.podCache[service] = pod
+}
+
+func (k *KubernetesBackend) resetPodCache() {
+	k.podMu.Lock()
+	defer k.podMu.Unlock()
+	k.podCache = map[string]string{}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"errors"
+)
+
+// Exit codes of the command-line tools. Scripts can rely on them to tell a failure worth
+// retrying from one that needs an operator.
+const (
+	ExitSuccess             = 0
+	ExitError               = 1 // any failure not listed below
+	ExitPrerequisites       = 2 // configuration, tools, permissions or services missing
+	ExitEnvironmentNotFound = 3 // no Infrahub deployment detected
+	ExitVerificationFailed  = 4 // checksum or metadata signature mismatch
+	ExitEditionMismatch     = 5 // backup incompatible with the Neo4j edition
+	ExitTimeout             = 6 // a wait or operation deadline expired
+	ExitAuthFailed          = 7 // credentials rejected by Neo4j, PostgreSQL or S3
+	ExitPartialBackup       = 8 // --best-effort backup archived without some components
+)
+
+var (
+	// ErrPrerequisites marks failures detected before any data is touched: invalid
+	// configuration, missing tools, missing permissions or services that are not running.
+	ErrPrerequisites = errors.New("prerequisites not met")
+	// ErrTimeout marks waits that gave up after their deadline.
+	ErrTimeout = errors.New("timed out")
+)
+
+// ExitCode maps an error returned by a command to the process exit code.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitSuccess
+	case errors.Is(err, ErrPartialBackup):
+		return ExitPartialBackup
+	case errors.Is(err, ErrEditionMismatch):
+		return ExitEditionMismatch
+	case isAuthFailure(err):
+		return ExitAuthFailed
+	case errors.Is(err, ErrChecksumMismatch), errors.Is(err, ErrSignatureMismatch), errors.Is(err, ErrRestoreVerification):
+		return ExitVerificationFailed
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ExitTimeout
+	case errors.Is(err, ErrEnvironmentNotFound):
+		return ExitEnvironmentNotFound
+	case errors.Is(err, ErrPrerequisites):
+		return ExitPrerequisites
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: ExitSuccess},
+		{name: "untyped", err: errors.New("boom"), want: ExitError},
+		{name: "prerequisites", err: fmt.Errorf("%w: --interval must be greater than zero", ErrPrerequisites), want: ExitPrerequisites},
+		{name: "environment not found", err: fmt.Errorf("detect: %w", ErrEnvironmentNotFound), want: ExitEnvironmentNotFound},
+		{name: "checksum mismatch", err: fmt.Errorf("validate: %w", ErrChecksumMismatch), want: ExitVerificationFailed},
+		{name: "signature mismatch", err: ErrSignatureMismatch, want: ExitVerificationFailed},
+		{name: "restore verification", err: fmt.Errorf("counts: %w", ErrRestoreVerification), want: ExitVerificationFailed},
+		{name: "edition mismatch", err: fmt.Errorf("restore: %w", ErrEditionMismatch), want: ExitEditionMismatch},
+		{name: "timeout", err: fmt.Errorf("wait: %w", ErrTimeout), want: ExitTimeout},
+		{name: "deadline exceeded", err: fmt.Errorf("upload: %w", context.DeadlineExceeded), want: ExitTimeout},
+		{name: "auth failed", err: fmt.Errorf("neo4j: %w", ErrAuthFailed), want: ExitAuthFailed},
+		{name: "s3 access denied", err: fmt.Errorf("upload: %w", &smithy.GenericAPIError{Code: "AccessDenied"}), want: ExitAuthFailed},
+		{name: "partial backup", err: fmt.Errorf("%w: created without neo4j", ErrPartialBackup), want: ExitPartialBackup},
+		{name: "partial backup in a retry", err: fmt.Errorf("attempt 2: %w", fmt.Errorf("%w: created without neo4j", ErrPartialBackup)), want: ExitPartialBackup},
+		{name: "joined", err: errors.Join(errors.New("summary"), fmt.Errorf("ns: %w", ErrEnvironmentNotFound)), want: ExitEnvironmentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceBackupError(t *testing.T) {
+	partial := fmt.Errorf("%w: created without neo4j", ErrPartialBackup)
+	tests := []struct {
+		name    string
+		results []namespaceBackupResult
+		want    int
+	}{
+		{name: "all succeeded", results: []namespaceBackupResult{{Namespace: "a"}, {Namespace: "b"}}, want: ExitSuccess},
+		{name: "only partial", results: []namespaceBackupResult{{Namespace: "a", Err: partial}, {Namespace: "b"}}, want: ExitPartialBackup},
+		{name: "partial and failed", results: []namespaceBackupResult{{Namespace: "a", Err: partial}, {Namespace: "b", Err: fmt.Errorf("detect: %w", ErrEnvironmentNotFound)}}, want: ExitEnvironmentNotFound},
+		{name: "untyped failure", results: []namespaceBackupResult{{Namespace: "a", Err: errors.New("boom")}, {Namespace: "b", Err: partial}}, want: ExitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(namespaceBackupError(tt.results)); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
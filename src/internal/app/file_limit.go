@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultMaxOpenFiles keeps well below the common 1024 descriptor limit, leaving room for the
+// sockets and pipes of the container commands running at the same time.
+const defaultMaxOpenFiles = 64
+
+// fileLimiter bounds the backup files read at once to --max-open-files. A nil fileLimiter
+// opens files without a bound.
+type fileLimiter struct {
+	slots chan struct{}
+}
+
+func newFileLimiter(n int) *fileLimiter {
+	if n < 1 {
+		logrus.Warnf("Ignoring --max-open-files %d; using %d", n, defaultMaxOpenFiles)
+		n = defaultMaxOpenFiles
+	}
+	return &fileLimiter{slots: make(chan struct{}, n)}
+}
+
+// limitedFile releases its open-file slot when closed.
+type limitedFile struct {
+	*os.File
+	release func()
+}
+
+func (f *limitedFile) Close() error {
+	defer f.release()
+	return f.File.Close()
+}
+
+// open opens path for reading once fewer than --max-open-files files are open.
+func (l *fileLimiter) open(path string) (*limitedFile, error) {
+	if l == nil {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return &limitedFile{File: file, release: func() {}}, nil
+	}
+	l.slots <- struct{}{}
+	file, err := os.Open(path)
+	if err != nil {
+		<-l.slots
+		return nil, l.describeOpenError(err)
+	}
+	var once sync.Once
+	return &limitedFile{File: file, release: func() { once.Do(func() { <-l.slots }) }}, nil
+}
+
+// describeOpenError turns descriptor exhaustion into an actionable message.
+func (l *fileLimiter) describeOpenError(err error) error {
+	if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) {
+		return fmt.Errorf("too many open files; lower --max-open-files (currently %d) or raise the descriptor limit (ulimit -n): %w", cap(l.slots), err)
+	}
+	return err
+}
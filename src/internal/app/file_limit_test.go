@@ -0,0 +1,120 @@
+package app
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLimiter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files := newFileLimiter(1)
+	first, err := files.open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	opened := make(chan *limitedFile)
+	go func() {
+		second, err := files.open(path)
+		if err != nil {
+			t.Errorf("second open: %v", err)
+		}
+		opened <- second
+	}()
+	select {
+	case <-opened:
+		t.Fatal("second open returned while the only slot was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Close()
+	first.Close() // a second Close must not release another slot
+	select {
+	case second := <-opened:
+		second.Close()
+	case <-time.After(time.Second):
+		t.Fatal("second open still blocked after the first file was closed")
+	}
+	if len(files.slots) != 0 {
+		t.Errorf("slots held after closing every file = %d, want 0", len(files.slots))
+	}
+
+	if _, err := files.open(filepath.Join(t.TempDir(), "missing")); err == nil || len(files.slots) != 0 {
+		t.Errorf("open of a missing file = %v with %d slots held, want an error and none held", err, len(files.slots))
+	}
+
+	var unbounded *fileLimiter
+	file, err := unbounded.open(path)
+	if err != nil {
+		t.Fatalf("open without a limiter: %v", err)
+	}
+	file.Close()
+
+	if got := cap(newFileLimiter(0).slots); got != defaultMaxOpenFiles {
+		t.Errorf("--max-open-files 0 gives %d slots, want the default %d", got, defaultMaxOpenFiles)
+	}
+}
+
+func TestFileLimiterPerInstance(t *testing.T) {
+	cfg := DefaultConfiguration()
+	cfg.MaxOpenFiles = 3
+	small := NewInfrahubOpsWithBackend(cfg, nil)
+	other := NewInfrahubOpsWithBackend(nil, nil)
+	if got := cap(small.files.slots); got != 3 {
+		t.Errorf("limiter of --max-open-files 3 has %d slots", got)
+	}
+	if got := cap(other.files.slots); got != defaultMaxOpenFiles {
+		t.Errorf("limiter of another instance has %d slots, want the default %d", got, defaultMaxOpenFiles)
+	}
+}
+
+// createTarball reads every file through the limiter, so it waits for a free slot.
+func TestCreateTarballUsesFileLimiter(t *testing.T) {
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "backup"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "backup", "file"), []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	files := newFileLimiter(1)
+	held, err := files.open(filepath.Join(source, "backup", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- createTarball(filepath.Join(t.TempDir(), "out.tar"), source, "backup/", tarballOptions{Files: files, Compression: archiveCompressionNone, Format: tar.FormatPAX})
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("createTarball returned (%v) while the only slot was held", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	held.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("createTarball: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("createTarball still blocked after the slot was released")
+	}
+}
+
+func TestNamespaceOpsSharesFileLimiter(t *testing.T) {
+	cfg := DefaultConfiguration()
+	cfg.MaxOpenFiles = 5
+	iops := NewInfrahubOpsWithBackend(cfg, nil)
+	if ops := iops.namespaceOps("infrahub-a"); ops.files != iops.files {
+		t.Errorf("namespace limiter has %d slots, want the --max-open-files limiter of the run", cap(ops.files.slots))
+	}
+}
@@ -0,0 +1,259 @@
+//go:build integration
+
+package app_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/testcontainers/testcontainers-go"
+	tcexec "github.com/testcontainers/testcontainers-go/exec"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"infrahub-ops/src/internal/app"
+)
+
+// The suite runs real CreateBackup and RestoreBackup calls through the Docker backend against
+// Neo4j Community, PostgreSQL and MinIO containers:
+//
+//	go test -tags integration -run Integration -timeout 20m ./src/internal/app/
+//
+// The containers carry the Docker Compose labels of a project, so that the docker compose
+// exec, cp, ps, stop and start commands of the tool address them by service name without a
+// compose file. It needs a Docker daemon and the docker CLI with the compose plugin.
+
+const (
+	itNeo4jImage    = "neo4j:5-community"
+	itPostgresImage = "postgres:15"
+	itMinioImage    = "minio/minio:RELEASE.2025-04-22T22-12-26Z"
+	itIdleImage     = "busybox:1.36"
+	itPassword      = "integration-secret"
+	itBucket        = "infrahub-backups"
+	itRegion        = "us-east-1"
+)
+
+// placeholderServices stand in for the application services that backups and restores
+// stop, restart and query; the tool tolerates their commands failing.
+var placeholderServices = []string{"infrahub-server", "task-worker", "task-manager", "cache", "message-queue"}
+
+type integrationEnv struct {
+	project  string
+	neo4j    testcontainers.Container
+	postgres testcontainers.Container
+	s3URL    string
+}
+
+func composeLabels(project, service string) map[string]string {
+	return map[string]string{
+		"com.docker.compose.project":          project,
+		"com.docker.compose.service":          service,
+		"com.docker.compose.oneoff":           "False",
+		"com.docker.compose.container-number": "1",
+	}
+}
+
+func startContainer(ctx context.Context, t *testing.T, req testcontainers.ContainerRequest) testcontainers.Container {
+	t.Helper()
+	ctr, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true})
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("start %s: %v", req.Image, err)
+	}
+	return ctr
+}
+
+func startIntegrationEnv(ctx context.Context, t *testing.T) *integrationEnv {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	if err := exec.Command("docker", "compose", "version").Run(); err != nil {
+		t.Skipf("docker compose CLI not available: %v", err)
+	}
+
+	env := &integrationEnv{project: fmt.Sprintf("infrahub-it-%d", time.Now().UnixNano())}
+	env.neo4j = startContainer(ctx, t, testcontainers.ContainerRequest{
+		Image:      itNeo4jImage,
+		Env:        map[string]string{"NEO4J_AUTH": "neo4j/" + itPassword},
+		Labels:     composeLabels(env.project, "database"),
+		WaitingFor: wait.ForLog("Started.").WithStartupTimeout(3 * time.Minute),
+	})
+	env.postgres = startContainer(ctx, t, testcontainers.ContainerRequest{
+		Image: itPostgresImage,
+		Env: map[string]string{
+			"POSTGRES_USER":     "postgres",
+			"POSTGRES_PASSWORD": itPassword,
+			"POSTGRES_DB":       "prefect",
+		},
+		Labels:     composeLabels(env.project, "task-manager-db"),
+		WaitingFor: wait.ForLog("database system is ready to accept connections").WithOccurrence(2).WithStartupTimeout(2 * time.Minute),
+	})
+	for _, service := range placeholderServices {
+		startContainer(ctx, t, testcontainers.ContainerRequest{
+			Image:  itIdleImage,
+			Cmd:    []string{"sleep", "86400"},
+			Labels: composeLabels(env.project, service),
+		})
+	}
+
+	minio := startContainer(ctx, t, testcontainers.ContainerRequest{
+		Image:        itMinioImage,
+		Cmd:          []string{"server", "/data"},
+		Env:          map[string]string{"MINIO_ROOT_USER": "minio", "MINIO_ROOT_PASSWORD": itPassword},
+		ExposedPorts: []string{"9000/tcp"},
+		WaitingFor:   wait.ForHTTP("/minio/health/ready").WithPort("9000/tcp").WithStartupTimeout(time.Minute),
+	})
+	url, err := minio.PortEndpoint(ctx, "9000/tcp", "http")
+	if err != nil {
+		t.Fatalf("minio endpoint: %v", err)
+	}
+	env.s3URL = url
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(url),
+		Region:       itRegion,
+		Credentials:  credentials.NewStaticCredentialsProvider("minio", itPassword, ""),
+		UsePathStyle: true,
+	})
+	if _, err := client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(itBucket)}); err != nil {
+		t.Fatalf("create bucket: %v", err)
+	}
+	return env
+}
+
+// newOps configures the tool for the project the way the CLI flags and S3_* variables would.
+func (env *integrationEnv) newOps(backupDir string) *app.InfrahubOps {
+	iops := app.NewInfrahubOps()
+	cfg := iops.Config()
+	cfg.DockerComposeProject = env.project
+	cfg.BackupDir = backupDir
+	cfg.Neo4jUsername, cfg.Neo4jPassword, cfg.Neo4jDatabase = "neo4j", itPassword, "neo4j"
+	cfg.PostgresUsername, cfg.PostgresPassword, cfg.PostgresDatabase = "postgres", itPassword, "prefect"
+	cfg.S3Upload = true
+	cfg.S3Bucket = itBucket
+	cfg.S3Endpoint = env.s3URL
+	cfg.S3AccessKeyID, cfg.S3SecretKey = "minio", itPassword
+	cfg.S3Region = itRegion
+	cfg.ConfirmDestructive = true
+	return iops
+}
+
+func execIn(ctx context.Context, t *testing.T, ctr testcontainers.Container, cmd ...string) string {
+	t.Helper()
+	code, reader, err := ctr.Exec(ctx, cmd, tcexec.Multiplexed())
+	if err != nil {
+		t.Fatalf("exec %v: %v", cmd, err)
+	}
+	output, _ := io.ReadAll(reader)
+	if code != 0 {
+		t.Fatalf("exec %v: exit code %d: %s", cmd, code, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func cypher(ctx context.Context, t *testing.T, env *integrationEnv, query string) string {
+	t.Helper()
+	return execIn(ctx, t, env.neo4j, "cypher-shell", "-u", "neo4j", "-p", itPassword, "--format", "plain", query)
+}
+
+func psql(ctx context.Context, t *testing.T, env *integrationEnv, query string) string {
+	t.Helper()
+	return execIn(ctx, t, env.postgres, "psql", "-U", "postgres", "-d", "prefect", "-tAc", query)
+}
+
+// waitForNeo4j polls until Neo4j answers queries again after the restore restarted it.
+func waitForNeo4j(ctx context.Context, t *testing.T, env *integrationEnv) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Minute)
+	for {
+		code, _, err := env.neo4j.Exec(ctx, []string{"cypher-shell", "-u", "neo4j", "-p", itPassword, "RETURN 1"})
+		if err == nil && code == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Neo4j did not come back after the restore (exit code %d): %v", code, err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func TestIntegrationCommunityBackupAndRestoreFromS3(t *testing.T) {
+	ctx := context.Background()
+	env := startIntegrationEnv(ctx, t)
+
+	cypher(ctx, t, env, "CREATE (:ITNode {name: 'a'})-[:LINKS]->(:ITNode {name: 'b'})")
+	psql(ctx, t, env, "CREATE TABLE it_marker (value text); INSERT INTO it_marker VALUES ('before')")
+
+	backupDir := t.TempDir()
+	if err := env.newOps(backupDir).CreateBackup(true, "none", false); err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	archives, err := filepath.Glob(filepath.Join(backupDir, "infrahub_backup_*.tar.gz"))
+	if err != nil || len(archives) != 1 {
+		t.Fatalf("archives in %s = %v (%v), want one", backupDir, archives, err)
+	}
+
+	// Change both databases, then restore the copy uploaded to MinIO
+	cypher(ctx, t, env, "MATCH (n:ITNode) DETACH DELETE n")
+	psql(ctx, t, env, "UPDATE it_marker SET value = 'after'")
+
+	source := fmt.Sprintf("s3://%s/%s", itBucket, filepath.Base(archives[0]))
+	if err := env.newOps(t.TempDir()).RestoreBackup(source, false, false); err != nil {
+		t.Fatalf("RestoreBackup %s: %v", source, err)
+	}
+	waitForNeo4j(ctx, t, env)
+
+	if got := cypher(ctx, t, env, "MATCH (a:ITNode)-[:LINKS]->(b:ITNode) RETURN a.name + '->' + b.name"); !strings.HasSuffix(got, `"a->b"`) {
+		t.Errorf("restored graph = %q, want the a->b relationship", got)
+	}
+	if got := psql(ctx, t, env, "SELECT value FROM it_marker"); got != "before" {
+		t.Errorf("restored task manager row = %q, want before", got)
+	}
+}
+
+func TestIntegrationRestoreRejectsATamperedArchive(t *testing.T) {
+	ctx := context.Background()
+	env := startIntegrationEnv(ctx, t)
+	cypher(ctx, t, env, "CREATE (:ITNode {name: 'kept'})")
+
+	backupDir := t.TempDir()
+	iops := env.newOps(backupDir)
+	iops.Config().S3Upload = false
+	if err := iops.CreateBackup(true, "none", true); err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	archives, _ := filepath.Glob(filepath.Join(backupDir, "infrahub_backup_*.tar.gz"))
+	if len(archives) != 1 {
+		t.Fatalf("archives = %v, want one", archives)
+	}
+
+	// Extract, change the dump and restore from the directory: checksum validation must stop
+	// the restore before Neo4j is touched
+	dir := t.TempDir()
+	if out, err := exec.Command("tar", "-xzf", archives[0], "-C", dir).CombinedOutput(); err != nil {
+		t.Fatalf("extract: %v: %s", err, out)
+	}
+	dumps, _ := filepath.Glob(filepath.Join(dir, "backup", "database", "*.dump"))
+	if len(dumps) != 1 {
+		t.Fatalf("dumps = %v, want one", dumps)
+	}
+	if out, err := exec.Command("sh", "-c", "printf x >> "+dumps[0]).CombinedOutput(); err != nil {
+		t.Fatalf("tamper: %v: %s", err, out)
+	}
+
+	restore := env.newOps(t.TempDir())
+	restore.Config().S3Upload = false
+	if err := restore.RestoreFromDirectory(dir, true, false); !errors.Is(err, app.ErrChecksumMismatch) {
+		t.Fatalf("RestoreFromDirectory = %v, want a checksum mismatch", err)
+	}
+	if got := cypher(ctx, t, env, "MATCH (n:ITNode {name: 'kept'}) RETURN count(n)"); !strings.HasSuffix(got, "1") {
+		t.Errorf("graph after the rejected restore = %q, want the node kept", got)
+	}
+}
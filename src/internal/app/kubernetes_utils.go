@@ -2,12 +2,17 @@ package app
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 )
 
-// podSelectors returns a list of label selectors to find pods for a service
+// podSelectors returns a list of label selectors to find pods for a service.
+// A configured override replaces the conventional selectors.
 func (k *KubernetesBackend) podSelectors(service string) []string {
+	if selector, ok := k.selectorOverride(service); ok {
+		return []string{selector}
+	}
 	return []string{
 		fmt.Sprintf("app.kubernetes.io/component=%s", service),
 		fmt.Sprintf("app=%s", service),
@@ -16,6 +21,46 @@ func (k *KubernetesBackend) podSelectors(service string) []string {
 	}
 }
 
+// infrahubComponents are the service names the name-based fallback may resolve
+var infrahubComponents = []string{
+	"infrahub-server", "task-worker", "task-manager", "task-manager-background-svc",
+	"task-manager-db", "cache", "message-queue", "database",
+}
+
+// nameMatchesService reports whether a pod or workload name belongs to service. Names are
+// compared on whole dash-separated tokens, and the most specific known component wins, so
+// "task-manager" does not match "task-manager-db-0" and unknown services never match.
+func nameMatchesService(name, service string) bool {
+	if !slices.Contains(infrahubComponents, service) {
+		return false
+	}
+	best := ""
+	for _, component := range infrahubComponents {
+		if containsTokens(name, component) && len(component) > len(best) {
+			best = component
+		}
+	}
+	return best == service
+}
+
+// containsTokens reports whether the dash-separated tokens of component appear contiguously in name
+func containsTokens(name, component string) bool {
+	nameTokens := strings.Split(name, "-")
+	componentTokens := strings.Split(component, "-")
+	for i := 0; i+len(componentTokens) <= len(nameTokens); i++ {
+		if slices.Equal(nameTokens[i:i+len(componentTokens)], componentTokens) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorOverride returns the configured label selector for a service, if any
+func (k *KubernetesBackend) selectorOverride(service string) (string, bool) {
+	selector, ok := k.config.K8sSelectors[service]
+	return selector, ok && selector != ""
+}
+
 // prepareCommand prepares a command for execution, adding environment variables and user switching
 func (k *KubernetesBackend) prepareCommand(command []string, opts *ExecOptions) []string {
 	if opts == nil {
@@ -100,8 +145,8 @@ func shellQuote(value string) string {
 }
 
 // ListKubernetesNamespaces lists all Kubernetes namespaces with Infrahub deployments
-func ListKubernetesNamespaces(executor *CommandExecutor) ([]string, error) {
-	output, err := executor.runCommand("kubectl", "get", "pods", "-A", "-l", "app.kubernetes.io/name=infrahub", "-o", "jsonpath={range .items[*]}{.metadata.namespace}{\"\\n\"}{end}")
+func ListKubernetesNamespaces(executor *CommandExecutor, kubectl string) ([]string, error) {
+	output, err := executor.runCommand(kubectl, "get", "pods", "-A", "-l", "app.kubernetes.io/name=infrahub", "-o", "jsonpath={range .items[*]}{.metadata.namespace}{\"\\n\"}{end}")
 	if err != nil {
 		return nil, err
 	}
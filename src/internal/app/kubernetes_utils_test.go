@@ -0,0 +1,59 @@
+package app
+
+import "testing"
+
+func TestNameMatchesService(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		want    bool
+	}{
+		{name: "infrahub-task-manager-7d9f8-abcde", service: "task-manager", want: true},
+		{name: "infrahub-task-manager-db-0", service: "task-manager-db", want: true},
+		{name: "infrahub-task-manager-db-0", service: "task-manager"},
+		{name: "infrahub-task-manager-background-svc-5c6d", service: "task-manager-background-svc", want: true},
+		{name: "infrahub-task-manager-background-svc-5c6d", service: "task-manager"},
+		{name: "infrahub-infrahub-server-6b7c-xyz12", service: "infrahub-server", want: true},
+		{name: "infrahub-task-worker-0", service: "task-worker", want: true},
+		{name: "infrahub-database-0", service: "database", want: true},
+		{name: "infrahub-databases-0", service: "database"},
+		{name: "infrahub-cache-0", service: "cache", want: true},
+		{name: "infrahub-cachex-0", service: "cache"},
+		{name: "infrahub-message-queue-0", service: "message-queue", want: true},
+		{name: "infrahub-message-0", service: "message-queue"},
+		{name: "infrahub-custom-0", service: "custom"},
+		{name: "task-manager", service: "task-manager", want: true},
+		{name: "", service: "database"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.service, func(t *testing.T) {
+			if got := nameMatchesService(tt.name, tt.service); got != tt.want {
+				t.Errorf("nameMatchesService(%q, %q) = %t, want %t", tt.name, tt.service, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		component string
+		want      bool
+	}{
+		{name: "infrahub-task-manager-db-0", component: "task-manager", want: true},
+		{name: "infrahub-task-manager-db-0", component: "manager-db", want: true},
+		{name: "infrahub-task-manager-db-0", component: "task-db"},
+		{name: "infrahub-databases-0", component: "database"},
+		{name: "database", component: "database", want: true},
+		{name: "cache", component: "message-queue"},
+		{name: "db", component: "task-manager-db"},
+		{name: "a-b-a-b-c", component: "a-b-c", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.component, func(t *testing.T) {
+			if got := containsTokens(tt.name, tt.component); got != tt.want {
+				t.Errorf("containsTokens(%q, %q) = %t, want %t", tt.name, tt.component, got, tt.want)
+			}
+		})
+	}
+}
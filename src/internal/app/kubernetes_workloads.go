@@ -3,7 +3,8 @@ package app
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
+
+	"github.com/sirupsen/logrus"
 )
 
 type kubernetesWorkload struct {
@@ -19,7 +20,7 @@ func (k *KubernetesBackend) findWorkloadResource(service string) (string, string
 
 	for _, kind := range kinds {
 		for _, selector := range selectors {
-			output, err := k.executor.runCommand("kubectl", "get", kind, "-n", k.namespace, "-l", selector, "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}")
+			output, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", kind, "-n", k.namespace, "-l", selector, "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}")
 			if err != nil || output == "" {
 				continue
 			}
@@ -29,6 +30,10 @@ func (k *KubernetesBackend) findWorkloadResource(service string) (string, string
 			}
 		}
 
+		// Workloads are often labeled differently from their pods, so an override is matched
+		// against pod template labels below, but never by name.
+		_, overridden := k.selectorOverride(service)
+
 		if workloads, err := k.listWorkloads(kind); err == nil {
 			var candidate string
 			for _, workload := range workloads {
@@ -37,21 +42,27 @@ func (k *KubernetesBackend) findWorkloadResource(service string) (string, string
 						return kind, workload.Name, nil
 					}
 				}
-				if candidate == "" && strings.Contains(workload.Name, service) {
+				if !overridden && candidate == "" && nameMatchesService(workload.Name, service) {
 					candidate = workload.Name
 				}
 			}
 			if candidate != "" {
+				logrus.Warnf("No %s matched the label selectors for %s; using %s matched by name", kind, service, candidate)
 				return kind, candidate, nil
 			}
 		}
 
-		output, err := k.executor.runCommand("kubectl", "get", kind, "-n", k.namespace, "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}")
+		if overridden {
+			continue
+		}
+
+		output, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", kind, "-n", k.namespace, "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}")
 		if err != nil {
 			continue
 		}
 		for _, name := range nonEmptyLines(output) {
-			if strings.Contains(name, service) {
+			if nameMatchesService(name, service) {
+				logrus.Warnf("No %s matched the label selectors for %s; using %s matched by name", kind, service, name)
 				return kind, name, nil
 			}
 		}
@@ -62,7 +73,7 @@ func (k *KubernetesBackend) findWorkloadResource(service string) (string, string
 
 // listWorkloads retrieves all workloads of a given kind with their labels
 func (k *KubernetesBackend) listWorkloads(kind string) ([]kubernetesWorkload, error) {
-	output, err := k.executor.runCommand("kubectl", "get", kind, "-n", k.namespace, "-o", "json")
+	output, err := k.executor.runCommand(k.config.tool(kubectlTool), "get", kind, "-n", k.namespace, "-o", "json")
 	if err != nil {
 		return nil, err
 	}
@@ -103,7 +114,7 @@ func (k *KubernetesBackend) listWorkloads(kind string) ([]kubernetesWorkload, er
 
 // scaleResource scales a Kubernetes resource to the specified number of replicas
 func (k *KubernetesBackend) scaleResource(kind, resource string, replicas int) error {
-	_, err := k.executor.runCommand("kubectl", "scale", "-n", k.namespace, fmt.Sprintf("%s/%s", kind, resource), fmt.Sprintf("--replicas=%d", replicas))
+	_, err := k.executor.runCommand(k.config.tool(kubectlTool), "scale", "-n", k.namespace, fmt.Sprintf("%s/%s", kind, resource), fmt.Sprintf("--replicas=%d", replicas))
 	return err
 }
 
@@ -121,6 +132,6 @@ func (k *KubernetesBackend) scaleServices(services []string, replicas int) error
 			return fmt.Errorf("failed to scale %s (%s/%s) to %d replicas: %w", service, kind, resource, replicas, err)
 		}
 	}
-	k.podCache = map[string]string{}
+	k.resetPodCache()
 	return nil
 }
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// operationReport collects what a backup or restore did for the --summary-file report and the
+// --summary-on-failure bundle. A nil report ignores all calls, so call sites need no checks.
+type operationReport struct {
+	mu        sync.Mutex
+	operation string
+	started   time.Time
+	details   [][2]string
+	steps     []reportStep
+	checksums map[string]string
+	warnings  []string
+	// Only kept for the --summary-on-failure bundle
+	captureLogs   bool
+	logLines      []string
+	failedCommand string
+	attachments   map[string]string
+}
+
+type reportStep struct {
+	name     string
+	duration time.Duration
+	err      error
+}
+
+// reportHook copies warnings and errors logged during an operation into the active report,
+// and every log line when it is kept for --summary-on-failure.
+type reportHook struct {
+	iops *InfrahubOps
+}
+
+func (h *reportHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *reportHook) Fire(entry *logrus.Entry) error {
+	if report := h.iops.report.Load(); report != nil {
+		report.mu.Lock()
+		if entry.Level <= logrus.WarnLevel {
+			report.warnings = append(report.warnings, entry.Message)
+		}
+		if report.captureLogs {
+			report.logLines = append(report.logLines, formatLogLine(entry))
+		}
+		report.mu.Unlock()
+	}
+	return nil
+}
+
+// startReport begins collecting a report when --summary-file or --summary-on-failure is set.
+func (iops *InfrahubOps) startReport(operation string) *operationReport {
+	if iops.config.SummaryFile == "" && !iops.config.SummaryOnFailure {
+		iops.report.Store(nil)
+		return nil
+	}
+	iops.reportHookOnce.Do(func() {
+		logrus.AddHook(&reportHook{iops: iops})
+	})
+	report := &operationReport{operation: operation, started: time.Now(), captureLogs: iops.config.SummaryOnFailure}
+	iops.report.Store(report)
+	return report
+}
+
+// finishReport writes the report to --summary-file, and the diagnostic bundle of a failed
+// operation with --summary-on-failure. Failures are logged, never returned.
+func (iops *InfrahubOps) finishReport(result error) {
+	report := iops.report.Swap(nil)
+	if report == nil {
+		return
+	}
+	if result != nil && iops.config.SummaryOnFailure {
+		iops.writeDiagnosticBundle(report, result)
+	}
+	if iops.config.SummaryFile == "" {
+		return
+	}
+	if err := os.WriteFile(iops.config.SummaryFile, []byte(report.render(result)), 0644); err != nil {
+		logrus.Warnf("Failed to write summary report %s: %v", iops.config.SummaryFile, err)
+		return
+	}
+	logrus.WithField("path", iops.config.SummaryFile).Info("Summary report written")
+}
+
+// begin starts timing a step; the returned function records its duration and outcome.
+func (r *operationReport) begin(name string) func(error) {
+	if r == nil {
+		return func(error) {}
+	}
+	start := time.Now()
+	return func(err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.steps = append(r.steps, reportStep{name: name, duration: time.Since(start), err: err})
+	}
+}
+
+// set records a detail line; setting the same key again replaces its value.
+func (r *operationReport) set(key, value string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.details {
+		if r.details[i][0] == key {
+			r.details[i][1] = value
+			return
+		}
+	}
+	r.details = append(r.details, [2]string{key, value})
+}
+
+func (r *operationReport) setChecksums(checksums map[string]string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checksums = checksums
+}
+
+// describeNeo4jPath names the Neo4j backup/restore method used for edition.
+func describeNeo4jPath(edition string) string {
+	switch strings.ToLower(edition) {
+	case neo4jEditionCommunity:
+		return "community (offline neo4j-admin dump, services stopped)"
+	case neo4jEditionExternal:
+		return "external (APOC cypher export)"
+	case neo4jEditionEnterprise:
+		return "enterprise (online neo4j-admin backup)"
+	default:
+		return edition + " (unrecognized)"
+	}
+}
+
+func (r *operationReport) render(result error) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	title := fmt.Sprintf("Infrahub %s report", r.operation)
+	fmt.Fprintf(&b, "%s\n%s\n\n", title, strings.Repeat("=", len(title)))
+
+	finished := time.Now()
+	status := "success"
+	if result != nil {
+		status = "FAILED: " + result.Error()
+	}
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Result:\t%s\n", status)
+	fmt.Fprintf(w, "Started:\t%s\n", r.started.Format(time.RFC3339))
+	fmt.Fprintf(w, "Finished:\t%s\n", finished.Format(time.RFC3339))
+	fmt.Fprintf(w, "Duration:\t%s\n", finished.Sub(r.started).Round(time.Second))
+	for _, detail := range r.details {
+		fmt.Fprintf(w, "%s:\t%s\n", detail[0], detail[1])
+	}
+	w.Flush()
+
+	if len(r.steps) > 0 {
+		b.WriteString("\nSteps\n")
+		w = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		for _, step := range r.steps {
+			outcome := "ok"
+			if step.err != nil {
+				outcome = "failed: " + step.err.Error()
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", step.name, step.duration.Round(100*time.Millisecond), outcome)
+		}
+		w.Flush()
+	}
+
+	if len(r.checksums) > 0 {
+		b.WriteString("\nChecksums (SHA-256)\n")
+		names := make([]string, 0, len(r.checksums))
+		for name := range r.checksums {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		w = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s\t%s\n", name, r.checksums[name])
+		}
+		w.Flush()
+	}
+
+	b.WriteString("\nWarnings\n")
+	if len(r.warnings) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, warning := range r.warnings {
+		fmt.Fprintf(&b, "  - %s\n", warning)
+	}
+	return b.String()
+}
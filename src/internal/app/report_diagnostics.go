@@ -0,0 +1,171 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// diagnosticBundlePrefix names the bundles written by --summary-on-failure.
+const diagnosticBundlePrefix = "diag-"
+
+// diagnosticNeo4jVersionTimeout bounds the Neo4j version query of the bundle, which runs after a
+// failure that may have left Neo4j unresponsive.
+const diagnosticNeo4jVersionTimeout = 10 * time.Second
+
+// formatLogLine renders a log entry for the diagnostic bundle, independently of --log-format.
+func formatLogLine(entry *logrus.Entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-7s %s", entry.Time.Format(time.RFC3339), strings.ToUpper(entry.Level.String()), entry.Message)
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, entry.Data[key])
+	}
+	return b.String()
+}
+
+// recordFailedCommand keeps the last command that failed, for the diagnostic bundle.
+func (r *operationReport) recordFailedCommand(service string, command []string, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failedCommand = fmt.Sprintf("service: %s\ncommand: %s\nerror: %v\n", valueOrDash(service), strings.Join(command, " "), err)
+}
+
+// attach adds a file to the diagnostic bundle; attaching the same name again replaces it.
+func (r *operationReport) attach(name, content string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.attachments == nil {
+		r.attachments = map[string]string{}
+	}
+	r.attachments[name] = content
+}
+
+// writeDiagnosticBundle writes diag-<timestamp>.tar.gz to the backup directory after a failed
+// backup or restore, with the report, the log of the run, the environment, the last failed
+// command and any attachment such as the watchdog log. Secrets are redacted. Failures are
+// logged, never returned, so that the original error is what the caller sees.
+func (iops *InfrahubOps) writeDiagnosticBundle(report *operationReport, result error) {
+	redact := secretRedactor(iops.config)
+	files := map[string]string{
+		"summary.txt":     report.render(result),
+		"environment.txt": iops.describeDiagnosticEnvironment(),
+	}
+	report.mu.Lock()
+	files["log.txt"] = strings.Join(report.logLines, "\n") + "\n"
+	if report.failedCommand != "" {
+		files["failed_command.txt"] = report.failedCommand
+	}
+	for name, content := range report.attachments {
+		files[name] = content
+	}
+	report.mu.Unlock()
+
+	if err := os.MkdirAll(iops.config.BackupDir, 0755); err != nil {
+		logrus.Warnf("Failed to write diagnostic bundle: %v", err)
+		return
+	}
+	path := filepath.Join(iops.config.BackupDir, diagnosticBundlePrefix+time.Now().UTC().Format(backupTimestampUTCFmt)+".tar.gz")
+	if err := writeTarGz(path, files, redact); err != nil {
+		os.Remove(path)
+		logrus.Warnf("Failed to write diagnostic bundle %s: %v", path, err)
+		return
+	}
+	logrus.WithField("path", path).Warn("Diagnostic bundle written; attach it to a support request")
+}
+
+// describeDiagnosticEnvironment lists the tool, host and deployment details of the bundle.
+// The Neo4j version is queried again, so it is "unknown" when Neo4j is down, and the query is
+// abandoned after diagnosticNeo4jVersionTimeout when Neo4j doesn't answer.
+func (iops *InfrahubOps) describeDiagnosticEnvironment() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Tool version:\t%s\n", BuildRevision())
+	fmt.Fprintf(w, "Go version:\t%s\n", runtime.Version())
+	fmt.Fprintf(w, "Platform:\t%s/%s\n", runtime.GOOS, runtime.GOARCH)
+	if iops.backend == nil {
+		fmt.Fprintf(w, "Environment:\tnot detected\n")
+		w.Flush()
+		return b.String()
+	}
+	fmt.Fprintf(w, "Environment:\t%s\n", iops.backend.Name())
+	fmt.Fprintf(w, "Target:\t%s\n", valueOrDash(iops.backend.Info()))
+	neo4jMode := "container"
+	if iops.isExternalNeo4j() {
+		neo4jMode = "external"
+	}
+	fmt.Fprintf(w, "Neo4j mode:\t%s\n", neo4jMode)
+	fmt.Fprintf(w, "Neo4j edition override:\t%s\n", valueOrDash(iops.config.Neo4jEdition))
+	fmt.Fprintf(w, "Neo4j version:\t%s\n", iops.detectNeo4jVersionWithin(diagnosticNeo4jVersionTimeout))
+	fmt.Fprintf(w, "Task manager database:\t%s:%d (external: %t)\n", iops.config.PostgresHost, iops.config.PostgresPort, iops.isExternalPostgres())
+	w.Flush()
+	return b.String()
+}
+
+// detectNeo4jVersionWithin runs detectNeo4jVersion, and returns without waiting for it when
+// cypher-shell hangs for longer than timeout. The abandoned query ends with the process.
+func (iops *InfrahubOps) detectNeo4jVersionWithin(timeout time.Duration) string {
+	version := make(chan string, 1)
+	go func() { version <- iops.detectNeo4jVersion() }()
+	select {
+	case v := <-version:
+		return v
+	case <-time.After(timeout):
+		logrus.Debugf("Neo4j version query did not answer within %s", timeout)
+		return fmt.Sprintf("unknown (no answer within %s)", timeout)
+	}
+}
+
+// writeTarGz writes files, passed through redact, to a new gzip-compressed tarball at path.
+func writeTarGz(path string, files map[string]string, redact func(string) string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	now := time.Now()
+	for _, name := range names {
+		content := redact(files[name])
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+// The report hook runs on whichever goroutine logs, such as the watchdog heartbeat, while
+// the operation starts and finishes reports; run with -race.
+func TestReportHookWithConcurrentLogging(t *testing.T) {
+	iops := newTestOps(t, apptest.NewFakeBackend())
+	iops.config.SummaryOnFailure = true
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(io.Discard)
+	defer logrus.SetOutput(out)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				logrus.Warn("heartbeat")
+			}
+		}
+	}()
+	for range 50 {
+		report := iops.startReport("backup")
+		report.set("Archive", "test")
+		iops.finishReport(nil)
+	}
+	close(stop)
+	wg.Wait()
+
+	report := iops.startReport("backup")
+	logrus.Warn("captured")
+	if !slices.Contains(report.warnings, "captured") {
+		t.Errorf("report warnings = %v, want the warning logged while it was active", report.warnings)
+	}
+	iops.finishReport(nil)
+}
+
+// A hung cypher-shell must not keep the diagnostic bundle from being written.
+func TestDetectNeo4jVersionWithin(t *testing.T) {
+	fake := apptest.NewFakeBackend("database")
+	iops := newTestOps(t, fake)
+	fake.On("database", []string{cypherShellTool}, apptest.Response{Output: "\"5.26.0\"\n"})
+	if got := iops.detectNeo4jVersionWithin(time.Second); got != "5.26.0" {
+		t.Errorf("version = %q, want 5.26.0", got)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	fake = apptest.NewFakeBackend("database")
+	iops = newTestOps(t, fake)
+	fake.On("database", []string{cypherShellTool}, apptest.Response{Run: func(*apptest.FakeBackend, apptest.Call) (string, error) {
+		<-release
+		return "", nil
+	}})
+	started := time.Now()
+	got := iops.detectNeo4jVersionWithin(50 * time.Millisecond)
+	if !strings.HasPrefix(got, "unknown") || time.Since(started) > time.Second {
+		t.Errorf("version of a hung Neo4j = %q after %s, want unknown without waiting", got, time.Since(started))
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RestoreFromDirectory restores from a directory that holds an already extracted backup/
+// tree, for archives unpacked or reassembled outside this tool. The checksums and metadata
+// are validated as for an archive; the directory is only read, never removed.
+func (iops *InfrahubOps) RestoreFromDirectory(dir string, excludeTaskManager bool, restoreMigrateFormat bool) error {
+	return iops.restore(filepath.Clean(dir), "", true, excludeTaskManager, restoreMigrateFormat)
+}
+
+// checkExtractedBackupDir checks that dir has the layout of an extracted archive:
+// a backup/ directory with its backup_information.json.
+func checkExtractedBackupDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("backup directory not found: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("--from-dir %s is not a directory; pass an archive as the backup file argument instead", dir)
+	}
+	if fileExists(filepath.Join(dir, "backup_information.json")) {
+		return fmt.Errorf("--from-dir %s is the backup/ directory itself; pass its parent directory, %s", dir, filepath.Dir(dir))
+	}
+	metadataPath := filepath.Join(dir, "backup", "backup_information.json")
+	if !fileExists(metadataPath) {
+		return fmt.Errorf("--from-dir %s does not contain an extracted backup: %s is missing", dir, metadataPath)
+	}
+	return nil
+}
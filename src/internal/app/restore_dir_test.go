@@ -0,0 +1,37 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+// A failed Community restore writes its diagnostics to diagDir, never into the --from-dir tree.
+func TestRestoreNeo4jCommunityDiagnosticsDir(t *testing.T) {
+	fake := apptest.NewFakeBackend("database")
+	fake.On("database", []string{"cat", neo4jPIDFile}, apptest.Response{Output: "123\n"})
+	fake.On("database", []string{"cat", neo4jRemoteWatchdogLog}, apptest.Response{Output: "watchdog output\n"})
+	fake.On("database", []string{"mkdir", "-p", neo4jRemoteWorkDir}, apptest.Response{Err: errors.New("read-only file system")})
+	fake.On("database", []string{"sh", "-c"}, apptest.Response{Output: "neo4j log line\n"})
+	fake.SetFile("database", neo4jRemoteWatchdogLog, []byte("watchdog output\n"))
+	iops := newTestOps(t, fake)
+	iops.config.IncludeNeo4jLogs = true
+	iops.config.Neo4jLogPaths = []string{"/logs/neo4j.log"}
+
+	workDir, diagDir := t.TempDir(), t.TempDir()
+	if err := iops.restoreNeo4jCommunity(workDir, diagDir, false, false); err == nil {
+		t.Fatal("restoreNeo4jCommunity() succeeded, want the mkdir failure")
+	}
+
+	for _, name := range []string{neo4jWatchdogLogFilename, filepath.Join(neo4jLogsDirname, "neo4j.log")} {
+		if _, err := os.Stat(filepath.Join(diagDir, name)); err != nil {
+			t.Errorf("%s not written to the diagnostics directory: %v", name, err)
+		}
+	}
+	if entries, _ := os.ReadDir(workDir); len(entries) != 0 {
+		t.Errorf("working directory has %d entries, want it left untouched", len(entries))
+	}
+}
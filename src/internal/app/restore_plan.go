@@ -0,0 +1,220 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// restoreBaseDowntime covers stopping and restarting the application services.
+	restoreBaseDowntime = 2 * time.Minute
+	// restoreThroughput is a conservative bytes-per-second rate for copying and loading data.
+	restoreThroughput = 25 * 1024 * 1024
+)
+
+// RestorePlan summarizes what a restore is about to do so it can be reviewed before any mutation.
+type RestorePlan struct {
+	BackupFile             string   `json:"backup_file"`
+	BackupSizeBytes        int64    `json:"backup_size_bytes"`
+	BackupID               string   `json:"backup_id"`
+	Label                  string   `json:"label,omitempty"`
+	BackupCreatedAt        string   `json:"backup_created_at"`
+	BackupAge              string   `json:"backup_age,omitempty"`
+	BackupInfrahubVersion  string   `json:"backup_infrahub_version"`
+	CurrentInfrahubVersion string   `json:"current_infrahub_version"`
+	SourceEnvironment      string   `json:"source_environment,omitempty"`
+	Environment            string   `json:"environment"`
+	Target                 string   `json:"target"`
+	Neo4jRestoreMethod     string   `json:"neo4j_restore_method"`
+	IntegrityKeyID         string   `json:"integrity_key_id,omitempty"`
+	RestoreComponents      []string `json:"restore_components"`
+	SkippedComponents      []string `json:"skipped_components,omitempty"`
+	MissingComponents      []string `json:"missing_components,omitempty"` // failed during a --best-effort backup
+	PgExcludedTables       []string `json:"pg_excluded_tables,omitempty"`
+	PgExcludedTableData    []string `json:"pg_excluded_table_data,omitempty"`
+	StoppedServices        []string `json:"stopped_services"`
+	Steps                  []string `json:"steps"`
+	EstimatedDowntime      string   `json:"estimated_downtime"`
+}
+
+// buildRestorePlan derives the restore plan from backup metadata and the detected environment.
+func (iops *InfrahubOps) buildRestorePlan(backupFile string, metadata *BackupMetadata, neo4jEdition string, taskManagerIncluded, restoreTaskManager, restoreMigrateFormat, streamNeo4j bool) *RestorePlan {
+	plan := &RestorePlan{
+		BackupFile:             backupFile,
+		BackupID:               metadata.BackupID,
+		Label:                  metadata.Label,
+		BackupCreatedAt:        metadata.CreatedAt,
+		BackupInfrahubVersion:  metadata.InfrahubVersion,
+		CurrentInfrahubVersion: iops.getInfrahubVersion(),
+		Neo4jRestoreMethod:     neo4jEdition,
+		IntegrityKeyID:         metadata.IntegrityKeyID,
+		SourceEnvironment:      metadata.SourceEnvironment,
+		MissingComponents:      metadata.failedComponentNames(),
+		StoppedServices:        append([]string(nil), appContainerServices...),
+	}
+	restoreNeo4jData := metadata.includesNeo4j() && !iops.config.ExcludeNeo4j
+	if restoreNeo4jData {
+		plan.RestoreComponents = append(plan.RestoreComponents, "database")
+	} else if metadata.includesNeo4j() {
+		plan.SkippedComponents = append(plan.SkippedComponents, "database")
+	}
+
+	if backend, err := iops.ensureBackend(); err == nil {
+		plan.Environment = backend.Name()
+		plan.Target = backend.Info()
+	}
+
+	// pathSize also covers a directory given to --from-dir
+	if size, err := pathSize(backupFile); err == nil {
+		plan.BackupSizeBytes = size
+	}
+
+	if createdAt, err := time.Parse(time.RFC3339, metadata.CreatedAt); err == nil {
+		plan.BackupAge = time.Since(createdAt).Round(time.Minute).String()
+	}
+
+	taskManagerComponents := []string{"task-manager-db"}
+	for _, database := range extraTaskManagerDatabases(metadata) {
+		taskManagerComponents = append(taskManagerComponents, taskManagerExtraComponentPrefix+database)
+	}
+	if restoreTaskManager {
+		plan.RestoreComponents = append(plan.RestoreComponents, taskManagerComponents...)
+		plan.PgExcludedTables = metadata.PgExcludedTables
+		plan.PgExcludedTableData = metadata.PgExcludedTableData
+	} else if taskManagerIncluded {
+		plan.SkippedComponents = append(plan.SkippedComponents, taskManagerComponents...)
+	}
+
+	if slices.Contains(metadata.Components, neo4jAuthComponent) {
+		if neo4jEdition == neo4jEditionCommunity && !iops.config.ExcludeNeo4jAuth {
+			plan.RestoreComponents = append(plan.RestoreComponents, neo4jAuthComponent)
+		} else {
+			plan.SkippedComponents = append(plan.SkippedComponents, neo4jAuthComponent)
+		}
+	}
+
+	if slices.Contains(metadata.Components, neo4jSchemaComponent) {
+		plan.SkippedComponents = append(plan.SkippedComponents, neo4jSchemaComponent+" (only used by --schema-only)")
+	}
+	if slices.Contains(metadata.Components, deploymentConfigComponent) {
+		plan.SkippedComponents = append(plan.SkippedComponents, deploymentConfigComponent+" (reference only)")
+	}
+
+	plan.Steps = append(plan.Steps, "Wipe cache and message-queue data", "Stop application services")
+	if restoreNeo4jData && neo4jEdition != neo4jEditionExternal && !streamNeo4j {
+		step := "Copy the Neo4j backup into the database container"
+		if restoreTaskManager && !iops.config.NoParallel {
+			step += " (concurrently with the next step)"
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	if restoreTaskManager {
+		step := "Restore task manager database (pg_restore"
+		if !iops.config.PgRestoreNoClean {
+			step += " --clean"
+		}
+		if !iops.config.PgRestoreNoCreate {
+			step += " --create"
+		}
+		plan.Steps = append(plan.Steps, step+")")
+	}
+	plan.Steps = append(plan.Steps, "Restart cache, message-queue and task manager")
+	switch {
+	case !restoreNeo4jData && metadata.includesNeo4j():
+		plan.Steps = append(plan.Steps, "Leave the Neo4j database as is (excluded from the restore)")
+	case !restoreNeo4jData:
+		plan.Steps = append(plan.Steps, "Leave the Neo4j database as is (not in this backup)")
+	case neo4jEdition == neo4jEditionExternal:
+		plan.Steps = append(plan.Steps, "Delete all data in the external Neo4j and replay the logical export")
+	case neo4jEdition == neo4jEditionCommunity:
+		if streamNeo4j {
+			plan.Steps = append(plan.Steps, "Stop Neo4j and stream the database dump into neo4j-admin database load --from-stdin")
+		} else {
+			plan.Steps = append(plan.Steps, "Stop Neo4j and load the database dump (neo4j-admin database load)")
+		}
+	default:
+		if metadata.Neo4jBackupMode == neo4jBackupModeOffline {
+			plan.Steps = append(plan.Steps, "Stop the Neo4j database and load the offline dump (neo4j-admin database load)")
+		} else {
+			plan.Steps = append(plan.Steps, "Stop the Neo4j database and restore the online backup (neo4j-admin database restore)")
+		}
+	}
+	if restoreNeo4jData && restoreMigrateFormat && neo4jEdition != neo4jEditionExternal {
+		plan.Steps = append(plan.Steps, "Migrate the Neo4j store to block format")
+	}
+	// Loaded after the dump, whether it was streamed or copied
+	if restoreNeo4jData && slices.Contains(plan.RestoreComponents, neo4jAuthComponent) {
+		plan.Steps = append(plan.Steps, "Load the Neo4j users (system database and auth files) captured in the backup")
+	}
+	if restoreNeo4jData && isNeo4jEnterpriseEdition(neo4jEdition) && !iops.config.SkipMetadataRestore && metadata.Neo4jMetadata != "none" {
+		plan.Steps = append(plan.Steps, "Replay the Neo4j users and roles captured in the backup")
+	}
+	if restoreNeo4jData && iops.config.Warmup && neo4jEdition != neo4jEditionExternal {
+		plan.Steps = append(plan.Steps, "Wait for Neo4j indexes to come online and warm up the database")
+	}
+	plan.Steps = append(plan.Steps, "Start infrahub-server and task-worker")
+
+	downtime := restoreBaseDowntime + time.Duration(plan.BackupSizeBytes/restoreThroughput)*time.Second
+	plan.EstimatedDowntime = "~" + downtime.Round(time.Minute).String()
+
+	return plan
+}
+
+// Print writes the plan in the requested format ("text" or "json").
+func (plan *RestorePlan) Print(format string) error {
+	if strings.EqualFold(format, "json") {
+		encoded, err := json.MarshalIndent(plan, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal restore plan: %w", err)
+		}
+		fmt.Println(string(encoded))
+		return nil
+	}
+
+	valueOr := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+
+	fmt.Println("Restore plan")
+	fmt.Printf("  Backup:              %s (%s)\n", plan.BackupFile, formatBytes(plan.BackupSizeBytes))
+	fmt.Printf("  Backup ID:           %s\n", plan.BackupID)
+	if plan.Label != "" {
+		fmt.Printf("  Label:               %s\n", plan.Label)
+	}
+	fmt.Printf("  Created at:          %s (age %s)\n", plan.BackupCreatedAt, valueOr(plan.BackupAge, "unknown"))
+	fmt.Printf("  Infrahub version:    %s (backup) -> %s (running)\n", plan.BackupInfrahubVersion, plan.CurrentInfrahubVersion)
+	if plan.SourceEnvironment != "" {
+		fmt.Printf("  Source:              %s\n", plan.SourceEnvironment)
+	}
+	fmt.Printf("  Target:              %s %s\n", plan.Environment, plan.Target)
+	fmt.Printf("  Neo4j restore:       %s\n", plan.Neo4jRestoreMethod)
+	if plan.IntegrityKeyID != "" {
+		fmt.Printf("  Integrity key:       %s\n", plan.IntegrityKeyID)
+	}
+	fmt.Printf("  Components:          %s\n", strings.Join(plan.RestoreComponents, ", "))
+	if len(plan.SkippedComponents) > 0 {
+		fmt.Printf("  Skipped components:  %s\n", strings.Join(plan.SkippedComponents, ", "))
+	}
+	if len(plan.MissingComponents) > 0 {
+		fmt.Printf("  PARTIAL BACKUP:      missing %s, left as is in the target\n", strings.Join(plan.MissingComponents, ", "))
+	}
+	if len(plan.PgExcludedTables) > 0 {
+		fmt.Printf("  Tables not dumped:   %s\n", strings.Join(plan.PgExcludedTables, ", "))
+	}
+	if len(plan.PgExcludedTableData) > 0 {
+		fmt.Printf("  Tables left empty:   %s\n", strings.Join(plan.PgExcludedTableData, ", "))
+	}
+	fmt.Printf("  Services stopped:    %s\n", strings.Join(plan.StoppedServices, ", "))
+	fmt.Printf("  Estimated downtime:  %s\n", plan.EstimatedDowntime)
+	fmt.Println("  Steps:")
+	for i, step := range plan.Steps {
+		fmt.Printf("    %d. %s\n", i+1, step)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"infrahub-ops/src/internal/apptest"
+)
+
+func TestRestorePlanNeo4jAuthStep(t *testing.T) {
+	const authStep = "Load the Neo4j users (system database and auth files) captured in the backup"
+	tests := []struct {
+		name        string
+		edition     string
+		stream      bool
+		excludeAuth bool
+		components  []string
+		want        bool
+	}{
+		{name: "community copy", edition: neo4jEditionCommunity, components: []string{"database", neo4jAuthComponent}, want: true},
+		{name: "community stream", edition: neo4jEditionCommunity, stream: true, components: []string{"database", neo4jAuthComponent}, want: true},
+		{name: "excluded", edition: neo4jEditionCommunity, stream: true, excludeAuth: true, components: []string{"database", neo4jAuthComponent}},
+		{name: "not in backup", edition: neo4jEditionCommunity, components: []string{"database"}},
+		{name: "enterprise", edition: neo4jEditionEnterprise, components: []string{"database", neo4jAuthComponent}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iops := newTestOps(t, apptest.NewFakeBackend("database"))
+			iops.config.ExcludeNeo4jAuth = tt.excludeAuth
+			metadata := &BackupMetadata{Components: tt.components}
+
+			plan := iops.buildRestorePlan("backup.tar.gz", metadata, tt.edition, false, false, false, tt.stream)
+			index := slices.Index(plan.Steps, authStep)
+			if (index >= 0) != tt.want {
+				t.Fatalf("auth step in plan = %t, want %t:\n%s", index >= 0, tt.want, strings.Join(plan.Steps, "\n"))
+			}
+			if tt.want && !strings.Contains(plan.Steps[index-1], "Stop Neo4j and") {
+				t.Errorf("auth step follows %q, want the Neo4j load", plan.Steps[index-1])
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// hasRestoreTarget reports whether --target-project or --target-namespace is set.
+func (iops *InfrahubOps) hasRestoreTarget() bool {
+	return iops.config.TargetProject != "" || iops.config.TargetNamespace != ""
+}
+
+// pinRestoreTarget selects the environment named by --target-project or --target-namespace
+// instead of auto-detecting one. There is no fallback: a target that has no running Infrahub
+// deployment fails the restore before anything is read from the archive.
+func (iops *InfrahubOps) pinRestoreTarget() error {
+	if iops.config.TargetProject != "" && iops.config.TargetNamespace != "" {
+		return fmt.Errorf("%w: --target-project and --target-namespace cannot be used together", ErrPrerequisites)
+	}
+	if iops.config.ReplayBackend != "" {
+		return fmt.Errorf("%w: --target-project and --target-namespace cannot be used with --replay-backend", ErrPrerequisites)
+	}
+
+	var backend Backend
+	if project := iops.config.TargetProject; project != "" {
+		projects, err := ListDockerProjects(iops.executor, iops.config.tool(dockerTool))
+		if err != nil {
+			return fmt.Errorf("target docker compose project %s: %w", project, err)
+		}
+		if !contains(projects, project) {
+			return fmt.Errorf("%w: target docker compose project %s has no running Infrahub deployment", ErrEnvironmentNotFound, project)
+		}
+		iops.config.DockerComposeProject = project
+		backend = iops.getDockerBackend()
+	} else {
+		namespace := iops.config.TargetNamespace
+		namespaces, err := ListKubernetesNamespaces(iops.executor, iops.config.tool(kubectlTool))
+		if err != nil {
+			return fmt.Errorf("target kubernetes namespace %s: %w", namespace, err)
+		}
+		if !contains(namespaces, namespace) {
+			return fmt.Errorf("%w: target kubernetes namespace %s has no Infrahub pods", ErrEnvironmentNotFound, namespace)
+		}
+		iops.config.K8sNamespace = namespace
+		backend = iops.getKubernetesBackend()
+	}
+
+	if err := backend.Detect(); err != nil {
+		return fmt.Errorf("%w: target %s %s: %v", ErrEnvironmentNotFound, backend.Name(), backend.Info(), err)
+	}
+	iops.backend = backend
+	logrus.WithFields(logrus.Fields{
+		"environment": backend.Name(),
+		"target":      backend.Info(),
+	}).Warn("Restore target set explicitly; environment auto-detection is disabled")
+	return nil
+}
+
+// logRestoreSourceAndTarget logs where the backup was taken and where it is about to be
+// restored, so that a restore across environments is visible in the logs.
+func (iops *InfrahubOps) logRestoreSourceAndTarget(metadata *BackupMetadata) {
+	backend, err := iops.ensureBackend()
+	if err != nil {
+		return
+	}
+	target := backend.Name() + " " + backend.Info()
+	source := valueOrDash(metadata.SourceEnvironment)
+	fields := logrus.Fields{"source": source, "target": target}
+	if source == target {
+		logrus.WithFields(fields).Info("Restoring into the environment the backup was taken from")
+		return
+	}
+	logrus.WithFields(fields).Warnf("Restoring a backup of %s into %s", source, target)
+}
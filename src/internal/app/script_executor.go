@@ -28,11 +28,7 @@ func (iops *InfrahubOps) executeScriptWithOpts(targetService string, scriptConte
 	if err := iops.CopyTo(targetService, tmpFile.Name(), targetPath); err != nil {
 		return "", fmt.Errorf("failed to copy script to target: %w", err)
 	}
-	defer func() {
-		if _, err := iops.Exec(targetService, []string{"rm", "-f", targetPath}, nil); err != nil {
-			logrus.Warnf("Failed to clean up script %s on %s: %v", targetPath, targetService, err)
-		}
-	}()
+	defer iops.removeRemotePaths(targetService, targetPath)
 
 	// Execute script inside container
 	logrus.Info("Executing script inside container...")
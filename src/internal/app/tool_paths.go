@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// External tools, run by these names unless a --<tool>-path flag overrides them.
+const (
+	neo4jAdminTool  = "neo4j-admin"
+	cypherShellTool = "cypher-shell"
+	pgDumpTool      = "pg_dump"
+	pgRestoreTool   = "pg_restore"
+	kubectlTool     = "kubectl"
+	dockerTool      = "docker"
+)
+
+// tool returns the command to run for one of the tools above: the configured path, or the
+// bare name looked up on PATH (locally for docker and kubectl, in the container otherwise).
+func (c *Configuration) tool(name string) string {
+	var path string
+	switch name {
+	case neo4jAdminTool:
+		path = c.Neo4jAdminPath
+	case cypherShellTool:
+		path = c.CypherShellPath
+	case pgDumpTool:
+		path = c.PgDumpPath
+	case pgRestoreTool:
+		path = c.PgRestorePath
+	case kubectlTool:
+		path = c.KubectlPath
+	case dockerTool:
+		path = c.DockerPath
+	}
+	if path == "" {
+		return name
+	}
+	return path
+}
+
+// isToolOverridden reports whether a --<tool>-path flag changed the command of name.
+func (c *Configuration) isToolOverridden(name string) bool {
+	return c.tool(name) != name
+}
+
+// checkLocalToolPaths verifies that the overridden docker and kubectl commands exist on this
+// machine. The bare names are not checked, since either CLI may legitimately be missing.
+func (iops *InfrahubOps) checkLocalToolPaths() error {
+	var missing []string
+	for _, name := range []string{dockerTool, kubectlTool} {
+		if !iops.config.isToolOverridden(name) {
+			continue
+		}
+		if _, err := exec.LookPath(iops.config.tool(name)); err != nil {
+			missing = append(missing, fmt.Sprintf("--%s-path %s: %v", name, iops.config.tool(name), err))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrPrerequisites, strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// checkContainerToolPaths verifies that the overridden Neo4j and PostgreSQL client commands
+// exist where they run: in the database service, and in the PostgreSQL client service or on
+// this machine. It needs the environment, so it runs once it has been detected.
+func (iops *InfrahubOps) checkContainerToolPaths() error {
+	var missing []string
+	check := func(flag, name, service string) {
+		if !iops.config.isToolOverridden(name) {
+			return
+		}
+		path := iops.config.tool(name)
+		if service == "" {
+			if _, err := exec.LookPath(path); err != nil {
+				missing = append(missing, fmt.Sprintf("--%s %s: %v", flag, path, err))
+			}
+			return
+		}
+		if _, err := iops.Exec(service, []string{"sh", "-c", "command -v " + shellQuote(path)}, nil); err != nil {
+			missing = append(missing, fmt.Sprintf("--%s %s: not found in service %s", flag, path, service))
+		}
+	}
+
+	if !iops.isExternalNeo4j() {
+		check("neo4j-admin-path", neo4jAdminTool, "database")
+		check("cypher-shell-path", cypherShellTool, "database")
+	}
+	if iops.config.isToolOverridden(pgDumpTool) || iops.config.isToolOverridden(pgRestoreTool) {
+		service, err := iops.postgresClient(iops.config.tool(pgDumpTool))
+		if err != nil {
+			return err
+		}
+		check("pg-dump-path", pgDumpTool, service)
+		check("pg-restore-path", pgRestoreTool, service)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrPrerequisites, strings.Join(missing, "; "))
+	}
+	return nil
+}
@@ -2,7 +2,10 @@ package app
 
 import (
 	"archive/tar"
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
@@ -10,8 +13,15 @@ import (
 	"path/filepath"
 	"runtime/debug"
 	"strings"
+	"time"
+
+	"github.com/klauspost/pgzip"
+	"github.com/sirupsen/logrus"
 )
 
+// gzipBlockSize is the amount of data each compression thread handles at a time.
+const gzipBlockSize = 1 << 20
+
 // Version can be set via SetVersion from main packages using ldflags
 var version string
 
@@ -58,33 +68,138 @@ func fileExists(path string) bool {
 	return !info.IsDir()
 }
 
+// isInteractiveTerminal reports whether the file is attached to a terminal
+func isInteractiveTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
 // calculateSHA256 calculates the SHA256 checksum of a file
 func calculateSHA256(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+	return calculateSHA256Context(context.Background(), nil, filePath)
+}
+
+// calculateSHA256Context is calculateSHA256 that opens the file through files and stops
+// reading as soon as ctx is cancelled.
+func calculateSHA256Context(ctx context.Context, files *fileLimiter, filePath string) (string, error) {
+	file, err := files.open(filePath)
 	if err != nil {
 		return "", err
 	}
 	defer file.Close()
 
 	hasher := sha256.New()
-	if _, err := io.Copy(hasher, file); err != nil {
+	if _, err := io.Copy(hasher, contextReader{ctx: ctx, r: file}); err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
 }
 
-func createTarball(filename, sourceDir, pathInTar string) error {
+// contextReader fails reads once ctx is done, so long copies can be interrupted.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// parseTarFormat validates --tar-format. PAX stores long paths and large sizes in extended
+// headers; GNU uses its own long-name entries and base-256 sizes, for readers without PAX support.
+func parseTarFormat(value string) (tar.Format, error) {
+	switch strings.ToLower(value) {
+	case "", "pax":
+		return tar.FormatPAX, nil
+	case "gnu":
+		return tar.FormatGNU, nil
+	default:
+		return tar.FormatUnknown, fmt.Errorf("invalid --tar-format %q: must be pax or gnu", value)
+	}
+}
+
+// parseArchiveCompression validates --compression. none writes a plain tar, for storage that
+// compresses on its own or for hosts where CPU, not disk, is the bottleneck.
+func parseArchiveCompression(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "", archiveCompressionGzip:
+		return archiveCompressionGzip, nil
+	case archiveCompressionNone:
+		return archiveCompressionNone, nil
+	default:
+		return "", fmt.Errorf("invalid --compression %q: must be gzip or none", value)
+	}
+}
+
+// archiveWriter is the stream a tar archive is written to: gzip members, or the file itself.
+type archiveWriter interface {
+	io.WriteCloser
+	setLevel(level int) error
+}
+
+// uncompressedArchive writes the tar stream as is; compression levels do not apply.
+type uncompressedArchive struct {
+	io.Writer
+}
+
+func (uncompressedArchive) setLevel(int) error { return nil }
+
+func (uncompressedArchive) Close() error { return nil }
+
+// tarballOptions are the settings of createTarball.
+type tarballOptions struct {
+	Files       *fileLimiter // bounds the files read at once; nil opens them without a bound
+	Compression string       // gzip, or none for a plain tar
+	Threads     int
+	Level       int
+	Format      tar.Format
+	// StoreCompressed stores files that are already compressed in their own uncompressed gzip
+	// member instead of deflating them again
+	StoreCompressed bool
+	Normalize       tarNormalization // rewrites the stored ownership and permissions
+}
+
+// createTarball writes sourceDir/pathInTar as a tar archive at filename. The file is closed
+// and its error returned, since the file system may report a failed write only then.
+func createTarball(filename, sourceDir, pathInTar string, opts tarballOptions) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	gw := gzip.NewWriter(file)
-	defer gw.Close()
+	if err := writeTarball(file, sourceDir, pathInTar, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
 
+// writeTarball writes sourceDir/pathInTar as a tar stream to w. The tar and gzip writers are
+// closed in order and the first error returned: parallel gzip compression often reports a
+// failed write, such as a full disk, only when the last blocks are flushed.
+func writeTarball(w io.Writer, sourceDir, pathInTar string, opts tarballOptions) (retErr error) {
+	var gw archiveWriter = uncompressedArchive{w}
+	if opts.Compression != archiveCompressionNone {
+		members, err := newGzipMembers(w, opts.Threads, opts.Level)
+		if err != nil {
+			return err
+		}
+		gw = members
+	}
 	tw := tar.NewWriter(gw)
-	defer tw.Close()
+	defer func() {
+		if err := tw.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("failed to finish the tar stream: %w", err)
+		}
+		if err := gw.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("failed to finish the compressed stream: %w", err)
+		}
+	}()
 
 	return filepath.Walk(filepath.Join(sourceDir, pathInTar), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -101,6 +216,24 @@ func createTarball(filename, sourceDir, pathInTar string) error {
 			return err
 		}
 		header.Name = filepath.ToSlash(relPath)
+		// Whole-second mtimes and no atime/ctime keep headers encodable in either format
+		// without extra records that older readers trip over
+		header.Format = opts.Format
+		header.ModTime = header.ModTime.Truncate(time.Second)
+		header.AccessTime = time.Time{}
+		header.ChangeTime = time.Time{}
+		opts.Normalize.apply(header)
+
+		if !info.IsDir() {
+			memberLevel := opts.Level
+			if opts.StoreCompressed && isCompressedFile(opts.Files, path) {
+				logrus.Debugf("Storing %s without recompressing it", header.Name)
+				memberLevel = gzip.NoCompression
+			}
+			if err := gw.setLevel(memberLevel); err != nil {
+				return err
+			}
+		}
 
 		if err := tw.WriteHeader(header); err != nil {
 			return err
@@ -110,7 +243,7 @@ func createTarball(filename, sourceDir, pathInTar string) error {
 			return nil
 		}
 
-		file, err := os.Open(path)
+		file, err := opts.Files.open(path)
 		if err != nil {
 			return err
 		}
@@ -121,6 +254,108 @@ func createTarball(filename, sourceDir, pathInTar string) error {
 	})
 }
 
+// gzipMembers writes a gzip stream as consecutive members, starting a new member whenever the
+// compression level changes. Gzip readers, including gzip and tar, see one continuous stream.
+type gzipMembers struct {
+	w       io.Writer
+	threads int
+	level   int
+	member  io.WriteCloser
+}
+
+func newGzipMembers(w io.Writer, threads, level int) (*gzipMembers, error) {
+	member, err := newGzipWriter(w, threads, level)
+	if err != nil {
+		return nil, err
+	}
+	return &gzipMembers{w: w, threads: threads, level: level, member: member}, nil
+}
+
+func (g *gzipMembers) Write(p []byte) (int, error) {
+	return g.member.Write(p)
+}
+
+// setLevel closes the current member and starts a new one when level differs from its level.
+func (g *gzipMembers) setLevel(level int) error {
+	if level == g.level {
+		return nil
+	}
+	if err := g.member.Close(); err != nil {
+		return err
+	}
+	member, err := newGzipWriter(g.w, g.threads, level)
+	if err != nil {
+		return err
+	}
+	g.member, g.level = member, level
+	return nil
+}
+
+func (g *gzipMembers) Close() error {
+	return g.member.Close()
+}
+
+// compressedMagics are the leading bytes of already-compressed files: gzip, zstd, pg_dump
+// custom-format dumps and Neo4j dumps (zstd and gzip variants).
+var compressedMagics = [][]byte{
+	{0x1f, 0x8b},
+	{0x28, 0xb5, 0x2f, 0xfd},
+	[]byte("PGDMP"),
+	[]byte("DZV1.0"),
+	[]byte("GZV1.0"),
+}
+
+// isCompressedFile reports whether the file at path is already compressed, going by its
+// extension or its leading bytes.
+func isCompressedFile(files *fileLimiter, path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".tgz", ".zst", ".bz2", ".xz", ".zip":
+		return true
+	}
+	file, err := files.open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	header := make([]byte, 8)
+	n, _ := io.ReadFull(file, header)
+	for _, magic := range compressedMagics {
+		if bytes.HasPrefix(header[:n], magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// newGzipWriter returns a single-threaded writer for threads <= 1, and a parallel one otherwise.
+func newGzipWriter(w io.Writer, threads, level int) (io.WriteCloser, error) {
+	if threads <= 1 {
+		return gzip.NewWriterLevel(w, level)
+	}
+	pw, err := pgzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	if err := pw.SetConcurrency(gzipBlockSize, threads); err != nil {
+		return nil, fmt.Errorf("failed to configure parallel compression: %w", err)
+	}
+	return pw, nil
+}
+
+// openArchiveStream returns the tar stream of an archive, decompressing it when its leading
+// bytes are the gzip magic number and reading it as is otherwise, whatever its extension.
+func openArchiveStream(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(magic, compressedMagics[0]) {
+		return gzip.NewReader(br)
+	}
+	return io.NopCloser(br), nil
+}
+
 func extractTarball(filename, destDir string) error {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -128,13 +363,13 @@ func extractTarball(filename, destDir string) error {
 	}
 	defer file.Close()
 
-	gr, err := gzip.NewReader(file)
+	stream, err := openArchiveStream(file)
 	if err != nil {
 		return err
 	}
-	defer gr.Close()
+	defer stream.Close()
 
-	tr := tar.NewReader(gr)
+	tr := tar.NewReader(stream)
 
 	// Ensure destination directory is absolute for security checks
 	destDir, err = filepath.Abs(destDir)
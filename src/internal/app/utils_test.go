@@ -0,0 +1,136 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// compressibleData returns size bytes that compress about as well as a database dump.
+func compressibleData(size int) []byte {
+	words := []string{"node", "edge", "attribute", "value", "relationship", "branch", "schema", "0123456789"}
+	rng := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[rng.Intn(len(words))])
+		buf.WriteByte(' ')
+	}
+	return buf.Bytes()[:size]
+}
+
+func TestNewGzipWriterThreads(t *testing.T) {
+	data := compressibleData(3*gzipBlockSize + 123)
+	for _, threads := range []int{1, 4} {
+		var compressed bytes.Buffer
+		w, err := newGzipWriter(&compressed, threads, gzip.DefaultCompression)
+		if err != nil {
+			t.Fatalf("newGzipWriter(%d): %v", threads, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("write with %d threads: %v", threads, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("close with %d threads: %v", threads, err)
+		}
+
+		r, err := gzip.NewReader(&compressed)
+		if err != nil {
+			t.Fatalf("read the %d-thread stream: %v", threads, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read the %d-thread stream: %v", threads, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%d-thread stream decompressed to %d bytes, want the %d written", threads, len(got), len(data))
+		}
+	}
+}
+
+// failingWriter accepts limit bytes, then fails every write.
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errors.New("no space left on device")
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+// A write that only fails when the compressed stream is flushed must fail the archive.
+func TestWriteTarballReportsCloseErrors(t *testing.T) {
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "backup"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "backup", "dump"), compressibleData(4096), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, threads := range []int{1, 4} {
+		opts := tarballOptions{Compression: archiveCompressionGzip, Threads: threads, Level: gzip.DefaultCompression, Format: tar.FormatPAX}
+		err := writeTarball(&failingWriter{limit: 64}, source, "backup/", opts)
+		if err == nil || !strings.Contains(err.Error(), "failed to finish the compressed stream") {
+			t.Errorf("writeTarball with %d threads = %v, want the error of the final flush", threads, err)
+		}
+	}
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	opts := tarballOptions{Compression: archiveCompressionGzip, Threads: 4, Level: gzip.DefaultCompression, Format: tar.FormatPAX}
+	if err := createTarball(archive, source, "backup/", opts); err != nil {
+		t.Fatalf("createTarball: %v", err)
+	}
+	file, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := tar.NewReader(r).Next()
+	if err != nil || header.Name != "backup" {
+		t.Errorf("first archive entry = %v, %v; want the backup directory", header, err)
+	}
+}
+
+// BenchmarkNewGzipWriter measures the compression throughput per --compression-threads value:
+//
+//	go test -run '^$' -bench NewGzipWriter ./src/internal/app/
+func BenchmarkNewGzipWriter(b *testing.B) {
+	data := compressibleData(32 * gzipBlockSize)
+	counts := []int{1, 2, 4, runtime.NumCPU()}
+	slices.Sort(counts)
+	for _, threads := range slices.Compact(counts) {
+		b.Run(fmt.Sprintf("threads=%d", threads), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				w, err := newGzipWriter(io.Discard, threads, gzip.DefaultCompression)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := w.Write(data); err != nil {
+					b.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -7,3 +7,17 @@ var neo4jWatchdogLinuxAMD64 []byte
 
 //go:embed embedded/neo4jwatchdog/neo4j_watchdog_linux_arm64
 var neo4jWatchdogLinuxARM64 []byte
+
+//go:embed embedded/neo4jwatchdog/neo4j_watchdog_linux_ppc64le
+var neo4jWatchdogLinuxPPC64LE []byte
+
+//go:embed embedded/neo4jwatchdog/neo4j_watchdog_linux_s390x
+var neo4jWatchdogLinuxS390X []byte
+
+// embeddedWatchdogBinaries maps normalized GOARCH names to the embedded watchdog builds.
+var embeddedWatchdogBinaries = map[string][]byte{
+	"amd64":   neo4jWatchdogLinuxAMD64,
+	"arm64":   neo4jWatchdogLinuxARM64,
+	"ppc64le": neo4jWatchdogLinuxPPC64LE,
+	"s390x":   neo4jWatchdogLinuxS390X,
+}
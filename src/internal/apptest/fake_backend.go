@@ -0,0 +1,256 @@
+// Package apptest provides helpers for exercising InfrahubOps without real containers.
+package apptest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"infrahub-ops/src/pkg/backend"
+)
+
+// Call records one backend invocation.
+type Call struct {
+	Method  string // Exec, ExecStream, CopyTo, CopyFrom, Start, Stop, IsRunning, IsReady, HasService or CaptureConfig
+	Service string
+	Args    []string // command for Exec/ExecStream, [src, dest] for copies, services for Start/Stop
+	Stdin   []byte   // data piped into Exec, if any
+}
+
+// Response is the scripted result of a command.
+type Response struct {
+	Output string
+	Err    error
+	// Run, when set, is called instead of returning Output/Err and may inspect or modify the fake.
+	Run func(f *FakeBackend, call Call) (string, error)
+}
+
+type rule struct {
+	service string
+	prefix  []string
+	resp    Response
+}
+
+// FakeBackend is an in-memory backend.Backend. Commands answer with scripted responses
+// (empty output by default), CopyTo/CopyFrom move files in and out of a virtual
+// per-service filesystem, and every call is recorded for assertions.
+type FakeBackend struct {
+	Project   string
+	DetectErr error
+
+	mu      sync.Mutex
+	rules   []rule
+	running map[string]bool
+	files   map[string][]byte
+	calls   []Call
+}
+
+var (
+	_ backend.Backend        = (*FakeBackend)(nil)
+	_ backend.ServiceChecker = (*FakeBackend)(nil)
+)
+
+// NewFakeBackend returns a fake with the given services running. Other services don't exist
+// until AddStopped or Start adds them.
+func NewFakeBackend(running ...string) *FakeBackend {
+	f := &FakeBackend{
+		Project: "fake",
+		running: map[string]bool{},
+		files:   map[string][]byte{},
+	}
+	for _, service := range running {
+		f.running[service] = true
+	}
+	return f
+}
+
+// On scripts the response to commands in service starting with prefix. An empty service
+// matches any service. When several rules match, the one with the longest prefix wins.
+func (f *FakeBackend) On(service string, prefix []string, resp Response) *FakeBackend {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, rule{service: service, prefix: prefix, resp: resp})
+	return f
+}
+
+// AddStopped adds services that exist in the deployment but are not running.
+func (f *FakeBackend) AddStopped(services ...string) *FakeBackend {
+	f.setRunning(services, false)
+	return f
+}
+
+// SetFile stores a file in the virtual filesystem of service.
+func (f *FakeBackend) SetFile(service, path string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[fileKey(service, path)] = slices.Clone(data)
+}
+
+// File returns a file from the virtual filesystem of service.
+func (f *FakeBackend) File(service, path string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.files[fileKey(service, path)]
+	return slices.Clone(data), ok
+}
+
+// Calls returns a copy of the recorded calls.
+func (f *FakeBackend) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// CallsTo returns the recorded calls of one method, e.g. "Start".
+func (f *FakeBackend) CallsTo(method string) []Call {
+	var matched []Call
+	for _, call := range f.Calls() {
+		if call.Method == method {
+			matched = append(matched, call)
+		}
+	}
+	return matched
+}
+
+func (f *FakeBackend) Name() string { return "fake" }
+
+func (f *FakeBackend) Info() string { return f.Project }
+
+func (f *FakeBackend) Detect() error { return f.DetectErr }
+
+func (f *FakeBackend) Exec(service string, command []string, opts *backend.ExecOptions) (string, error) {
+	return f.exec("Exec", service, command, opts)
+}
+
+func (f *FakeBackend) ExecStream(service string, command []string, opts *backend.ExecOptions) (string, error) {
+	return f.exec("ExecStream", service, command, opts)
+}
+
+func (f *FakeBackend) exec(method, service string, command []string, opts *backend.ExecOptions) (string, error) {
+	call := Call{Method: method, Service: service, Args: slices.Clone(command)}
+	if opts != nil && opts.Stdin != nil {
+		data, err := io.ReadAll(opts.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("fake: failed to read stdin: %w", err)
+		}
+		call.Stdin = data
+	}
+	f.record(call)
+
+	resp, ok := f.match(service, command)
+	if !ok {
+		return "", nil
+	}
+	if resp.Run != nil {
+		return resp.Run(f, call)
+	}
+	return resp.Output, resp.Err
+}
+
+func (f *FakeBackend) match(service string, command []string) (Response, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	best, found := Response{}, false
+	bestLen := -1
+	for _, r := range f.rules {
+		if r.service != "" && r.service != service {
+			continue
+		}
+		if len(r.prefix) > len(command) || !slices.Equal(r.prefix, command[:len(r.prefix)]) {
+			continue
+		}
+		if len(r.prefix) > bestLen {
+			best, found, bestLen = r.resp, true, len(r.prefix)
+		}
+	}
+	return best, found
+}
+
+func (f *FakeBackend) CopyTo(service, src, dest string) error {
+	f.record(Call{Method: "CopyTo", Service: service, Args: []string{src, dest}})
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("fake: failed to read %s: %w", src, err)
+	}
+	f.SetFile(service, dest, data)
+	return nil
+}
+
+func (f *FakeBackend) CopyFrom(service, src, dest string) error {
+	f.record(Call{Method: "CopyFrom", Service: service, Args: []string{src, dest}})
+	data, ok := f.File(service, src)
+	if !ok {
+		return fmt.Errorf("fake: %s:%s does not exist", service, src)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0644)
+}
+
+func (f *FakeBackend) Start(services ...string) error {
+	f.record(Call{Method: "Start", Args: slices.Clone(services)})
+	f.setRunning(services, true)
+	return nil
+}
+
+func (f *FakeBackend) Stop(services ...string) error {
+	f.record(Call{Method: "Stop", Args: slices.Clone(services)})
+	f.setRunning(services, false)
+	return nil
+}
+
+func (f *FakeBackend) IsRunning(service string) (bool, error) {
+	f.record(Call{Method: "IsRunning", Service: service})
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running[service], nil
+}
+
+// IsReady treats every running service as ready.
+func (f *FakeBackend) IsReady(service string) (bool, error) {
+	f.record(Call{Method: "IsReady", Service: service})
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running[service], nil
+}
+
+// HasService reports whether the service was created running, added with AddStopped,
+// started or stopped.
+func (f *FakeBackend) HasService(service string) (bool, error) {
+	f.record(Call{Method: "HasService", Service: service})
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.running[service]
+	return ok, nil
+}
+
+func (f *FakeBackend) CaptureConfig(destDir string) error {
+	f.record(Call{Method: "CaptureConfig", Args: []string{destDir}})
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(destDir, "fake-backend.txt"), []byte("project: "+f.Project+"\n"), 0644)
+}
+
+func (f *FakeBackend) setRunning(services []string, running bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, service := range services {
+		f.running[service] = running
+	}
+}
+
+func (f *FakeBackend) record(call Call) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func fileKey(service, path string) string {
+	return service + ":" + strings.TrimSpace(path)
+}
@@ -0,0 +1,39 @@
+// Package backend defines the deployment environments infrahub-backup operates on. Go programs
+// implement Backend to drive backups and restores against an environment of their own, through
+// infrahubops.NewWithBackend.
+package backend
+
+import "io"
+
+// ExecOptions are the optional settings of a command run in a service.
+type ExecOptions struct {
+	User  string
+	Env   map[string]string
+	Stdin io.Reader // piped into the command when set (Exec only)
+}
+
+// Backend is a deployment environment the tool can operate on. The Docker Compose and
+// Kubernetes backends of infrahub-backup implement it.
+type Backend interface {
+	Name() string
+	Detect() error
+	Info() string
+	Exec(service string, command []string, opts *ExecOptions) (string, error)
+	ExecStream(service string, command []string, opts *ExecOptions) (string, error)
+	CopyTo(service, src, dest string) error
+	CopyFrom(service, src, dest string) error
+	Start(services ...string) error
+	Stop(services ...string) error
+	IsRunning(service string) (bool, error)
+	// IsReady reports whether the service is running and passes its health or readiness check.
+	IsReady(service string) (bool, error)
+	// CaptureConfig writes a redacted snapshot of the deployment configuration into destDir.
+	CaptureConfig(destDir string) error
+}
+
+// ServiceChecker is implemented by backends that can tell a service missing from the
+// deployment from one that exists but is stopped. Without it, every service is assumed to exist.
+type ServiceChecker interface {
+	// HasService reports whether the deployment has the service, running or not.
+	HasService(service string) (bool, error)
+}
@@ -0,0 +1,25 @@
+// Package infrahubops lets other Go programs run infrahub-backup operations against a
+// backend.Backend of their own instead of a detected Docker Compose or Kubernetes deployment.
+package infrahubops
+
+import (
+	"infrahub-ops/src/internal/app"
+	"infrahub-ops/src/pkg/backend"
+)
+
+// Configuration holds the settings of the operations, as set by the command-line flags.
+type Configuration = app.Configuration
+
+// InfrahubOps runs backups, restores and the other operations of the CLI.
+type InfrahubOps = app.InfrahubOps
+
+// DefaultConfiguration returns the configuration defaults, including values read from the environment.
+func DefaultConfiguration() *Configuration {
+	return app.DefaultConfiguration()
+}
+
+// NewWithBackend returns an InfrahubOps that uses b instead of detecting the environment.
+// A nil cfg uses DefaultConfiguration.
+func NewWithBackend(cfg *Configuration, b backend.Backend) *InfrahubOps {
+	return app.NewInfrahubOpsWithBackend(cfg, b)
+}
@@ -8,14 +8,17 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
 
 var (
-	pidFile   = flag.String("pid-file", "/var/lib/neo4j/run/neo4j.pid", "Path to the neo4j pid file")
-	readyFile = flag.String("ready-file", "", "Optional path to write once watcher is initialized")
+	pidFile          = flag.String("pid-file", "/var/lib/neo4j/run/neo4j.pid", "Path to the neo4j pid file")
+	readyFile        = flag.String("ready-file", "", "Optional path to write once watcher is initialized")
+	heartbeatFile    = flag.String("heartbeat-file", "", "Optional file refreshed by the backup tool; neo4j is resumed if it goes stale")
+	heartbeatTimeout = flag.Duration("heartbeat-timeout", 60*time.Second, "Maximum heartbeat age before neo4j is resumed")
 )
 
 func main() {
@@ -29,6 +32,39 @@ func main() {
 	if err := watchForDelete(*pidFile, pid, *readyFile); err != nil {
 		log.Fatalf("watcher error: %v", err)
 	}
+
+	if *heartbeatFile != "" {
+		if err := watchHeartbeat(*heartbeatFile, pid, *heartbeatTimeout); err != nil {
+			log.Fatalf("heartbeat error: %v", err)
+		}
+	}
+}
+
+// watchHeartbeat resumes the process once the heartbeat file goes stale. It returns
+// when the heartbeat file is removed, which signals that the backup tool is done.
+func watchHeartbeat(path string, pid int, timeout time.Duration) error {
+	interval := timeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	for {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Printf("heartbeat file %s removed; exiting", path)
+				return nil
+			}
+			return fmt.Errorf("stat heartbeat: %w", err)
+		}
+		if age := time.Since(info.ModTime()); age > timeout {
+			log.Printf("heartbeat stale for %s; resuming pid %d", age.Round(time.Second), pid)
+			if err := syscall.Kill(pid, syscall.SIGCONT); err != nil {
+				return fmt.Errorf("failed to SIGCONT pid %d: %w", pid, err)
+			}
+			return nil
+		}
+		time.Sleep(interval)
+	}
 }
 
 func readPID(path string) (int, error) {